@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+
+	"github.com/fedemengo/d2bist/pkg/analyze"
+	"github.com/fedemengo/d2bist/pkg/flags"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+var (
+	analyzeChunk   = -1
+	analyzeSlen    = 2
+	analyzeEstims  = ""
+	analyzeLen     = analyze.DefaultSubstrLen
+	analyzeTopK    = analyze.DefaultTopK
+	analyzeWorkers = 0
+	analyzeBinStr  = false
+	analyzeFormat  = "table"
+)
+
+var analyzeCommand = &cli.Command{
+	Name:      "analyze",
+	Usage:     "Compare the bit balance, entropy, compression ratios and top substrings of several files",
+	ArgsUsage: "FILE|GLOB...",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:        "chunk",
+			Usage:       "size in bits of the chunks the entropy is averaged on",
+			DefaultText: "whole file",
+			Destination: &analyzeChunk,
+		}, &cli.IntFlag{
+			Name:        "slen",
+			Value:       analyzeSlen,
+			Usage:       "length of unitary symbol used when calculating data entropy",
+			Destination: &analyzeSlen,
+		}, &cli.StringFlag{
+			Name:        "estimators",
+			Usage:       "comma separated entropy estimators (shannon, lz76 or a codec with optional parameters, e.g. zstd:level=4:window=23), replace the default ones",
+			DefaultText: "gzip, brotli, bzip2 and shannon",
+			Destination: &analyzeEstims,
+		}, &cli.IntFlag{
+			Name:        "len",
+			Value:       analyzeLen,
+			Usage:       "length of the reported substrings",
+			Destination: &analyzeLen,
+		}, &cli.IntFlag{
+			Name:        "topk",
+			Aliases:     []string{"k"},
+			Value:       analyzeTopK,
+			Usage:       "number of most frequent substrings reported for each file",
+			Destination: &analyzeTopK,
+		}, &cli.IntFlag{
+			Name:        "workers",
+			Usage:       "number of files analysed at the same time",
+			DefaultText: "number of CPUs",
+			Destination: &analyzeWorkers,
+		}, &cli.BoolFlag{
+			Name:        "binstr",
+			Usage:       "the inputs are strings of 0s and 1s",
+			Destination: &analyzeBinStr,
+		}, &cli.StringFlag{
+			Name:        "format",
+			Aliases:     []string{"f"},
+			Value:       analyzeFormat,
+			Usage:       "output format (table, json, html)",
+			Destination: &analyzeFormat,
+		},
+	},
+	Action: analyzeAction,
+}
+
+// expandGlobs replaces each pattern with the files it matches, a pattern without matches is kept as it is
+func expandGlobs(patterns []string) ([]string, error) {
+	seen := map[string]bool{}
+	filenames := []string{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern `%s`: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			matches = []string{pattern}
+		}
+
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				filenames = append(filenames, m)
+			}
+		}
+	}
+
+	return filenames, nil
+}
+
+func analyzeAction(cliCtx *cli.Context) error {
+	log := zerolog.Ctx(cliCtx.Context).With().Str("command", "analyze").Logger()
+	ctx := log.WithContext(cliCtx.Context)
+
+	if cliCtx.NArg() == 0 {
+		return fmt.Errorf("analyze requires at least a file")
+	}
+
+	filenames, err := expandGlobs(cliCtx.Args().Slice())
+	if err != nil {
+		return err
+	}
+
+	write := analyze.WriteTable
+	switch analyzeFormat {
+	case "table":
+	case "json":
+		write = analyze.WriteJSON
+	case "html":
+		write = analyze.WriteHTML
+	default:
+		return fmt.Errorf("format `%s` is not supported", analyzeFormat)
+	}
+
+	estimators, err := flags.ParseEstimatorsFlag(analyzeEstims, analyzeSlen)
+	if err != nil {
+		return fmt.Errorf("cannot parse estimators flag: %w", err)
+	}
+
+	opts := []analyze.Opt{
+		analyze.WithSymbolLen(analyzeSlen),
+		analyze.WithTopSubstrs(analyzeLen, analyzeTopK),
+		analyze.WithEstimators(estimators...),
+	}
+	if analyzeChunk > 0 {
+		opts = append(opts, analyze.WithChunkSize(analyzeChunk))
+	}
+	if analyzeWorkers > 0 {
+		opts = append(opts, analyze.WithWorkers(analyzeWorkers))
+	}
+
+	log.Trace().
+		Strs("files", filenames).
+		Int("chunk", analyzeChunk).
+		Msg("analysing files")
+
+	read := func(ctx context.Context, filename string) ([]types.Bit, error) {
+		return readInputBits(ctx, filename, analyzeBinStr)
+	}
+
+	return write(os.Stdout, analyze.Files(ctx, filenames, read, opts...))
+}
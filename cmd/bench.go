@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+
+	"github.com/fedemengo/d2bist/pkg/bench"
+	"github.com/fedemengo/d2bist/pkg/compression"
+	"github.com/fedemengo/d2bist/pkg/flags"
+	iio "github.com/fedemengo/d2bist/pkg/io"
+)
+
+var (
+	benchAlgorithms = ""
+	benchJSON       = false
+	benchBinStr     = false
+)
+
+var benchCommand = &cli.Command{
+	Name:      "bench",
+	Usage:     "Compress the input with every algorithm at several levels and report ratio, throughput and memory",
+	ArgsUsage: "[FILE]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:        "algo",
+			Usage:       "comma separated algorithms to benchmark",
+			DefaultText: "all",
+			Destination: &benchAlgorithms,
+		}, &cli.BoolFlag{
+			Name:        "json",
+			Usage:       "output the results as json",
+			Destination: &benchJSON,
+		}, &cli.BoolFlag{
+			Name:        "binstr",
+			Usage:       "the input is a string of 0s and 1s",
+			Destination: &benchBinStr,
+		},
+	},
+	Action: benchAction,
+}
+
+func benchAction(cliCtx *cli.Context) error {
+	log := zerolog.Ctx(cliCtx.Context).With().Str("command", "bench").Logger()
+	ctx := log.WithContext(cliCtx.Context)
+
+	opts := []bench.Opt{}
+	if len(benchAlgorithms) > 0 {
+		algorithms := []compression.CompressionType{}
+		for _, name := range strings.Split(benchAlgorithms, ",") {
+			cType, err := flags.ParseCompressionFlag(strings.TrimSpace(name))
+			if err != nil {
+				return fmt.Errorf("cannot parse algorithms flag: %w", err)
+			}
+			if cType == compression.None || cType == compression.Auto {
+				return fmt.Errorf("algorithm `%s` is not supported", name)
+			}
+			algorithms = append(algorithms, cType)
+		}
+		opts = append(opts, bench.WithAlgorithms(algorithms...))
+	}
+
+	bits, err := readInputBits(ctx, cliCtx.Args().First(), benchBinStr)
+	if err != nil {
+		return err
+	}
+
+	var data bytes.Buffer
+	if err := iio.BitsToByteWriter(ctx, &data, bits); err != nil {
+		return err
+	}
+
+	log.Trace().
+		Int("bytes", data.Len()).
+		Msg("benchmarking compression")
+
+	results := bench.Run(ctx, data.Bytes(), opts...)
+	if benchJSON {
+		return bench.WriteJSON(os.Stdout, results)
+	}
+
+	return bench.WriteTable(os.Stdout, results)
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+
+	"github.com/fedemengo/d2bist/pkg/carve"
+)
+
+var (
+	carveBitOffsets = false
+	carveFormats    = ""
+	carveJSON       = false
+	carveBinStr     = false
+	carveOut        = ""
+)
+
+var carveCommand = &cli.Command{
+	Name:      "carve",
+	Usage:     "Find the compressed streams and files (gzip, zstd, bzip2, png, zip, elf, ...) embedded in the data",
+	ArgsUsage: "[FILE]",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:        "bits",
+			Usage:       "look for signatures at every bit offset, not only at byte offsets",
+			Destination: &carveBitOffsets,
+		}, &cli.StringFlag{
+			Name:        "format",
+			Usage:       "comma separated formats to look for: " + strings.Join(carve.Formats(), ", "),
+			DefaultText: "all",
+			Destination: &carveFormats,
+		}, &cli.BoolFlag{
+			Name:        "json",
+			Usage:       "output the streams as json",
+			Destination: &carveJSON,
+		}, &cli.BoolFlag{
+			Name:        "binstr",
+			Usage:       "the input is a string of 0s and 1s",
+			Destination: &carveBinStr,
+		}, &cli.StringFlag{
+			Name:        "out",
+			Usage:       "extract the streams to files in the directory",
+			Destination: &carveOut,
+		},
+	},
+	Action: carveAction,
+}
+
+func carveAction(cliCtx *cli.Context) error {
+	log := zerolog.Ctx(cliCtx.Context).With().Str("command", "carve").Logger()
+	ctx := log.WithContext(cliCtx.Context)
+
+	opts := []carve.Opt{}
+	if carveBitOffsets {
+		opts = append(opts, carve.WithBitOffsets())
+	}
+	if len(carveFormats) > 0 {
+		for _, name := range strings.Split(carveFormats, ",") {
+			opts = append(opts, carve.WithFormats(strings.TrimSpace(name)))
+		}
+	}
+
+	bits, err := readInputBits(ctx, cliCtx.Args().Get(0), carveBinStr)
+	if err != nil {
+		return err
+	}
+
+	streams, err := carve.Scan(ctx, bits, opts...)
+	if err != nil {
+		return err
+	}
+	log.Trace().Int("bits", len(bits)).Int("streams", len(streams)).Msg("carve done")
+
+	if carveJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(streams); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(os.Stdout, "%-12s %-12s %-10s %-10s %s\n", "bit offset", "byte offset", "size", "decoded", "format")
+		for _, s := range streams {
+			byteOffset := fmt.Sprintf("%d", s.Offset/8)
+			if s.Offset%8 != 0 {
+				byteOffset = fmt.Sprintf("%d+%d", s.Offset/8, s.Offset%8)
+			}
+			decoded := "-"
+			if s.Decoded > 0 {
+				decoded = fmt.Sprintf("%d", s.Decoded)
+			}
+			fmt.Fprintf(os.Stdout, "%-12d %-12s %-10d %-10s %s\n", s.Offset, byteOffset, s.Size, decoded, s.Format)
+		}
+	}
+
+	if len(carveOut) > 0 {
+		paths, err := carve.WriteFiles(carveOut, streams)
+		if err != nil {
+			return fmt.Errorf("cannot extract streams: %w", err)
+		}
+		log.Debug().Int("files", len(paths)).Str("dir", carveOut).Msg("streams extracted")
+	}
+
+	return nil
+}
@@ -11,10 +11,16 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/urfave/cli/v2"
 
+	"github.com/fedemengo/d2bist/pkg/analyze"
+	"github.com/fedemengo/d2bist/pkg/compression"
 	"github.com/fedemengo/d2bist/pkg/core"
+	"github.com/fedemengo/d2bist/pkg/dump"
 	"github.com/fedemengo/d2bist/pkg/flags"
 	"github.com/fedemengo/d2bist/pkg/image"
 	iio "github.com/fedemengo/d2bist/pkg/io"
+	"github.com/fedemengo/d2bist/pkg/report"
+	"github.com/fedemengo/d2bist/pkg/segment"
+	"github.com/fedemengo/d2bist/pkg/stats"
 	"github.com/fedemengo/d2bist/pkg/types"
 )
 
@@ -25,15 +31,41 @@ var (
 	maxBlockSize = 8
 	blockSize    = -1
 	symbolLen    = 2
+	estimatorsIn = ""
+	stride       = -1
+	approximate  = false
+
+	segmentName    = ""
+	segmentMethod  = ""
+	segmentPenalty = 0.0
 
 	readDataCap   = ""
+	readOffsets   = ""
+	readLengths   = ""
 	compressionIn = ""
+	dictIn        = ""
 
 	writeDataCap   = ""
 	compressionOut = ""
+	codecLevel     = -1
+	codecWindow    = 0
+	dictOut        = ""
+
+	lineDecode = ""
+	lineEncode = ""
+
+	extractor    = ""
+	foldSize     = 2
+	toeplitzIn   = 64
+	toeplitzOut  = 32
+	toeplitzSeed = int64(0)
 
 	pngFileName   = ""
 	pixelLen      = 1
+	heatmapName   = ""
+	heatmapMode   = ""
+	plotPath      = ""
+	reportPath    = ""
 	separatorRune = rune(0)
 	count         = 8
 )
@@ -50,17 +82,51 @@ func init() {
 			Name:        "compression",
 			Aliases:     []string{"c"},
 			Usage:       "specify the compression algorithm to compress the output data",
-			DefaultText: "auto",
+			DefaultText: "none",
 			Destination: &compressionOut,
+		}, &cli.IntFlag{
+			Name:        "level",
+			Value:       -1,
+			Usage:       "compression level of the output codec (brotli quality, zstd 1-4, s2 1-3, cm context order)",
+			DefaultText: "codec default",
+			Destination: &codecLevel,
+		}, &cli.IntFlag{
+			Name:        "window",
+			Usage:       "base 2 logarithm of the output codec window (brotli lgwin 10-24, zstd 10-29)",
+			DefaultText: "auto",
+			Destination: &codecWindow,
+		}, &cli.StringFlag{
+			Name:        "dict",
+			Usage:       "dictionary file of the output codec (zip, zstd)",
+			Destination: &dictOut,
 		}, &cli.StringFlag{
 			Name:        "png",
 			Usage:       "write bit string to png file",
 			Destination: &pngFileName,
 		}, &cli.IntFlag{
 			Name:        "plen",
+			Value:       1,
 			Usage:       "length of a pixel in bits",
 			DefaultText: "1",
 			Destination: &pixelLen,
+		}, &cli.StringFlag{
+			Name:        "heatmap",
+			Usage:       "colour the png by the entropy of each chunk (shannon or a codec, e.g. gzip, xz), requires --chunk",
+			Destination: &heatmapName,
+		}, &cli.StringFlag{
+			Name:        "heatmode",
+			Usage:       "draw the heatmap over the bits (overlay) or next to them (side)",
+			DefaultText: "overlay",
+			Destination: &heatmapMode,
+		}, &cli.StringFlag{
+			Name:        "plot",
+			Usage:       "write the entropy chart to path, the extension selects the format (png, svg, csv, json)",
+			DefaultText: "none",
+			Destination: &plotPath,
+		}, &cli.StringFlag{
+			Name:        "report",
+			Usage:       "write a self-contained html report with the bit image, entropy, compression and substrings",
+			Destination: &reportPath,
 		}, &cli.StringFlag{
 			Name:        "sep",
 			Usage:       "separator to make the bin string more readable",
@@ -81,28 +147,99 @@ func init() {
 			DefaultText: "8",
 		}, &cli.IntFlag{
 			Name:        "topk",
+			Value:       -1,
 			Aliases:     []string{"k"},
 			Usage:       "output the top k most frequent substrings",
 			Destination: &topKOutput,
 			DefaultText: "all",
 		}, &cli.IntFlag{
 			Name:        "maxchunk",
+			Value:       8,
 			Usage:       "max chunk size of bits consider when counting substrings",
 			Destination: &maxBlockSize,
 			DefaultText: "8",
 		}, &cli.IntFlag{
 			Name:        "chunk",
+			Value:       -1,
 			Usage:       "exact chunk size to consider when counting substrings",
 			Destination: &blockSize,
 		}, &cli.IntFlag{
 			Name:        "slen",
+			Value:       2,
 			Usage:       "length of unitary symbol used when calculating data entropy",
 			Destination: &symbolLen,
+		}, &cli.StringFlag{
+			Name:        "estimators",
+			Usage:       "comma separated entropy estimators (shannon, lz76 or a codec with optional parameters, e.g. zstd:level=4:window=23), replace the default ones",
+			DefaultText: "gzip, brotli, bzip2 and shannon",
+			Destination: &estimatorsIn,
+		}, &cli.IntFlag{
+			Name:        "stride",
+			Value:       -1,
+			Usage:       "bits between the start of consecutive entropy windows, windows overlap when smaller than the chunk",
+			DefaultText: "chunk",
+			Destination: &stride,
+		}, &cli.StringFlag{
+			Name:        "segment",
+			Usage:       "split the bits in regions where the entropy changes (shannon or a codec, e.g. gzip, xz), requires --chunk",
+			Destination: &segmentName,
+		}, &cli.StringFlag{
+			Name:        "segmethod",
+			Usage:       "change-point detection method of the regions (pelt, binseg, cusum)",
+			DefaultText: "pelt",
+			Destination: &segmentMethod,
+		}, &cli.Float64Flag{
+			Name:        "penalty",
+			Usage:       "how large an entropy change must be to start a region, in units of the noise",
+			DefaultText: "3*ln(windows) for pelt and binseg, 8 for cusum",
+			Destination: &segmentPenalty,
+		}, &cli.BoolFlag{
+			Name:        "approx",
+			Usage:       "estimate the most frequent substrings in bounded memory (count-min sketch)",
+			Destination: &approximate,
 		}, &cli.BoolFlag{
 			Name:        "stats",
 			Aliases:     []string{"s"},
 			Usage:       "output bits distribution stats",
 			Destination: &printStats,
+		}, &cli.StringFlag{
+			Name:        "linedec",
+			Usage:       "remove a line code from the bits (nrzi, manchester, thomas, dm, hdlc, usb)",
+			DefaultText: "none",
+			Destination: &lineDecode,
+		}, &cli.StringFlag{
+			Name:        "lineenc",
+			Usage:       "apply a line code to the bits (nrzi, manchester, thomas, dm, hdlc, usb)",
+			DefaultText: "none",
+			Destination: &lineEncode,
+		}, &cli.StringFlag{
+			Name:        "extract",
+			Usage:       "debias the bits with a randomness extractor (vn, peres, xor, toeplitz)",
+			DefaultText: "none",
+			Destination: &extractor,
+		}, &cli.IntFlag{
+			Name:        "fold",
+			Value:       2,
+			Usage:       "number of bits XORed together by the xor extractor",
+			DefaultText: "2",
+			Destination: &foldSize,
+		}, &cli.IntFlag{
+			Name:        "tin",
+			Value:       64,
+			Usage:       "input block length of the toeplitz extractor",
+			DefaultText: "64",
+			Destination: &toeplitzIn,
+		}, &cli.IntFlag{
+			Name:        "tout",
+			Value:       32,
+			Usage:       "output block length of the toeplitz extractor",
+			DefaultText: "32",
+			Destination: &toeplitzOut,
+		}, &cli.Int64Flag{
+			Name:        "seed",
+			Usage:       "seed used to generate the toeplitz matrix",
+			DefaultText: "0",
+			Destination: &toeplitzSeed,
 		}, &cli.BoolFlag{
 			Name:        "str",
 			Usage:       "the output will be a string of 0s and 1s",
@@ -110,6 +247,9 @@ func init() {
 		},
 	}
 
+	flags = append(flags, chartFlags...)
+	flags = append(flags, dumpFlags()...)
+
 	app = &cli.App{
 		Suggest:              true,
 		EnableBashCompletion: true,
@@ -121,12 +261,26 @@ func init() {
 				Name:        "rcap",
 				Usage:       "cap the amount of data to read before processing",
 				Destination: &readDataCap,
+			}, &cli.StringFlag{
+				Name:        "offset",
+				Usage:       "comma separated offsets of the regions to read, in bits or bytes (1K, 512B), files are read from the offsets",
+				DefaultText: "0",
+				Destination: &readOffsets,
+			}, &cli.StringFlag{
+				Name:        "length",
+				Usage:       "comma separated lengths of the regions to read, one for every offset or one for all",
+				DefaultText: "to the end",
+				Destination: &readLengths,
 			}, &cli.StringFlag{
 				Name:        "compression",
 				Aliases:     []string{"c"},
-				Usage:       "specify the compression algorithm to decompress the input data",
-				DefaultText: "auto",
+				Usage:       "specify the compression algorithm to decompress the input data, auto detects it",
+				DefaultText: "none",
 				Destination: &compressionIn,
+			}, &cli.StringFlag{
+				Name:        "dict",
+				Usage:       "dictionary file of the codec that decompresses the input data (zip, zstd)",
+				Destination: &dictIn,
 			},
 		},
 		Commands: []*cli.Command{
@@ -144,6 +298,16 @@ func init() {
 				Flags:   flags,
 				Action:  encode,
 			},
+			intcodeCommand,
+			searchCommand,
+			repeatsCommand,
+			dotPlotCommand,
+			plotCommand,
+			analyzeCommand,
+			benchCommand,
+			carveCommand,
+			splitCommand,
+			parseCommand,
 		},
 	}
 }
@@ -161,7 +325,7 @@ func Run() {
 func logger() zerolog.Logger {
 	level := zerolog.ErrorLevel
 	l, err := zerolog.ParseLevel(os.Getenv("LOG_LEVEL"))
-	if err == nil {
+	if err == nil && l != zerolog.NoLevel {
 		level = l
 	}
 
@@ -185,18 +349,68 @@ func OptsFromFlags(_ context.Context) ([]core.Opt, error) {
 	} else if maxBits > 0 {
 		options = append(options, core.WithInBitsCap(maxBits))
 	}
+	if ranges, err := flags.ParseBitRangesFlag(readOffsets, readLengths); err != nil {
+		return nil, fmt.Errorf("cannot parse offset and length flags: %w", err)
+	} else if len(ranges) > 0 {
+		options = append(options, core.WithInRanges(ranges...))
+	}
 	if maxBits, err := flags.ParseDataCapToBitsCount(writeDataCap); err != nil {
 		return nil, fmt.Errorf("cannot parse data cap flag")
 	} else if maxBits > 0 {
 		options = append(options, core.WithOutBitsCap(maxBits))
 	}
 
-	cInType := flags.ParseCompressionFlag(compressionIn)
+	cInType, err := flags.ParseCompressionFlag(compressionIn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse compression flag: %w", err)
+	}
 	options = append(options, core.WithInCompression(cInType))
 
-	cOutType := flags.ParseCompressionFlag(compressionOut)
+	inCodecOpts, err := codecOptsFromFlags(-1, 0, dictIn)
+	if err != nil {
+		return nil, err
+	}
+	options = append(options, core.WithInCodecOpts(inCodecOpts...))
+
+	cOutType, err := flags.ParseCompressionFlag(compressionOut)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse compression flag: %w", err)
+	}
+	if cOutType == compression.Auto {
+		return nil, fmt.Errorf("the output compression cannot be detected")
+	}
+	// the entropy is of the bits before the output compression, the png shows the compressed bits
+	if cOutType != compression.None && len(heatmapName) > 0 {
+		return nil, fmt.Errorf("heatmap cannot colour the compressed output (--compression)")
+	}
 	options = append(options, core.WithOutCompression(cOutType))
 
+	outCodecOpts, err := codecOptsFromFlags(codecLevel, codecWindow, dictOut)
+	if err != nil {
+		return nil, err
+	}
+	options = append(options, core.WithOutCodecOpts(outCodecOpts...))
+
+	ldCode, err := flags.ParseLineCodeFlag(lineDecode)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse line decode flag: %w", err)
+	}
+	leCode, err := flags.ParseLineCodeFlag(lineEncode)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse line encode flag: %w", err)
+	}
+	options = append(options, core.WithLineDecode(ldCode), core.WithLineEncode(leCode))
+
+	eType, err := flags.ParseExtractorFlag(extractor)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse extractor flag: %w", err)
+	}
+	options = append(options,
+		core.WithExtractor(eType),
+		core.WithExtractFoldSize(foldSize),
+		core.WithExtractToeplitz(toeplitzIn, toeplitzOut, toeplitzSeed),
+	)
+
 	if blockSize > 0 {
 		options = append(options, core.WithStatsBlockSize(blockSize))
 		if symbolLen > blockSize {
@@ -206,6 +420,27 @@ func OptsFromFlags(_ context.Context) ([]core.Opt, error) {
 			return nil, fmt.Errorf("entropy chunk size must be a multiple of block size")
 		}
 		options = append(options, core.WithStatsSymbolLen(symbolLen))
+
+		estimators, err := flags.ParseEstimatorsFlag(estimatorsIn, symbolLen)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse estimators flag: %w", err)
+		}
+		options = append(options, core.WithStatsEstimators(estimators...))
+
+		if stride > 0 {
+			if stride > blockSize {
+				return nil, fmt.Errorf("stride cannot be greater than block size")
+			}
+			options = append(options, core.WithStatsStride(stride))
+		}
+
+		segmentOpts, err := segmentOptsFromFlags()
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, segmentOpts...)
+	} else if len(segmentName) > 0 {
+		return nil, fmt.Errorf("segment requires the chunk size (--chunk)")
 	} else {
 		options = append(options, core.WithStatsMaxBlockSize(maxBlockSize))
 	}
@@ -214,11 +449,65 @@ func OptsFromFlags(_ context.Context) ([]core.Opt, error) {
 		options = append(options, core.WithStatsTopK(topKOutput))
 	}
 
-	options = append(options, core.WithEntropyPlotName(fmt.Sprintf("entropy-%d", time.Now().Unix())))
+	if approximate {
+		options = append(options, core.WithStatsApproximate(stats.DefaultSketchWidth, stats.DefaultSketchDepth))
+	}
+
+	if len(plotPath) > 0 {
+		options = append(options, core.WithEntropyPlotPath(plotPath))
+	}
+
+	chartOpts, err := chartOptsFromFlags()
+	if err != nil {
+		return nil, err
+	}
+	options = append(options, core.WithEntropyChartOpts(chartOpts...))
 
 	return options, nil
 }
 
+// segmentOptsFromFlags splits the bits in regions when --segment is set
+func segmentOptsFromFlags() ([]core.Opt, error) {
+	if len(segmentName) == 0 {
+		return nil, nil
+	}
+
+	eType, err := flags.ParseEntropyFlag(segmentName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse segment flag: %w", err)
+	}
+
+	method, err := flags.ParseSegmentMethodFlag(segmentMethod)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse segmethod flag: %w", err)
+	}
+
+	return []core.Opt{core.WithSegments(eType, segment.WithMethod(method), segment.WithPenalty(segmentPenalty))}, nil
+}
+
+// codecOptsFromFlags tunes a codec, a negative level or a zero window keep the codec defaults
+func codecOptsFromFlags(level, window int, dictPath string) ([]compression.Opt, error) {
+	opts := []compression.Opt{}
+
+	if level >= 0 {
+		opts = append(opts, compression.WithLevel(level))
+	}
+
+	if window > 0 {
+		opts = append(opts, compression.WithWindow(window))
+	}
+
+	if len(dictPath) > 0 {
+		dict, err := os.ReadFile(dictPath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read dictionary: %w", err)
+		}
+		opts = append(opts, compression.WithDictionary(dict))
+	}
+
+	return opts, nil
+}
+
 type operation func(context.Context, io.Reader, ...core.Opt) (*types.Result, error)
 
 // decode read data and decodes it to the binary string
@@ -269,22 +558,114 @@ func process(ctx context.Context, filename string, op operation) error {
 	log.Trace().Int("bits", len(res.Bits)).Msg("encoded bits")
 
 	if printStats {
-		res.Stats.RenderStats(os.Stderr)
+		if err := res.Stats.RenderStats(os.Stderr); err != nil {
+			return err
+		}
 	}
 
-	if len(pngFileName) > 0 {
-		if pixelLen == 0 {
-			pixelLen = 1
+	if pixelLen == 0 {
+		pixelLen = 1
+	}
+
+	imgOpts, err := imageOptsFromFlags(res.Stats)
+	if err != nil {
+		return err
+	}
+
+	if len(reportPath) > 0 {
+		if err := writeReport(ctx, filename, res, imgOpts); err != nil {
+			return fmt.Errorf("cannot write report: %w", err)
 		}
-		return image.WriteToPNG(res.Bits, pngFileName, pixelLen)
+	}
+
+	if len(pngFileName) > 0 {
+		return image.WriteToPNG(res.Bits, pngFileName, pixelLen, imgOpts...)
 	}
 
 	return nil
 }
 
+func writeReport(ctx context.Context, filename string, res *types.Result, imgOpts []image.Opt) error {
+	title := filename
+	if len(title) == 0 {
+		title = "stdin"
+	}
+
+	chartOpts, err := chartOptsFromFlags()
+	if err != nil {
+		return err
+	}
+
+	estimators, err := flags.ParseEstimatorsFlag(estimatorsIn, symbolLen)
+	if err != nil {
+		return fmt.Errorf("cannot parse estimators flag: %w", err)
+	}
+
+	return report.WriteFile(ctx, reportPath, res,
+		report.WithTitle(title),
+		report.WithPixelLen(pixelLen),
+		report.WithImageOpts(imgOpts...),
+		report.WithChartOpts(chartOpts...),
+		report.WithAnalyzeOpts(analyze.WithSymbolLen(symbolLen), analyze.WithEstimators(estimators...)),
+	)
+}
+
+func imageOptsFromFlags(s *types.Stats) ([]image.Opt, error) {
+	if len(heatmapName) == 0 {
+		return nil, nil
+	}
+
+	if blockSize <= 0 {
+		return nil, fmt.Errorf("heatmap requires the chunk size (--chunk)")
+	}
+
+	eType, err := flags.ParseEntropyFlag(heatmapName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse heatmap flag: %w", err)
+	}
+
+	mode, err := flags.ParseHeatmapModeFlag(heatmapMode)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse heatmode flag: %w", err)
+	}
+
+	for _, e := range s.Entropy {
+		if e.Name == eType {
+			return []image.Opt{image.WithHeatmap(e.Values, entropyStride(), mode)}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("entropy `%s` was not calculated", eType)
+}
+
+// entropyStride is the bits between entropy windows, each value of the heatmap colours as many bits
+func entropyStride() int {
+	if stride > 0 {
+		return stride
+	}
+
+	return blockSize
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 func outputBinaryString(ctx context.Context, bits []types.Bit) error {
 	var err error
-	if outputString || isatty.IsTerminal(os.Stdout.Fd()) {
+	if dumpOutput {
+		err = dump.Write(os.Stdout, bits, dumpOptsFromFlags(pixelLen)...)
+	} else if outputString || isatty.IsTerminal(os.Stdout.Fd()) {
 		opts := []iio.Opt{
 			iio.WithSep(separatorRune),
 			iio.WithSepDistance(count),
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"image/color"
+
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+
+	"github.com/fedemengo/d2bist/pkg/image"
+)
+
+var (
+	dotPlotBlockLen = 8
+	dotPlotSize     = image.DefaultDotPlotSize
+	dotPlotStep     = 1
+	dotPlotReverse  = false
+	dotPlotBinStr   = false
+	dotPlotPNG      = "dotplot"
+)
+
+var reverseColor = color.RGBA{R: 255, G: 0, B: 0, A: 255}
+
+var dotPlotCommand = &cli.Command{
+	Name:      "dotplot",
+	Usage:     "Render the self-similarity dot plot of the blocks of k bits",
+	ArgsUsage: "[FILE]",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:        "block",
+			Aliases:     []string{"k"},
+			Usage:       "length of the compared blocks in bits",
+			Value:       dotPlotBlockLen,
+			Destination: &dotPlotBlockLen,
+		}, &cli.IntFlag{
+			Name:        "size",
+			Usage:       "max side of the image in pixels, each pixel is a cell of blocks when there are more",
+			Value:       dotPlotSize,
+			Destination: &dotPlotSize,
+		}, &cli.IntFlag{
+			Name:        "step",
+			Usage:       "distance in bits between compared blocks, use the block length for aligned blocks",
+			Value:       dotPlotStep,
+			Destination: &dotPlotStep,
+		}, &cli.BoolFlag{
+			Name:        "reverse",
+			Aliases:     []string{"r"},
+			Usage:       "highlight in red the blocks equal to another block reversed",
+			Destination: &dotPlotReverse,
+		}, &cli.BoolFlag{
+			Name:        "binstr",
+			Usage:       "the input is a string of 0s and 1s",
+			Destination: &dotPlotBinStr,
+		}, &cli.StringFlag{
+			Name:        "png",
+			Usage:       "name of the png file",
+			Value:       dotPlotPNG,
+			Destination: &dotPlotPNG,
+		},
+	},
+	Action: dotPlotAction,
+}
+
+func dotPlotAction(cliCtx *cli.Context) error {
+	log := zerolog.Ctx(cliCtx.Context).With().Str("command", "dotplot").Logger()
+	ctx := log.WithContext(cliCtx.Context)
+
+	bits, err := readInputBits(ctx, cliCtx.Args().First(), dotPlotBinStr)
+	if err != nil {
+		return err
+	}
+
+	opts := []image.DotPlotOpt{
+		image.WithDotPlotSize(dotPlotSize),
+		image.WithDotPlotStep(dotPlotStep),
+	}
+	if dotPlotReverse {
+		opts = append(opts, image.WithReverseMatches(reverseColor))
+	}
+
+	log.Trace().
+		Int("bits", len(bits)).
+		Int("block", dotPlotBlockLen).
+		Int("size", dotPlotSize).
+		Int("step", dotPlotStep).
+		Msg("rendering dot plot")
+
+	return image.WriteDotPlot(bits, dotPlotPNG, dotPlotBlockLen, opts...)
+}
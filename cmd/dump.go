@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"github.com/urfave/cli/v2"
+
+	"github.com/fedemengo/d2bist/pkg/dump"
+)
+
+var (
+	dumpOutput = false
+	dumpRow    = dump.DefaultBitsPerRow
+	dumpGroup  = dump.DefaultGroup
+	dumpColor  = false
+)
+
+// dumpFlags select and style the dump output of every command that prints it, each command
+// gets its own flags so that parsing the flags of one does not reset the destinations of another
+func dumpFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:        "dump",
+			Usage:       "output the bits like `xxd -b`, with offsets, hex and ascii",
+			Destination: &dumpOutput,
+		}, &cli.IntFlag{
+			Name:        "row",
+			Value:       dumpRow,
+			Usage:       "bits per row of the dump",
+			Destination: &dumpRow,
+		}, &cli.IntFlag{
+			Name:        "group",
+			Value:       dumpGroup,
+			Usage:       "bits per group in the rows of the dump",
+			Destination: &dumpGroup,
+		}, &cli.BoolFlag{
+			Name:        "color",
+			Usage:       "colour the bits of the dump like the png pixels (--plen)",
+			Destination: &dumpColor,
+		},
+	}
+}
+
+// dumpOptsFromFlags parses the flags that style the dump, pixelLen is the length of the coloured pixels
+func dumpOptsFromFlags(pixelLen int) []dump.Opt {
+	opts := []dump.Opt{
+		dump.WithBitsPerRow(dumpRow),
+		dump.WithGroup(dumpGroup),
+	}
+	if dumpColor {
+		if pixelLen == 0 {
+			pixelLen = 1
+		}
+		opts = append(opts, dump.WithColor(pixelLen))
+	}
+
+	return opts
+}
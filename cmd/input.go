@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fedemengo/d2bist/pkg/core"
+	"github.com/fedemengo/d2bist/pkg/flags"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+// openInput opens filename, or stdin when no filename is given
+func openInput(filename string) (io.Reader, func(), error) {
+	if len(filename) == 0 {
+		return os.Stdin, func() {}, nil
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return f, func() { f.Close() }, nil
+}
+
+// inputOptsFromFlags parses the global flags that control how the input is read
+func inputOptsFromFlags() ([]core.Opt, error) {
+	options := []core.Opt{}
+
+	if maxBits, err := flags.ParseDataCapToBitsCount(readDataCap); err != nil {
+		return nil, fmt.Errorf("cannot parse data cap flag")
+	} else if maxBits > 0 {
+		options = append(options, core.WithInBitsCap(maxBits))
+	}
+
+	if ranges, err := flags.ParseBitRangesFlag(readOffsets, readLengths); err != nil {
+		return nil, fmt.Errorf("cannot parse offset and length flags: %w", err)
+	} else if len(ranges) > 0 {
+		options = append(options, core.WithInRanges(ranges...))
+	}
+
+	cInType, err := flags.ParseCompressionFlag(compressionIn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse compression flag: %w", err)
+	}
+	options = append(options, core.WithInCompression(cInType))
+
+	inCodecOpts, err := codecOptsFromFlags(-1, 0, dictIn)
+	if err != nil {
+		return nil, err
+	}
+	options = append(options, core.WithInCodecOpts(inCodecOpts...))
+
+	return options, nil
+}
+
+// readInputBits reads the bits of filename, or stdin, without analysing them
+//
+// when binStr is set the input is a string of 0s and 1s
+func readInputBits(ctx context.Context, filename string, binStr bool) ([]types.Bit, error) {
+	r, closeInput, err := openInput(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer closeInput()
+
+	opts, err := inputOptsFromFlags()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing input flags: %w", err)
+	}
+
+	if binStr {
+		return core.ReadBinStrBits(ctx, r, opts...)
+	}
+
+	return core.ReadBits(ctx, r, opts...)
+}
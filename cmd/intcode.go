@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+
+	"github.com/fedemengo/d2bist/pkg/flags"
+	"github.com/fedemengo/d2bist/pkg/intcode"
+	iio "github.com/fedemengo/d2bist/pkg/io"
+)
+
+var (
+	intCode      = "gamma"
+	intCodeParam = uint64(4)
+	intDecode    = false
+)
+
+var intcodeCommand = &cli.Command{
+	Name:      "intcode",
+	Usage:     "Encode decimal integers with a universal code, or decode a binary string to integers",
+	ArgsUsage: "[FILE]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:        "code",
+			Usage:       "integer code (unary, gamma, delta, omega, fib, golomb, rice, lev)",
+			Value:       intCode,
+			Destination: &intCode,
+		}, &cli.Uint64Flag{
+			Name:        "m",
+			Usage:       "parameter of golomb and rice codes",
+			Value:       intCodeParam,
+			Destination: &intCodeParam,
+		}, &cli.BoolFlag{
+			Name:        "reverse",
+			Aliases:     []string{"r"},
+			Usage:       "decode a binary string to decimal integers",
+			Destination: &intDecode,
+		},
+	},
+	Action: intcodeAction,
+}
+
+func intcodeAction(cliCtx *cli.Context) error {
+	log := zerolog.Ctx(cliCtx.Context).With().Str("command", "intcode").Logger()
+	ctx := log.WithContext(cliCtx.Context)
+
+	code, err := flags.ParseIntCodeFlag(intCode)
+	if err != nil {
+		return fmt.Errorf("cannot parse code flag: %w", err)
+	}
+
+	r, closeInput, err := openInput(cliCtx.Args().First())
+	if err != nil {
+		return err
+	}
+	defer closeInput()
+
+	opts := []intcode.Opt{intcode.WithParameter(intCodeParam)}
+	if intDecode {
+		return decodeInts(ctx, r, code, opts...)
+	}
+
+	return encodeInts(r, code, opts...)
+}
+
+func encodeInts(r io.Reader, code intcode.Code, opts ...intcode.Opt) error {
+	values := []uint64{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		n, err := strconv.ParseUint(scanner.Text(), 10, 64)
+		if err != nil {
+			return fmt.Errorf("cannot parse integer: %w", err)
+		}
+		values = append(values, n)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	bits, err := intcode.Encode(values, code, opts...)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stdout, iio.BitsToString(bits))
+
+	return nil
+}
+
+func decodeInts(ctx context.Context, r io.Reader, code intcode.Code, opts ...intcode.Opt) error {
+	bits, err := iio.BitsFromBinStrReader(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	values, err := intcode.Decode(bits, code, opts...)
+	for _, n := range values {
+		fmt.Fprintln(os.Stdout, n)
+	}
+
+	return err
+}
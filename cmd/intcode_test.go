@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+
+	"github.com/fedemengo/d2bist/pkg/intcode"
+	iio "github.com/fedemengo/d2bist/pkg/io"
+)
+
+// captureStdout returns what f writes to stdout
+func captureStdout(t *testing.T, f func() error) string {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+
+	require.NoError(t, f())
+	w.Close()
+
+	return <-out
+}
+
+func TestIntcodeDefaults(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "ints")
+	require.NoError(t, os.WriteFile(input, []byte("1 2 3 5"), 0o644))
+
+	rice, err := intcode.Encode([]uint64{1, 2, 3, 5}, intcode.Rice, intcode.WithParameter(4))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "no flags",
+			args:     []string{input},
+			expected: "1010011" + "00101",
+		}, {
+			name:     "rice without m",
+			args:     []string{"--code", "rice", input},
+			expected: iio.BitsToString(rice),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			app := &cli.App{Commands: []*cli.Command{intcodeCommand}}
+			out := captureStdout(tt, func() error {
+				return app.Run(append([]string{"d2bist", "intcode"}, tc.args...))
+			})
+
+			assert.Equal(tt, tc.expected, strings.TrimSpace(out))
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+
+	"github.com/fedemengo/d2bist/pkg/dump"
+	"github.com/fedemengo/d2bist/pkg/schema"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+var (
+	parseSchema = ""
+	parseCount  = 0
+	parseJSON   = false
+	parseBinStr = false
+)
+
+var parseCommand = &cli.Command{
+	Name:      "parse",
+	Usage:     "Decode the bits in records of fields of any width (uint, int, bool, enum, string) described by a yaml or json schema",
+	ArgsUsage: "[FILE]",
+	Flags: append([]cli.Flag{
+		&cli.StringFlag{
+			Name:        "schema",
+			Usage:       "yaml or json file with the fields of a record",
+			Required:    true,
+			Destination: &parseSchema,
+		}, &cli.IntFlag{
+			Name:        "count",
+			Usage:       "max number of records to parse",
+			DefaultText: "until the bits end",
+			Destination: &parseCount,
+		}, &cli.BoolFlag{
+			Name:        "json",
+			Usage:       "output the records as json",
+			Destination: &parseJSON,
+		}, &cli.BoolFlag{
+			Name:        "binstr",
+			Usage:       "the input is a string of 0s and 1s",
+			Destination: &parseBinStr,
+		},
+	}, dumpFlags()...),
+	Action: parseAction,
+}
+
+func parseAction(cliCtx *cli.Context) error {
+	log := zerolog.Ctx(cliCtx.Context).With().Str("command", "parse").Logger()
+	ctx := log.WithContext(cliCtx.Context)
+
+	s, err := schema.Load(parseSchema)
+	if err != nil {
+		return fmt.Errorf("cannot load schema: %w", err)
+	}
+
+	bits, err := readInputBits(ctx, cliCtx.Args().First(), parseBinStr)
+	if err != nil {
+		return err
+	}
+
+	res, err := schema.Parse(bits, s, schema.WithMaxRecords(parseCount))
+	if err != nil {
+		return err
+	}
+	log.Trace().Int("bits", len(bits)).Int("records", len(res.Records)).Int("unused", res.Unused.Length).Msg("parse done")
+
+	if parseJSON {
+		return schema.WriteJSON(os.Stdout, res)
+	}
+
+	if dumpOutput {
+		return dump.Write(os.Stdout, bits, append(dumpOptsFromFlags(1), fieldHighlights(res)...)...)
+	}
+
+	return schema.WriteTable(os.Stdout, res)
+}
+
+// fieldColor alternates with highlightColor between consecutive fields of the dump
+var fieldColor = color.RGBA{R: 0, G: 0, B: 255, A: 255}
+
+// fieldHighlights highlights the parsed fields, consecutive fields in alternate colours, or styles without --color
+func fieldHighlights(res *schema.Result) []dump.Opt {
+	ranges := [2][]types.Range{}
+	i := 0
+	for _, r := range res.Records {
+		for _, v := range r.Fields {
+			ranges[i%2] = append(ranges[i%2], types.Range{Offset: v.Offset, Length: v.Width})
+			i++
+		}
+	}
+
+	return []dump.Opt{
+		dump.WithHighlights(ranges[0], highlightColor),
+		dump.WithHighlights(ranges[1], fieldColor),
+	}
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+
+	"github.com/fedemengo/d2bist/pkg/flags"
+	"github.com/fedemengo/d2bist/pkg/stats"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+var (
+	chartTitle  = ""
+	chartSize   = ""
+	chartXRange = ""
+	chartYRange = ""
+	chartLogX   = false
+	chartSmooth = 0
+
+	plotChunk   = -1
+	plotSlen    = 2
+	plotEstims  = ""
+	plotStride  = -1
+	plotBinStr  = false
+	plotOutPath = ""
+)
+
+// chartFlags style the entropy chart of every command that plots it
+var chartFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:        "title",
+		Usage:       "title of the entropy chart",
+		DefaultText: "data entropy",
+		Destination: &chartTitle,
+	}, &cli.StringFlag{
+		Name:        "plotsize",
+		Usage:       "size of the entropy chart in pixels, as WxH",
+		DefaultText: "1300x800",
+		Destination: &chartSize,
+	}, &cli.StringFlag{
+		Name:        "xrange",
+		Usage:       "bit offsets shown in the entropy chart, as min:max",
+		DefaultText: "all",
+		Destination: &chartXRange,
+	}, &cli.StringFlag{
+		Name:        "yrange",
+		Usage:       "entropy shown in the entropy chart, as min:max",
+		DefaultText: "0:1",
+		Destination: &chartYRange,
+	}, &cli.BoolFlag{
+		Name:        "logx",
+		Usage:       "use a logarithmic x axis in the entropy chart",
+		Destination: &chartLogX,
+	}, &cli.IntFlag{
+		Name:        "smooth",
+		Usage:       "plot the moving average of this many chunks",
+		DefaultText: "none",
+		Destination: &chartSmooth,
+	},
+}
+
+var plotCommand = &cli.Command{
+	Name:      "plot",
+	Usage:     "Plot the entropy of one or more inputs in the same chart",
+	ArgsUsage: "[FILE...]",
+	Flags: append([]cli.Flag{
+		&cli.IntFlag{
+			Name:        "chunk",
+			Usage:       "size in bits of the chunks the entropy is calculated on",
+			Required:    true,
+			Destination: &plotChunk,
+		}, &cli.IntFlag{
+			Name:        "slen",
+			Value:       plotSlen,
+			Usage:       "length of unitary symbol used when calculating data entropy",
+			Destination: &plotSlen,
+		}, &cli.StringFlag{
+			Name:        "estimators",
+			Usage:       "comma separated entropy estimators (shannon, lz76 or a codec with optional parameters, e.g. zstd:level=4:window=23), replace the default ones",
+			DefaultText: "gzip, brotli, bzip2 and shannon",
+			Destination: &plotEstims,
+		}, &cli.IntFlag{
+			Name:        "stride",
+			Value:       plotStride,
+			Usage:       "bits between the start of consecutive entropy windows, windows overlap when smaller than the chunk",
+			DefaultText: "chunk",
+			Destination: &plotStride,
+		}, &cli.BoolFlag{
+			Name:        "binstr",
+			Usage:       "the inputs are strings of 0s and 1s",
+			Destination: &plotBinStr,
+		}, &cli.StringFlag{
+			Name:        "plot",
+			Usage:       "write the entropy chart to path, the extension selects the format (png, svg, csv, json)",
+			DefaultText: "entropy-<unix time>.png",
+			Destination: &plotOutPath,
+		},
+	}, chartFlags...),
+	Action: plotAction,
+}
+
+// chartOptsFromFlags parses the flags that style the entropy chart
+func chartOptsFromFlags() ([]types.ChartOpt, error) {
+	opts := []types.ChartOpt{}
+
+	if len(chartTitle) > 0 {
+		opts = append(opts, types.WithChartTitle(chartTitle))
+	}
+
+	if len(chartSize) > 0 {
+		w, h, err := flags.ParseSizeFlag(chartSize)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse plot size flag: %w", err)
+		}
+		opts = append(opts, types.WithChartSize(w, h))
+	}
+
+	if len(chartXRange) > 0 {
+		min, max, err := flags.ParseRangeFlag(chartXRange)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse x range flag: %w", err)
+		}
+		opts = append(opts, types.WithChartXRange(min, max))
+	}
+
+	if len(chartYRange) > 0 {
+		min, max, err := flags.ParseRangeFlag(chartYRange)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse y range flag: %w", err)
+		}
+		opts = append(opts, types.WithChartYRange(min, max))
+	}
+
+	if chartLogX {
+		opts = append(opts, types.WithChartLogX())
+	}
+
+	if chartSmooth > 1 {
+		opts = append(opts, types.WithChartSmoothing(chartSmooth))
+	}
+
+	return opts, nil
+}
+
+// defaultPlotPath is where the plot command writes the chart when --plot is not set
+func defaultPlotPath() string {
+	return fmt.Sprintf("entropy-%d.png", time.Now().Unix())
+}
+
+func plotAction(cliCtx *cli.Context) error {
+	log := zerolog.Ctx(cliCtx.Context).With().Str("command", "plot").Logger()
+	ctx := log.WithContext(cliCtx.Context)
+
+	if plotChunk < 1 || plotSlen < 1 || plotChunk%plotSlen != 0 {
+		return fmt.Errorf("chunk size must be a positive multiple of the symbol length")
+	}
+	if plotStride > plotChunk {
+		return fmt.Errorf("stride cannot be greater than the chunk size")
+	}
+
+	chartOpts, err := chartOptsFromFlags()
+	if err != nil {
+		return err
+	}
+
+	estimators, err := flags.ParseEstimatorsFlag(plotEstims, plotSlen)
+	if err != nil {
+		return fmt.Errorf("cannot parse estimators flag: %w", err)
+	}
+
+	filenames := cliCtx.Args().Slice()
+	if len(filenames) == 0 {
+		// read stdin
+		filenames = []string{""}
+	}
+
+	sets := make([]types.EntropySet, 0, len(filenames))
+	for _, filename := range filenames {
+		bits, err := readInputBits(ctx, filename, plotBinStr)
+		if err != nil {
+			return err
+		}
+
+		log.Trace().
+			Str("file", filename).
+			Int("bits", len(bits)).
+			Int("chunk", plotChunk).
+			Msg("calculating entropy")
+
+		entropies, err := stats.Entropies(ctx, bits, plotChunk, plotSlen, stats.WithEstimators(estimators...), stats.WithStride(plotStride))
+		if err != nil {
+			return err
+		}
+
+		source := filename
+		if len(filenames) == 1 {
+			source = ""
+		}
+		sets = append(sets, types.EntropySet{
+			Source:  source,
+			Entropy: entropies,
+		})
+	}
+
+	path := plotOutPath
+	if len(path) == 0 {
+		path = defaultPlotPath()
+	}
+
+	return types.WriteEntropyComparison(path, sets, chartOpts...)
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"math"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+
+	"github.com/fedemengo/d2bist/pkg/engine"
+	"github.com/fedemengo/d2bist/pkg/image"
+	"github.com/fedemengo/d2bist/pkg/repeats"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+const maxRenderedRepeatLen = 64
+
+var (
+	repeatsTopK     = 10
+	repeatsMaxLen   = 16
+	repeatsMinLen   = 32
+	repeatsJSON     = false
+	repeatsBinStr   = false
+	repeatsPNG      = ""
+	repeatsPixelLen = 1
+)
+
+var coverageColor = color.RGBA{R: 0, G: 128, B: 255, A: 255}
+
+var repeatsCommand = &cli.Command{
+	Name:      "repeats",
+	Usage:     "Find the longest repeated substrings, the distinct substrings per length and the repeat coverage",
+	ArgsUsage: "[FILE]",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:        "topk",
+			Aliases:     []string{"k"},
+			Usage:       "output the k longest repeated substrings",
+			Value:       repeatsTopK,
+			Destination: &repeatsTopK,
+		}, &cli.IntFlag{
+			Name:        "maxlen",
+			Usage:       "count the distinct substrings up to this length",
+			Value:       repeatsMaxLen,
+			Destination: &repeatsMaxLen,
+		}, &cli.IntFlag{
+			Name:        "minlen",
+			Usage:       "min length of the repeats included in the coverage map",
+			Value:       repeatsMinLen,
+			Destination: &repeatsMinLen,
+		}, &cli.BoolFlag{
+			Name:        "json",
+			Usage:       "output the results as json",
+			Destination: &repeatsJSON,
+		}, &cli.BoolFlag{
+			Name:        "binstr",
+			Usage:       "the input is a string of 0s and 1s",
+			Destination: &repeatsBinStr,
+		}, &cli.StringFlag{
+			Name:        "png",
+			Usage:       "write bit string to png file, with the repeat coverage as overlay",
+			Destination: &repeatsPNG,
+		}, &cli.IntFlag{
+			Name:        "plen",
+			Usage:       "length of a pixel in bits",
+			Value:       repeatsPixelLen,
+			Destination: &repeatsPixelLen,
+		},
+	},
+	Action: repeatsAction,
+}
+
+type distinctCount struct {
+	Length int `json:"length"`
+	Count  int `json:"count"`
+	Max    int `json:"max"`
+}
+
+type repeatsResult struct {
+	Bits          int              `json:"bits"`
+	Repeats       []repeats.Repeat `json:"repeats"`
+	Distinct      []distinctCount  `json:"distinct"`
+	MinLen        int              `json:"min_len"`
+	CoveredBits   int              `json:"covered_bits"`
+	CoverageRatio float64          `json:"coverage_ratio"`
+}
+
+func repeatsAction(cliCtx *cli.Context) error {
+	log := zerolog.Ctx(cliCtx.Context).With().Str("command", "repeats").Logger()
+	ctx := log.WithContext(cliCtx.Context)
+
+	bits, err := readInputBits(ctx, cliCtx.Args().First(), repeatsBinStr)
+	if err != nil {
+		return err
+	}
+
+	log.Trace().Int("bits", len(bits)).Msg("building suffix array")
+	sa := repeats.NewSuffixArray(bits)
+
+	res := repeatsResult{
+		Bits:    len(bits),
+		Repeats: sa.LongestRepeats(repeatsTopK),
+		MinLen:  repeatsMinLen,
+	}
+
+	for i, count := range sa.DistinctSubstrings(repeatsMaxLen) {
+		l := i + 1
+		maxCount := len(bits) - l + 1
+		if l < 62 && 1<<l < maxCount {
+			maxCount = 1 << l
+		}
+		res.Distinct = append(res.Distinct, distinctCount{Length: l, Count: count, Max: max(maxCount, 0)})
+	}
+
+	coverage := sa.Coverage(repeatsMinLen)
+	longest := 0
+	for _, c := range coverage {
+		if c > 0 {
+			res.CoveredBits++
+		}
+		if c > longest {
+			longest = c
+		}
+	}
+	if len(bits) > 0 {
+		res.CoverageRatio = float64(res.CoveredBits) / float64(len(bits))
+	}
+
+	if repeatsJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(res); err != nil {
+			return err
+		}
+	} else {
+		renderRepeats(res, bits)
+	}
+
+	if len(repeatsPNG) > 0 {
+		if repeatsPixelLen == 0 {
+			repeatsPixelLen = 1
+		}
+
+		// log scale, so that short repeats are still visible next to long ones
+		values := make([]float64, len(coverage))
+		for i, c := range coverage {
+			if c > 0 {
+				values[i] = math.Log2(float64(c)) / math.Log2(float64(longest)+1)
+			}
+		}
+
+		return image.WriteToPNG(bits, repeatsPNG, repeatsPixelLen, image.WithOverlay(values, coverageColor))
+	}
+
+	return nil
+}
+
+func renderRepeats(res repeatsResult, bits []types.Bit) {
+	w := os.Stdout
+
+	fmt.Fprintln(w, "bits:", res.Bits)
+	fmt.Fprintln(w)
+
+	for _, r := range res.Repeats {
+		start := r.Offsets[0]
+		end := start + min(r.Length, maxRenderedRepeatLen)
+		substr := engine.BitsToString(bits[start:end])
+		if r.Length > maxRenderedRepeatLen {
+			substr += "..."
+		}
+		fmt.Fprintf(w, "length %d, %d occurrences at %v: %s\n", r.Length, len(r.Offsets), r.Offsets, substr)
+	}
+	fmt.Fprintln(w)
+
+	for _, d := range res.Distinct {
+		fmt.Fprintf(w, "%d: %d/%d distinct substrings\n", d.Length, d.Count, d.Max)
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "coverage: %d bits (%.2f %%) in repeats of at least %d bits\n", res.CoveredBits, 100*res.CoverageRatio, res.MinLen)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fedemengo/d2bist/pkg/repeats"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+func TestRenderRepeats(t *testing.T) {
+	bits := []types.Bit{1, 0, 1, 1, 0, 0, 1, 0, 1, 1}
+	res := repeatsResult{
+		Bits:          len(bits),
+		Repeats:       []repeats.Repeat{{Length: 4, Offsets: []int{0, 6}}},
+		Distinct:      []distinctCount{{Length: 1, Count: 2, Max: 2}, {Length: 2, Count: 4, Max: 4}},
+		MinLen:        4,
+		CoveredBits:   8,
+		CoverageRatio: 0.8,
+	}
+
+	out := captureStdout(t, func() error {
+		renderRepeats(res, bits)
+		return nil
+	})
+
+	expected := "bits: 10\n" +
+		"\n" +
+		"length 4, 2 occurrences at [0 6]: 1011\n" +
+		"\n" +
+		"1: 2/2 distinct substrings\n" +
+		"2: 4/4 distinct substrings\n" +
+		"\n" +
+		"coverage: 8 bits (80.00 %) in repeats of at least 4 bits\n"
+	assert.Equal(t, expected, out)
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+
+	"github.com/fedemengo/d2bist/pkg/dump"
+	"github.com/fedemengo/d2bist/pkg/image"
+	"github.com/fedemengo/d2bist/pkg/search"
+)
+
+var (
+	searchTolerance = 0
+	searchJSON      = false
+	searchBinStr    = false
+	searchPNG       = ""
+	searchPixelLen  = 1
+)
+
+var highlightColor = color.RGBA{R: 255, G: 0, B: 0, A: 255}
+
+var searchCommand = &cli.Command{
+	Name:      "search",
+	Usage:     "Report every bit offset where a pattern of 0s, 1s and x (don't care) occurs",
+	ArgsUsage: "PATTERN [FILE]",
+	Flags: append([]cli.Flag{
+		&cli.IntFlag{
+			Name:        "tol",
+			Usage:       "max number of mismatching bits (Hamming distance) allowed",
+			DefaultText: "0",
+			Destination: &searchTolerance,
+		}, &cli.BoolFlag{
+			Name:        "json",
+			Usage:       "output the matches as json",
+			Destination: &searchJSON,
+		}, &cli.BoolFlag{
+			Name:        "binstr",
+			Usage:       "the input is a string of 0s and 1s",
+			Destination: &searchBinStr,
+		}, &cli.StringFlag{
+			Name:        "png",
+			Usage:       "write bit string to png file, highlighting the matches",
+			Destination: &searchPNG,
+		}, &cli.IntFlag{
+			Name:        "plen",
+			Usage:       "length of a pixel in bits",
+			DefaultText: "1",
+			Destination: &searchPixelLen,
+		},
+	}, dumpFlags()...),
+	Action: searchAction,
+}
+
+type searchResult struct {
+	Pattern   string         `json:"pattern"`
+	Length    int            `json:"length"`
+	Tolerance int            `json:"tolerance"`
+	Matches   []search.Match `json:"matches"`
+}
+
+func searchAction(cliCtx *cli.Context) error {
+	log := zerolog.Ctx(cliCtx.Context).With().Str("command", "search").Logger()
+	ctx := log.WithContext(cliCtx.Context)
+
+	if cliCtx.NArg() < 1 {
+		return fmt.Errorf("missing pattern")
+	}
+
+	p, err := search.ParsePattern(cliCtx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	bits, err := readInputBits(ctx, cliCtx.Args().Get(1), searchBinStr)
+	if err != nil {
+		return err
+	}
+
+	matches := search.Search(bits, p, searchTolerance)
+	log.Trace().Int("bits", len(bits)).Int("matches", len(matches)).Msg("search done")
+
+	if searchJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(searchResult{
+			Pattern:   p.String(),
+			Length:    p.Len(),
+			Tolerance: searchTolerance,
+			Matches:   matches,
+		})
+		if err != nil {
+			return err
+		}
+	} else if dumpOutput {
+		opts := append(dumpOptsFromFlags(searchPixelLen), dump.WithHighlights(search.Ranges(matches, p), highlightColor))
+		if err := dump.Write(os.Stdout, bits, opts...); err != nil {
+			return err
+		}
+	} else {
+		for _, m := range matches {
+			fmt.Fprintf(os.Stdout, "%d\t%d\n", m.Offset, m.Errors)
+		}
+	}
+
+	if len(searchPNG) > 0 {
+		if searchPixelLen == 0 {
+			searchPixelLen = 1
+		}
+		hl := image.WithHighlights(search.Ranges(matches, p), highlightColor)
+		return image.WriteToPNG(bits, searchPNG, searchPixelLen, hl)
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+
+	"github.com/fedemengo/d2bist/pkg/analyze"
+	"github.com/fedemengo/d2bist/pkg/compression"
+	"github.com/fedemengo/d2bist/pkg/flags"
+	"github.com/fedemengo/d2bist/pkg/split"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+var (
+	splitSize        = ""
+	splitAt          = ""
+	splitOut         = "chunks"
+	splitStr         = false
+	splitCompression = ""
+	splitSlen        = 2
+	splitEstims      = ""
+	splitJSON        = false
+	splitBinStr      = false
+)
+
+var splitCommand = &cli.Command{
+	Name:      "split",
+	Usage:     "Split the input in chunks of fixed size or at boundaries, each to its own file, with a manifest of their offsets and stats",
+	ArgsUsage: "[FILE]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:        "size",
+			Usage:       "size of the chunks, in bits or bytes (4K, 512B)",
+			Destination: &splitSize,
+		}, &cli.StringFlag{
+			Name:        "at",
+			Usage:       "comma separated offsets where a new chunk starts, in bits or bytes",
+			Destination: &splitAt,
+		}, &cli.StringFlag{
+			Name:        "out",
+			Aliases:     []string{"o"},
+			Value:       splitOut,
+			Usage:       "directory of the chunks and the manifest",
+			Destination: &splitOut,
+		}, &cli.BoolFlag{
+			Name:        "str",
+			Usage:       "the chunks will be strings of 0s and 1s",
+			Destination: &splitStr,
+		}, &cli.StringFlag{
+			Name:        "compression",
+			Aliases:     []string{"c"},
+			Usage:       "compress each chunk with the algorithm",
+			DefaultText: "none",
+			Destination: &splitCompression,
+		}, &cli.IntFlag{
+			Name:        "slen",
+			Value:       splitSlen,
+			Usage:       "length of unitary symbol used when calculating the entropy of the chunks",
+			Destination: &splitSlen,
+		}, &cli.StringFlag{
+			Name:        "estimators",
+			Usage:       "comma separated entropy estimators of the chunk stats (shannon, lz76 or a codec with optional parameters, e.g. zstd:level=4:window=23)",
+			DefaultText: "gzip, brotli, bzip2 and shannon",
+			Destination: &splitEstims,
+		}, &cli.BoolFlag{
+			Name:        "json",
+			Usage:       "output the manifest as json",
+			Destination: &splitJSON,
+		}, &cli.BoolFlag{
+			Name:        "binstr",
+			Usage:       "the input is a string of 0s and 1s",
+			Destination: &splitBinStr,
+		},
+	},
+	Action: splitAction,
+}
+
+func splitAction(cliCtx *cli.Context) error {
+	log := zerolog.Ctx(cliCtx.Context).With().Str("command", "split").Logger()
+	ctx := log.WithContext(cliCtx.Context)
+
+	if (len(splitSize) > 0) == (len(splitAt) > 0) {
+		return fmt.Errorf("split requires either the chunk size (--size) or the boundaries (--at)")
+	}
+
+	cType, err := flags.ParseCompressionFlag(splitCompression)
+	if err != nil {
+		return fmt.Errorf("cannot parse compression flag: %w", err)
+	}
+	if cType == compression.Auto {
+		return fmt.Errorf("the output compression cannot be detected")
+	}
+
+	estimators, err := flags.ParseEstimatorsFlag(splitEstims, splitSlen)
+	if err != nil {
+		return fmt.Errorf("cannot parse estimators flag: %w", err)
+	}
+
+	opts := []split.Opt{
+		split.WithCompression(cType),
+		split.WithSummaryOpts(analyze.WithSymbolLen(splitSlen), analyze.WithEstimators(estimators...)),
+	}
+	if splitStr {
+		opts = append(opts, split.WithBinStr())
+	}
+
+	bits, err := readInputBits(ctx, cliCtx.Args().First(), splitBinStr)
+	if err != nil {
+		return err
+	}
+
+	var ranges []types.Range
+	if len(splitSize) > 0 {
+		size, err := flags.ParseDataCapToBitsCount(splitSize)
+		if err != nil {
+			return fmt.Errorf("cannot parse size flag: %w", err)
+		}
+		ranges, err = split.BySize(len(bits), size)
+		if err != nil {
+			return err
+		}
+	} else {
+		boundaries, err := flags.ParseBitOffsetsFlag(splitAt)
+		if err != nil {
+			return fmt.Errorf("cannot parse at flag: %w", err)
+		}
+		ranges, err = split.AtBoundaries(len(bits), boundaries)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Trace().Int("bits", len(bits)).Int("chunks", len(ranges)).Str("dir", splitOut).Msg("splitting")
+
+	m, err := split.Write(ctx, splitOut, bits, ranges, opts...)
+	if err != nil {
+		return err
+	}
+
+	if splitJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(m)
+	}
+
+	summaries := make([]analyze.Summary, 0, len(m.Chunks))
+	fmt.Fprintf(os.Stdout, "%-12s %-12s %s\n", "bit offset", "bits", "file")
+	for _, c := range m.Chunks {
+		fmt.Fprintf(os.Stdout, "%-12d %-12d %s\n", c.Offset, c.Length, c.File)
+		summaries = append(summaries, c.Stats)
+	}
+	fmt.Fprintln(os.Stdout)
+
+	return analyze.WriteTable(os.Stdout, summaries)
+}
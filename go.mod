@@ -3,16 +3,20 @@ module github.com/fedemengo/d2bist
 go 1.19
 
 require (
+	github.com/ajstarks/svgo v0.0.0-20211024235047-1546f124cd8b
 	github.com/andybalholm/brotli v1.0.4
 	github.com/dsnet/compress v0.0.1
 	github.com/fedemengo/go-data-structures v0.0.0-20180922000948-80ff6179d6c9
 	github.com/klauspost/compress v1.15.13
 	github.com/mattn/go-isatty v0.0.17
+	github.com/pierrec/lz4/v4 v4.1.17
 	github.com/pkg/profile v1.7.0
 	github.com/rs/zerolog v1.28.0
 	github.com/stretchr/testify v1.8.1
+	github.com/ulikunitz/xz v0.5.11
 	github.com/urfave/cli/v2 v2.23.7
 	github.com/vdobler/chart v1.0.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -28,5 +32,4 @@ require (
 	github.com/xrash/smetrics v0.0.0-20201216005158-039620a65673 // indirect
 	golang.org/x/image v0.10.0 // indirect
 	golang.org/x/sys v0.5.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,237 @@
+package analyze
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/rs/zerolog"
+
+	"github.com/fedemengo/d2bist/pkg/compression"
+	iio "github.com/fedemengo/d2bist/pkg/io"
+	"github.com/fedemengo/d2bist/pkg/stats"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+const (
+	DefaultSubstrLen = 8
+	DefaultTopK      = 3
+)
+
+// Substr is one of the most frequent substrings of an input
+type Substr struct {
+	Bits  string  `json:"bits"`
+	Count int     `json:"count"`
+	Freq  float64 `json:"freq"`
+}
+
+// Summary holds the stats of an input that are compared with the other inputs
+//
+// Ones is the fraction of 1s, Entropy is the mean of the chunks entropy and
+// Compression is the size of the compressed input over its size
+type Summary struct {
+	File        string                                  `json:"file"`
+	Bits        int                                     `json:"bits"`
+	Ones        float64                                 `json:"ones"`
+	Entropy     map[types.EntropyType]float64           `json:"entropy"`
+	Compression map[compression.CompressionType]float64 `json:"compression"`
+	TopSubstrs  []Substr                                `json:"top_substrs"`
+	Err         string                                  `json:"error,omitempty"`
+}
+
+type config struct {
+	chunkSize int
+	symbolLen int
+	substrLen int
+	topK      int
+	workers   int
+
+	estimators []stats.Estimator
+}
+
+type Opt func(c *config)
+
+// WithChunkSize calculates the entropy on chunks of chunkSize bits, by default the whole input is a chunk
+func WithChunkSize(chunkSize int) Opt {
+	return func(c *config) {
+		c.chunkSize = chunkSize
+	}
+}
+
+// WithSymbolLen sets the symbol length of the Shannon entropy
+func WithSymbolLen(symbolLen int) Opt {
+	return func(c *config) {
+		c.symbolLen = symbolLen
+	}
+}
+
+// WithEstimators replaces the default estimators of the entropy, see stats.DefaultEstimators
+func WithEstimators(estimators ...stats.Estimator) Opt {
+	return func(c *config) {
+		c.estimators = append(c.estimators, estimators...)
+	}
+}
+
+// WithTopSubstrs reports the topK most frequent substrings of substrLen bits
+func WithTopSubstrs(substrLen, topK int) Opt {
+	return func(c *config) {
+		c.substrLen = substrLen
+		c.topK = topK
+	}
+}
+
+// WithWorkers sets how many inputs are analysed at the same time, by default one per CPU
+func WithWorkers(workers int) Opt {
+	return func(c *config) {
+		c.workers = workers
+	}
+}
+
+func newConfig(opts []Opt) *config {
+	c := &config{
+		symbolLen: 2,
+		substrLen: DefaultSubstrLen,
+		topK:      DefaultTopK,
+		workers:   runtime.NumCPU(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// ReadFunc reads the bits of a file
+type ReadFunc func(ctx context.Context, filename string) ([]types.Bit, error)
+
+// Files summarizes each file concurrently, the summaries are in the same order as the files
+//
+// a file that cannot be read has its error in the summary
+func Files(ctx context.Context, filenames []string, read ReadFunc, opts ...Opt) []Summary {
+	log := zerolog.Ctx(ctx)
+	c := newConfig(opts)
+
+	workers := c.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	summaries := make([]Summary, len(filenames))
+	sem := make(chan struct{}, workers)
+
+	var wg sync.WaitGroup
+	for i, filename := range filenames {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, filename string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			bits, err := read(ctx, filename)
+			if err != nil {
+				log.Debug().Err(err).Str("file", filename).Msg("cannot read file")
+				summaries[i] = Summary{File: filename, Err: err.Error()}
+				return
+			}
+
+			summaries[i] = summarize(ctx, filename, bits, c)
+		}(i, filename)
+	}
+	wg.Wait()
+
+	return summaries
+}
+
+// Summarize calculates the stats of bits that are compared between inputs
+func Summarize(ctx context.Context, name string, bits []types.Bit, opts ...Opt) Summary {
+	return summarize(ctx, name, bits, newConfig(opts))
+}
+
+func summarize(ctx context.Context, name string, bits []types.Bit, c *config) Summary {
+	s := Summary{
+		File:        name,
+		Bits:        len(bits),
+		Entropy:     map[types.EntropyType]float64{},
+		Compression: map[compression.CompressionType]float64{},
+	}
+
+	if len(bits) == 0 {
+		return s
+	}
+
+	ones := 0
+	for _, b := range bits {
+		ones += int(b)
+	}
+	s.Ones = float64(ones) / float64(len(bits))
+
+	chunkSize := c.chunkSize
+	if chunkSize <= 0 {
+		chunkSize = len(bits)
+	}
+	entropies, err := stats.Entropies(ctx, bits, chunkSize, c.symbolLen, stats.WithEstimators(c.estimators...))
+	if err != nil {
+		s.Err = err.Error()
+		return s
+	}
+	for _, e := range entropies {
+		s.Entropy[e.Name] = mean(e.Values)
+	}
+
+	for _, cType := range compression.Types() {
+		cr, err := iio.BitsToReader(ctx, bits, cType)
+		if err != nil {
+			continue
+		}
+		s.Compression[cType] = float64(cr.Size()*8) / float64(len(bits))
+	}
+
+	s.TopSubstrs = topSubstrs(ctx, bits, c.substrLen, c.topK)
+
+	return s
+}
+
+// topSubstrs returns the topK most frequent substrings of substrLen bits, the most frequent first
+func topSubstrs(ctx context.Context, bits []types.Bit, substrLen, topK int) []Substr {
+	if substrLen < 1 || topK < 1 || substrLen > len(bits) {
+		return nil
+	}
+
+	bitsStats := stats.AnalizeBits(ctx, bits, stats.WithMaxBlockSize(substrLen), stats.WithTopKFreq(topK))
+	counts := bitsStats.SubstrsCount[substrLen-1].Counts
+	windows := float64(len(bits) - substrLen + 1)
+
+	substrs := make([]Substr, 0, len(counts))
+	for bitStr, count := range counts {
+		substrs = append(substrs, Substr{Bits: bitStr, Count: count, Freq: float64(count) / windows})
+	}
+	sort.Slice(substrs, func(i, j int) bool {
+		if substrs[i].Count != substrs[j].Count {
+			return substrs[i].Count > substrs[j].Count
+		}
+		return substrs[i].Bits < substrs[j].Bits
+	})
+
+	if len(substrs) > topK {
+		substrs = substrs[:topK]
+	}
+
+	return substrs
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sum := float64(0)
+	for _, v := range values {
+		sum += v
+	}
+
+	return sum / float64(len(values))
+}
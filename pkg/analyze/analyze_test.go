@@ -0,0 +1,108 @@
+package analyze
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fedemengo/d2bist/pkg/compression"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+func repeatBits(pattern []types.Bit, n int) []types.Bit {
+	bits := make([]types.Bit, 0, len(pattern)*n)
+	for i := 0; i < n; i++ {
+		bits = append(bits, pattern...)
+	}
+
+	return bits
+}
+
+func TestSummarize(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+
+	bits := repeatBits([]types.Bit{1, 1, 0, 1}, 64)
+	s := Summarize(ctx, "a", bits, WithTopSubstrs(4, 2))
+
+	a.Equal("a", s.File)
+	a.Equal(256, s.Bits)
+	a.Equal(0.75, s.Ones)
+	a.Contains(s.Entropy, types.ShannonEntropy)
+	a.Contains(s.Compression, compression.Gzip)
+	a.Less(s.Compression[compression.Gzip], 1.0)
+
+	// 1101 starts the pattern so it occurs once more than its rotations
+	a.Len(s.TopSubstrs, 2)
+	a.Equal(Substr{Bits: "1101", Count: 64, Freq: 64.0 / 253}, s.TopSubstrs[0])
+	a.Equal(63, s.TopSubstrs[1].Count)
+}
+
+func TestSummarizeEmpty(t *testing.T) {
+	s := Summarize(context.Background(), "empty", nil)
+
+	assert.Equal(t, 0, s.Bits)
+	assert.Empty(t, s.TopSubstrs)
+}
+
+func TestFiles(t *testing.T) {
+	a := assert.New(t)
+
+	files := map[string][]types.Bit{
+		"zeros": repeatBits([]types.Bit{0}, 128),
+		"ones":  repeatBits([]types.Bit{1}, 128),
+	}
+	read := func(_ context.Context, filename string) ([]types.Bit, error) {
+		bits, ok := files[filename]
+		if !ok {
+			return nil, errors.New("no such file")
+		}
+		return bits, nil
+	}
+
+	summaries := Files(context.Background(), []string{"ones", "missing", "zeros"}, read, WithWorkers(2))
+	require.Len(t, summaries, 3)
+
+	a.Equal("ones", summaries[0].File)
+	a.Equal(1.0, summaries[0].Ones)
+	a.Equal("missing", summaries[1].File)
+	a.Equal("no such file", summaries[1].Err)
+	a.Equal("zeros", summaries[2].File)
+	a.Equal(0.0, summaries[2].Ones)
+}
+
+func TestWriters(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	summaries := []Summary{
+		Summarize(context.Background(), "a", repeatBits([]types.Bit{0, 1}, 64)),
+		{File: "b", Err: "no such file"},
+	}
+
+	var table bytes.Buffer
+	r.NoError(WriteTable(&table, summaries))
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	r.Len(lines, 3)
+	a.True(strings.HasPrefix(lines[0], "file"))
+	a.Contains(lines[0], "H Shannon")
+	a.Contains(lines[0], "C Gzip")
+	a.Contains(lines[2], "error: no such file")
+
+	var js bytes.Buffer
+	r.NoError(WriteJSON(&js, summaries))
+	var decoded []Summary
+	r.NoError(json.Unmarshal(js.Bytes(), &decoded))
+	a.Equal(summaries[0].File, decoded[0].File)
+	a.Equal(summaries[1].Err, decoded[1].Err)
+
+	var html bytes.Buffer
+	r.NoError(WriteHTML(&html, summaries))
+	a.Contains(html.String(), "<th>H Shannon</th>")
+	a.Contains(html.String(), "<td>a</td>")
+}
@@ -0,0 +1,149 @@
+package analyze
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fedemengo/d2bist/pkg/compression"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+// table is the comparison of the summaries, a row per input
+type table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// entropyColumns returns the estimators of all the summaries, sorted by name
+func entropyColumns(summaries []Summary) []types.EntropyType {
+	seen := map[types.EntropyType]bool{}
+	columns := []types.EntropyType{}
+	for _, s := range summaries {
+		for name := range s.Entropy {
+			if !seen[name] {
+				seen[name] = true
+				columns = append(columns, name)
+			}
+		}
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i] < columns[j] })
+
+	return columns
+}
+
+// compressionColumns returns the algorithms of all the summaries, in the order of compression.Types
+func compressionColumns(summaries []Summary) []compression.CompressionType {
+	columns := []compression.CompressionType{}
+	for _, cType := range compression.Types() {
+		for _, s := range summaries {
+			if _, ok := s.Compression[cType]; ok {
+				columns = append(columns, cType)
+				break
+			}
+		}
+	}
+
+	return columns
+}
+
+func newTable(summaries []Summary) table {
+	eColumns, cColumns := entropyColumns(summaries), compressionColumns(summaries)
+
+	t := table{Header: []string{"file", "bits", "ones"}}
+	for _, name := range eColumns {
+		t.Header = append(t.Header, fmt.Sprintf("H %s", name))
+	}
+	for _, cType := range cColumns {
+		t.Header = append(t.Header, fmt.Sprintf("C %s", cType))
+	}
+	t.Header = append(t.Header, "top substrings")
+
+	for _, s := range summaries {
+		if len(s.Err) > 0 {
+			row := make([]string, len(t.Header))
+			row[0], row[len(row)-1] = s.File, fmt.Sprintf("error: %s", s.Err)
+			t.Rows = append(t.Rows, row)
+			continue
+		}
+
+		row := []string{s.File, fmt.Sprint(s.Bits), fmt.Sprintf("%.4f", s.Ones)}
+		for _, name := range eColumns {
+			row = append(row, cell(s.Entropy, name))
+		}
+		for _, cType := range cColumns {
+			row = append(row, cell(s.Compression, cType))
+		}
+
+		substrs := make([]string, 0, len(s.TopSubstrs))
+		for _, substr := range s.TopSubstrs {
+			substrs = append(substrs, fmt.Sprintf("%s:%d", substr.Bits, substr.Count))
+		}
+		row = append(row, strings.Join(substrs, " "))
+
+		t.Rows = append(t.Rows, row)
+	}
+
+	return t
+}
+
+func cell[K comparable](values map[K]float64, key K) string {
+	v, ok := values[key]
+	if !ok {
+		return "-"
+	}
+
+	return fmt.Sprintf("%.4f", v)
+}
+
+// WriteTable writes the comparison as a text table, H columns are entropies and C columns compression ratios
+func WriteTable(w io.Writer, summaries []Summary) error {
+	t := newTable(summaries)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.Header, "\t"))
+	for _, row := range t.Rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// WriteJSON writes the summaries as json
+func WriteJSON(w io.Writer, summaries []Summary) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(summaries)
+}
+
+var htmlTable = template.Must(template.New("analyze").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>d2bist analyze</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; font-family: monospace; }
+th { background: #eee; }
+td:first-child, td:last-child { text-align: left; }
+</style>
+</head>
+<body>
+<table>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteHTML writes the comparison as a html page with the table
+func WriteHTML(w io.Writer, summaries []Summary) error {
+	return htmlTable.Execute(w, newTable(summaries))
+}
@@ -0,0 +1,218 @@
+// Package bench measures ratio, throughput and memory of every compression algorithm at its levels
+package bench
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/fedemengo/d2bist/pkg/compression"
+)
+
+// memSampling is how often the heap is sampled while a codec runs
+const memSampling = time.Millisecond
+
+// Result is the measure of an algorithm at a level
+//
+// Ratio is the compressed size over the input size, throughputs are in MB of input per second
+// and PeakHeap is the max heap growth in bytes while compressing or decompressing
+type Result struct {
+	Algorithm       compression.CompressionType `json:"algorithm"`
+	Level           int                         `json:"level"`
+	InputBytes      int                         `json:"input_bytes"`
+	CompressedBytes int                         `json:"compressed_bytes"`
+	Ratio           float64                     `json:"ratio"`
+	CompressMBps    float64                     `json:"compress_mbps"`
+	DecompressMBps  float64                     `json:"decompress_mbps"`
+	PeakHeap        uint64                      `json:"peak_heap_bytes"`
+	RoundTrip       bool                        `json:"round_trip"`
+	Err             string                      `json:"error,omitempty"`
+}
+
+type config struct {
+	algorithms []compression.CompressionType
+	levels     map[compression.CompressionType][]int
+}
+
+type Opt func(c *config)
+
+// WithAlgorithms benchmarks only algorithms instead of compression.Types
+func WithAlgorithms(algorithms ...compression.CompressionType) Opt {
+	return func(c *config) {
+		c.algorithms = algorithms
+	}
+}
+
+// WithLevels benchmarks levels instead of the levels of each codec
+func WithLevels(levels map[compression.CompressionType][]int) Opt {
+	return func(c *config) {
+		c.levels = levels
+	}
+}
+
+// levelsOf returns the levels of cType set by WithLevels, or the levels of its codec
+func (c *config) levelsOf(cType compression.CompressionType) []int {
+	if c.levels != nil {
+		return c.levels[cType]
+	}
+
+	codec, ok := compression.Lookup(cType)
+	if !ok {
+		return nil
+	}
+
+	return codec.Levels
+}
+
+// Run compresses and decompresses data with every algorithm at every level, one run at a time
+func Run(ctx context.Context, data []byte, opts ...Opt) []Result {
+	log := zerolog.Ctx(ctx)
+
+	c := &config{
+		algorithms: compression.Types(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	results := []Result{}
+	for _, cType := range c.algorithms {
+		for _, level := range c.levelsOf(cType) {
+			log.Debug().
+				Str("algorithm", string(cType)).
+				Int("level", level).
+				Msg("benchmarking")
+
+			results = append(results, run(ctx, data, cType, level))
+		}
+	}
+
+	return results
+}
+
+func run(ctx context.Context, data []byte, cType compression.CompressionType, level int) Result {
+	r := Result{Algorithm: cType, Level: level, InputBytes: len(data)}
+
+	start := time.Now()
+	compressed, err := compress(ctx, data, cType, level)
+	compressTime := time.Since(start)
+	if err != nil {
+		r.Err = err.Error()
+		return r
+	}
+
+	start = time.Now()
+	decompressed, err := decompress(ctx, compressed, cType)
+	decompressTime := time.Since(start)
+	if err != nil {
+		r.Err = err.Error()
+		return r
+	}
+
+	// the heap is measured on another run, sampling it stops the world and would slow down the timed one
+	r.PeakHeap = peakHeap(func() {
+		if c, err := compress(ctx, data, cType, level); err == nil {
+			_, _ = decompress(ctx, c, cType)
+		}
+	})
+
+	r.CompressedBytes = len(compressed)
+	if len(data) > 0 {
+		r.Ratio = float64(len(compressed)) / float64(len(data))
+	}
+	r.CompressMBps = throughput(len(data), compressTime)
+	r.DecompressMBps = throughput(len(data), decompressTime)
+	r.RoundTrip = bytes.Equal(data, decompressed)
+
+	return r
+}
+
+func compress(ctx context.Context, data []byte, cType compression.CompressionType, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	cw, err := compression.NewCompressedWriter(ctx, &buf, cType, compression.WithLevel(level))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get compressed writer: %w", err)
+	}
+
+	if _, err := cw.Write(data); err != nil {
+		return nil, fmt.Errorf("cannot compress: %w", err)
+	}
+	if err := cw.Close(); err != nil {
+		return nil, fmt.Errorf("error when closing writer: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decompress(ctx context.Context, compressed []byte, cType compression.CompressionType) ([]byte, error) {
+	cr, err := compression.NewCompressedReader(ctx, bytes.NewReader(compressed), cType)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get compressed reader: %w", err)
+	}
+
+	data, err := io.ReadAll(cr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decompress: %w", err)
+	}
+
+	return data, nil
+}
+
+func throughput(n int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+
+	return float64(n) / 1e6 / d.Seconds()
+}
+
+// peakHeap runs f and returns the max growth of the heap, sampled every memSampling
+func peakHeap(f func()) uint64 {
+	var m runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m)
+	base := m.HeapAlloc
+
+	var peak uint64
+	sample := func() {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		if m.HeapAlloc > base && m.HeapAlloc-base > peak {
+			peak = m.HeapAlloc - base
+		}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(memSampling)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				sample()
+			}
+		}
+	}()
+
+	f()
+	close(done)
+	wg.Wait()
+
+	// a run shorter than the sampling period still has its final heap
+	sample()
+
+	return peak
+}
@@ -0,0 +1,74 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fedemengo/d2bist/pkg/compression"
+)
+
+func TestRun(t *testing.T) {
+	a := assert.New(t)
+
+	data := bytes.Repeat([]byte("d2bist "), 512)
+	results := Run(context.Background(), data)
+
+	levels := 0
+	for _, cType := range compression.Types() {
+		codec, _ := compression.Lookup(cType)
+		levels += len(codec.Levels)
+	}
+	require.Len(t, results, levels)
+
+	for _, r := range results {
+		a.Empty(r.Err, "%s level %d", r.Algorithm, r.Level)
+		a.True(r.RoundTrip, "%s level %d", r.Algorithm, r.Level)
+		a.Equal(len(data), r.InputBytes)
+		a.Less(r.Ratio, 0.5, "%s level %d", r.Algorithm, r.Level)
+	}
+}
+
+func TestRunOpts(t *testing.T) {
+	a := assert.New(t)
+
+	results := Run(context.Background(), []byte("data"),
+		WithAlgorithms(compression.Gzip, compression.Huff),
+		WithLevels(map[compression.CompressionType][]int{
+			compression.Gzip: {1},
+			compression.Huff: {0},
+		}),
+	)
+	require.Len(t, results, 2)
+
+	a.Equal(compression.Gzip, results[0].Algorithm)
+	a.True(results[0].RoundTrip)
+	// the run is shorter than the sampling period, its heap is still measured
+	a.Positive(results[0].PeakHeap)
+	a.Equal(compression.Huff, results[1].Algorithm)
+	a.NotEmpty(results[1].Err)
+	a.False(results[1].RoundTrip)
+}
+
+func TestBest(t *testing.T) {
+	results := []Result{
+		{Algorithm: compression.Gzip, Ratio: 0.5, RoundTrip: true},
+		{Algorithm: compression.Zstd, Ratio: 0.1, RoundTrip: false},
+		{Algorithm: compression.CM, Ratio: 0.3, RoundTrip: true},
+	}
+
+	best, ok := Best(results)
+	require.True(t, ok)
+	assert.Equal(t, compression.CM, best.Algorithm)
+
+	_, ok = Best(nil)
+	assert.False(t, ok)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteTable(&buf, results))
+	assert.True(t, strings.HasSuffix(buf.String(), "best: CM level 0, ratio 0.3000\n"))
+}
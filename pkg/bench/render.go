@@ -0,0 +1,58 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Best returns the round-tripping result with the lowest ratio, false if none round-tripped
+func Best(results []Result) (Result, bool) {
+	best, found := Result{}, false
+	for _, r := range results {
+		if !r.RoundTrip {
+			continue
+		}
+		if !found || r.Ratio < best.Ratio {
+			best, found = r, true
+		}
+	}
+
+	return best, found
+}
+
+// WriteTable writes a row per result and the best algorithm at the end
+func WriteTable(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "algorithm\tlevel\tbytes\tratio\tcompress MB/s\tdecompress MB/s\tpeak heap KB\tround trip\t")
+
+	for _, r := range results {
+		if len(r.Err) > 0 {
+			fmt.Fprintf(tw, "%s\t%d\t-\t-\t-\t-\t-\terror: %s\t\n", r.Algorithm, r.Level, r.Err)
+			continue
+		}
+
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.4f\t%.2f\t%.2f\t%d\t%t\t\n",
+			r.Algorithm, r.Level, r.CompressedBytes, r.Ratio,
+			r.CompressMBps, r.DecompressMBps, r.PeakHeap/1024, r.RoundTrip)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if best, ok := Best(results); ok {
+		fmt.Fprintf(w, "\nbest: %s level %d, ratio %.4f\n", best.Algorithm, best.Level, best.Ratio)
+	}
+
+	return nil
+}
+
+// WriteJSON writes the results as json
+func WriteJSON(w io.Writer, results []Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(results)
+}
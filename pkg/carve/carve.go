@@ -0,0 +1,231 @@
+package carve
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/rs/zerolog"
+
+	"github.com/fedemengo/d2bist/pkg/engine"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown format")
+	ErrFormatExists  = errors.New("format already registered")
+	// ErrInvalidStream is returned by Format.Size when the data at a signature is not a valid stream
+	ErrInvalidStream = errors.New("invalid stream")
+)
+
+// Format is a file or stream format found by its signature
+type Format struct {
+	Name string
+	// Ext is the extension of the extracted files
+	Ext   string
+	Magic []byte
+	// Size validates the stream at the start of data and returns its size in bytes and,
+	// for compressed streams, the size of the decoded data
+	Size func(ctx context.Context, data []byte) (size, decoded int, err error)
+}
+
+// Stream is a valid stream found in the input
+//
+// Offset is in bits, to locate streams that do not start at a byte boundary, Size and Decoded are in bytes
+type Stream struct {
+	Format  string `json:"format"`
+	Offset  int    `json:"offset"`
+	Size    int    `json:"size"`
+	Decoded int    `json:"decoded,omitempty"`
+
+	Data []byte `json:"-"`
+}
+
+var registry = struct {
+	sync.RWMutex
+	formats []*Format
+	byName  map[string]*Format
+}{
+	byName: map[string]*Format{},
+}
+
+// Register adds a format that Scan looks for
+func Register(f Format) error {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.byName[f.Name]; ok {
+		return fmt.Errorf("format `%s`: %w", f.Name, ErrFormatExists)
+	}
+	if len(f.Magic) == 0 || f.Size == nil {
+		return fmt.Errorf("format `%s` needs a magic and a size function", f.Name)
+	}
+
+	format := &f
+	registry.formats = append(registry.formats, format)
+	registry.byName[f.Name] = format
+
+	return nil
+}
+
+func mustRegister(f Format) {
+	if err := Register(f); err != nil {
+		panic(err)
+	}
+}
+
+// Formats returns the names of the formats, in the order they were registered
+func Formats() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	names := make([]string, 0, len(registry.formats))
+	for _, f := range registry.formats {
+		names = append(names, f.Name)
+	}
+
+	return names
+}
+
+type config struct {
+	bitOffsets bool
+	formats    []string
+}
+
+type Opt func(c *config)
+
+// WithBitOffsets looks for streams at every bit offset, by default only at byte offsets
+func WithBitOffsets() Opt {
+	return func(c *config) {
+		c.bitOffsets = true
+	}
+}
+
+// WithFormats looks only for the named formats, by default every format is looked for
+func WithFormats(names ...string) Opt {
+	return func(c *config) {
+		c.formats = append(c.formats, names...)
+	}
+}
+
+// Scan looks for the signature of each format at every byte offset of bits, or every bit offset
+// with WithBitOffsets, and returns the valid streams sorted by offset
+//
+// streams inside other streams, like a PNG stored in a ZIP, are reported too
+func Scan(ctx context.Context, bits []types.Bit, opts ...Opt) ([]Stream, error) {
+	log := zerolog.Ctx(ctx)
+
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	formats, err := selectFormats(c.formats)
+	if err != nil {
+		return nil, err
+	}
+
+	shifts := 1
+	if c.bitOffsets {
+		shifts = 8
+	}
+
+	streams := []Stream{}
+	for shift := 0; shift < shifts && shift < len(bits); shift++ {
+		data := toBytes(bits[shift:])
+
+		for _, f := range formats {
+			for offset := bytes.Index(data, f.Magic); offset >= 0; {
+				size, decoded, err := f.Size(ctx, data[offset:])
+				if err == nil {
+					streams = append(streams, Stream{
+						Format:  f.Name,
+						Offset:  offset*8 + shift,
+						Size:    size,
+						Decoded: decoded,
+						Data:    data[offset : offset+size],
+					})
+				} else {
+					log.Trace().Err(err).Str("format", f.Name).Int("offset", offset*8+shift).Msg("invalid stream")
+				}
+
+				next := bytes.Index(data[offset+1:], f.Magic)
+				if next < 0 {
+					break
+				}
+				offset += next + 1
+			}
+		}
+	}
+
+	sort.SliceStable(streams, func(i, j int) bool {
+		return streams[i].Offset < streams[j].Offset
+	})
+
+	return streams, nil
+}
+
+func selectFormats(names []string) ([]*Format, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	if len(names) == 0 {
+		return append([]*Format{}, registry.formats...), nil
+	}
+
+	formats := make([]*Format, 0, len(names))
+	for _, name := range names {
+		f, ok := registry.byName[name]
+		if !ok {
+			return nil, fmt.Errorf("format `%s`: %w", name, ErrUnknownFormat)
+		}
+		formats = append(formats, f)
+	}
+
+	return formats, nil
+}
+
+// toBytes packs bits in bytes, the trailing bits that do not fill a byte are dropped
+func toBytes(bits []types.Bit) []byte {
+	data := make([]byte, len(bits)/8)
+
+	var byteBits [8]types.Bit
+	for i := range data {
+		copy(byteBits[:], bits[i*8:i*8+8])
+		data[i] = engine.BitsToByte(byteBits)
+	}
+
+	return data
+}
+
+// WriteFiles writes each stream to dir, named by its bit offset and the extension of its format,
+// and returns the paths of the files
+func WriteFiles(dir string, streams []Stream) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	registry.RLock()
+	defer registry.RUnlock()
+
+	paths := make([]string, 0, len(streams))
+	for _, s := range streams {
+		ext := s.Format
+		if f, ok := registry.byName[s.Format]; ok {
+			ext = f.Ext
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("%d.%s", s.Offset, ext))
+		if err := os.WriteFile(path, s.Data, 0o644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
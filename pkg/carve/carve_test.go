@@ -0,0 +1,246 @@
+package carve
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fedemengo/d2bist/pkg/compression"
+	"github.com/fedemengo/d2bist/pkg/engine"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+var payload = []byte(strings.Repeat("carve me out of the noise ", 64))
+
+func compress(t *testing.T, cType compression.CompressionType) []byte {
+	buf := &bytes.Buffer{}
+	w, err := compression.NewCompressedWriter(context.Background(), buf, cType)
+	require.NoError(t, err)
+	_, err = w.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 16; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 16), G: uint8(y * 16), A: 255})
+		}
+	}
+
+	return img
+}
+
+func pngFile(t *testing.T) []byte {
+	buf := &bytes.Buffer{}
+	require.NoError(t, png.Encode(buf, testImage()))
+
+	return buf.Bytes()
+}
+
+func jpegFile(t *testing.T) []byte {
+	buf := &bytes.Buffer{}
+	require.NoError(t, jpeg.Encode(buf, testImage(), nil))
+
+	return buf.Bytes()
+}
+
+func zipFile(t *testing.T) []byte {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.Create("payload.txt")
+	require.NoError(t, err)
+	_, err = w.Write(payload)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
+
+func bytesToBits(data []byte) []types.Bit {
+	bits := make([]types.Bit, 0, len(data)*8)
+	for _, b := range data {
+		byteBits := engine.ByteToBits(b)
+		bits = append(bits, byteBits[:]...)
+	}
+
+	return bits
+}
+
+func TestScan(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	junk := func() []byte {
+		b := make([]byte, 100+rnd.Intn(100))
+		rnd.Read(b)
+		return b
+	}
+
+	testCases := []struct {
+		format  string
+		data    []byte
+		decoded int
+	}{
+		{format: "gzip", data: compress(t, compression.Gzip), decoded: len(payload)},
+		{format: "zlib", data: compress(t, compression.Zlib), decoded: len(payload)},
+		{format: "zstd", data: compress(t, compression.Zstd), decoded: len(payload)},
+		{format: "bzip2", data: compress(t, compression.Bzip2), decoded: len(payload)},
+		{format: "xz", data: compress(t, compression.Xz), decoded: len(payload)},
+		{format: "lz4", data: compress(t, compression.Lz4), decoded: len(payload)},
+		{format: "png", data: pngFile(t)},
+		{format: "jpeg", data: jpegFile(t)},
+		{format: "zip", data: zipFile(t), decoded: len(payload)},
+	}
+
+	data := junk()
+	expected := []Stream{}
+	for _, tc := range testCases {
+		expected = append(expected, Stream{
+			Format:  tc.format,
+			Offset:  len(data) * 8,
+			Size:    len(tc.data),
+			Decoded: tc.decoded,
+			Data:    tc.data,
+		})
+		data = append(data, tc.data...)
+		data = append(data, junk()...)
+	}
+
+	streams, err := Scan(context.Background(), bytesToBits(data))
+	require.NoError(t, err)
+
+	// the image data of the png is a zlib stream
+	assert.Subset(t, streams, expected)
+	assert.Len(t, streams, len(expected)+1)
+}
+
+func TestScanBitOffsets(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	gz := compress(t, compression.Gzip)
+	bits := append(make([]types.Bit, 83), bytesToBits(gz)...)
+	bits = append(bits, make([]types.Bit, 64)...)
+
+	streams, err := Scan(context.Background(), bits)
+	r.NoError(err)
+	a.Empty(streams)
+
+	streams, err = Scan(context.Background(), bits, WithBitOffsets())
+	r.NoError(err)
+	r.Len(streams, 1)
+	a.Equal("gzip", streams[0].Format)
+	a.Equal(83, streams[0].Offset)
+	a.Equal(len(gz), streams[0].Size)
+	a.Equal(gz, streams[0].Data)
+}
+
+func TestScanFormats(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	data := append(compress(t, compression.Gzip), pngFile(t)...)
+	bits := bytesToBits(data)
+
+	streams, err := Scan(context.Background(), bits, WithFormats("png"))
+	r.NoError(err)
+	r.Len(streams, 1)
+	a.Equal("png", streams[0].Format)
+
+	_, err = Scan(context.Background(), bits, WithFormats("png", "rar"))
+	a.ErrorIs(err, ErrUnknownFormat)
+}
+
+func TestScanNested(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	// a stored file is not compressed, the png is found inside the zip
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "image.png", Method: zip.Store})
+	r.NoError(err)
+	_, err = w.Write(pngFile(t))
+	r.NoError(err)
+	r.NoError(zw.Close())
+
+	streams, err := Scan(context.Background(), bytesToBits(buf.Bytes()))
+	r.NoError(err)
+	r.Len(streams, 3)
+	a.Equal("zip", streams[0].Format)
+	a.Equal("png", streams[1].Format)
+	a.Equal(pngFile(t), streams[1].Data)
+	a.Equal("zlib", streams[2].Format)
+}
+
+func TestScanTruncated(t *testing.T) {
+	for _, cType := range []compression.CompressionType{compression.Gzip, compression.Zstd, compression.Bzip2, compression.Xz} {
+		t.Run(string(cType), func(t *testing.T) {
+			data := compress(t, cType)
+			streams, err := Scan(context.Background(), bytesToBits(data[:len(data)-8]))
+			require.NoError(t, err)
+			assert.Empty(t, streams)
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	err := Register(Format{Name: "gzip", Magic: []byte{0x1f, 0x8b}, Size: gzipSize})
+	assert.ErrorIs(t, err, ErrFormatExists)
+
+	err = Register(Format{Name: "empty"})
+	assert.Error(t, err)
+	assert.NotContains(t, Formats(), "empty")
+}
+
+func TestWriteFiles(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	dir := filepath.Join(t.TempDir(), "streams")
+	streams := []Stream{
+		{Format: "gzip", Offset: 80, Data: []byte{1, 2, 3}},
+		{Format: "png", Offset: 83, Data: []byte{4, 5}},
+	}
+
+	paths, err := WriteFiles(dir, streams)
+	r.NoError(err)
+	a.Equal([]string{filepath.Join(dir, "80.gz"), filepath.Join(dir, "83.png")}, paths)
+
+	for i, path := range paths {
+		data, err := os.ReadFile(path)
+		r.NoError(err)
+		a.Equal(streams[i].Data, data)
+	}
+}
+
+func TestScanELF(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	exe, err := os.Executable()
+	r.NoError(err)
+	bin, err := os.ReadFile(exe)
+	r.NoError(err)
+	if !bytes.HasPrefix(bin, []byte{0x7f, 'E', 'L', 'F'}) {
+		t.Skip("the test binary is not an elf file")
+	}
+
+	data := append([]byte("junk before the binary"), bin...)
+	data = append(data, []byte("junk after the binary")...)
+
+	streams, err := Scan(context.Background(), bytesToBits(data), WithFormats("elf"))
+	r.NoError(err)
+	r.NotEmpty(streams)
+	a.Equal(len("junk before the binary")*8, streams[0].Offset)
+	a.Equal(len(bin), streams[0].Size)
+}
@@ -0,0 +1,420 @@
+package carve
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image/jpeg"
+	"io"
+
+	"github.com/fedemengo/d2bist/pkg/compression"
+)
+
+func init() {
+	mustRegister(Format{
+		Name:  "gzip",
+		Ext:   "gz",
+		Magic: []byte{0x1f, 0x8b, 0x08},
+		Size:  gzipSize,
+	})
+	mustRegister(Format{
+		Name:  "zlib",
+		Ext:   "zlib",
+		Magic: []byte{0x78},
+		Size:  zlibSize,
+	})
+	mustRegister(Format{
+		Name:  "zstd",
+		Ext:   "zst",
+		Magic: []byte{0x28, 0xb5, 0x2f, 0xfd},
+		Size:  zstdSize,
+	})
+	mustRegister(Format{
+		Name:  "bzip2",
+		Ext:   "bz2",
+		Magic: []byte("BZh"),
+		Size:  bzip2Size,
+	})
+	mustRegister(Format{
+		Name:  "xz",
+		Ext:   "xz",
+		Magic: []byte{0xfd, '7', 'z', 'X', 'Z', 0x00},
+		Size:  xzSize,
+	})
+	mustRegister(Format{
+		Name:  "lz4",
+		Ext:   "lz4",
+		Magic: []byte{0x04, 0x22, 0x4d, 0x18},
+		Size:  lz4Size,
+	})
+	mustRegister(Format{
+		Name:  "png",
+		Ext:   "png",
+		Magic: []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'},
+		Size:  pngSize,
+	})
+	mustRegister(Format{
+		Name:  "jpeg",
+		Ext:   "jpg",
+		Magic: []byte{0xff, 0xd8, 0xff},
+		Size:  jpegSize,
+	})
+	mustRegister(Format{
+		Name:  "zip",
+		Ext:   "zip",
+		Magic: []byte{'P', 'K', 0x03, 0x04},
+		Size:  zipSize,
+	})
+	mustRegister(Format{
+		Name:  "elf",
+		Ext:   "elf",
+		Magic: []byte{0x7f, 'E', 'L', 'F'},
+		Size:  elfSize,
+	})
+}
+
+// decodedSize decodes data with the codec and returns the size of the decoded data
+func decodedSize(ctx context.Context, data []byte, cType compression.CompressionType) (int, error) {
+	r, err := compression.NewCompressedReader(ctx, bytes.NewReader(data), cType)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v: %w", cType, err, ErrInvalidStream)
+	}
+
+	n, err := io.Copy(io.Discard, r)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v: %w", cType, err, ErrInvalidStream)
+	}
+
+	return int(n), nil
+}
+
+// consumedSize decodes the stream at the start of data with a reader that does not read past
+// its end, the stream size is how much of data was read
+func consumedSize(data []byte, newReader func(r *bytes.Reader) (io.Reader, error)) (int, int, error) {
+	br := bytes.NewReader(data)
+	r, err := newReader(br)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%v: %w", err, ErrInvalidStream)
+	}
+
+	n, err := io.Copy(io.Discard, r)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%v: %w", err, ErrInvalidStream)
+	}
+
+	return len(data) - br.Len(), int(n), nil
+}
+
+// gzipSize decodes a single gzip member, the flate reader reads byte by byte from a bytes.Reader
+func gzipSize(_ context.Context, data []byte) (int, int, error) {
+	return consumedSize(data, func(r *bytes.Reader) (io.Reader, error) {
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		gr.Multistream(false)
+
+		return gr, nil
+	})
+}
+
+// zlibSize checks the header of streams with a 32K window, the magic is only the first byte
+// because the second depends on the compression level
+func zlibSize(ctx context.Context, data []byte) (int, int, error) {
+	if len(data) < 2 || (int(data[0])<<8|int(data[1]))%31 != 0 || data[1]&0x20 != 0 {
+		return 0, 0, fmt.Errorf("zlib header: %w", ErrInvalidStream)
+	}
+
+	return consumedSize(data, func(r *bytes.Reader) (io.Reader, error) {
+		return compression.NewCompressedReader(ctx, r, compression.Zlib)
+	})
+}
+
+func lz4Size(ctx context.Context, data []byte) (int, int, error) {
+	return consumedSize(data, func(r *bytes.Reader) (io.Reader, error) {
+		return compression.NewCompressedReader(ctx, r, compression.Lz4)
+	})
+}
+
+// zstdSize walks the blocks of a zstd frame, see RFC 8878
+func zstdSize(ctx context.Context, data []byte) (int, int, error) {
+	if len(data) < 5 {
+		return 0, 0, fmt.Errorf("zstd header: %w", ErrInvalidStream)
+	}
+
+	fhd := data[4]
+	singleSegment := fhd&0x20 != 0
+	checksum := fhd&0x04 != 0
+
+	size := 5
+	if !singleSegment {
+		size++
+	}
+	size += []int{0, 1, 2, 4}[fhd&0x03]
+	switch fcs := fhd >> 6; {
+	case fcs == 0 && singleSegment:
+		size++
+	case fcs > 0:
+		size += 1 << fcs
+	}
+
+	for last := false; !last; {
+		if size+3 > len(data) {
+			return 0, 0, fmt.Errorf("zstd block header: %w", ErrInvalidStream)
+		}
+
+		header := uint32(data[size]) | uint32(data[size+1])<<8 | uint32(data[size+2])<<16
+		size += 3
+
+		last = header&1 != 0
+		switch blockType := (header >> 1) & 0x03; blockType {
+		case 1:
+			// rle blocks store a single byte
+			size++
+		case 3:
+			return 0, 0, fmt.Errorf("zstd reserved block type: %w", ErrInvalidStream)
+		default:
+			size += int(header >> 3)
+		}
+	}
+	if checksum {
+		size += 4
+	}
+	if size > len(data) {
+		return 0, 0, fmt.Errorf("zstd frame truncated: %w", ErrInvalidStream)
+	}
+
+	decoded, err := decodedSize(ctx, data[:size], compression.Zstd)
+	return size, decoded, err
+}
+
+// bzip2EOS is the 48 bits magic of the end of a bzip2 stream, followed by the 32 bits stream crc
+const bzip2EOS = 0x177245385090
+
+// bzip2Size looks for the end of stream magic, which is not byte aligned
+func bzip2Size(ctx context.Context, data []byte) (int, int, error) {
+	const mask = 1<<48 - 1
+
+	window := uint64(0)
+	for i := 4; i < len(data); i++ {
+		for bit := 7; bit >= 0; bit-- {
+			window = (window<<1 | uint64(data[i]>>bit&1)) & mask
+
+			// the window ends at bit end, the crc and the padding to the byte follow
+			end := i*8 + 8 - bit
+			if window != bzip2EOS || end < 4*8+48 {
+				continue
+			}
+
+			size := (end + 32 + 7) / 8
+			if size > len(data) {
+				return 0, 0, fmt.Errorf("bzip2 stream truncated: %w", ErrInvalidStream)
+			}
+
+			decoded, err := decodedSize(ctx, data[:size], compression.Bzip2)
+			return size, decoded, err
+		}
+	}
+
+	return 0, 0, fmt.Errorf("bzip2 end of stream not found: %w", ErrInvalidStream)
+}
+
+// xzSize looks for the stream footer, whose flags match the stream header, see the xz file format
+func xzSize(ctx context.Context, data []byte) (int, int, error) {
+	const headerLen, footerLen = 12, 12
+	if len(data) < headerLen+footerLen {
+		return 0, 0, fmt.Errorf("xz header: %w", ErrInvalidStream)
+	}
+	flags := data[6:8]
+
+	for i := headerLen; i+footerLen <= len(data); i++ {
+		footer := data[i : i+footerLen]
+		if footer[10] != 'Y' || footer[11] != 'Z' || !bytes.Equal(footer[8:10], flags) {
+			continue
+		}
+		if crc32.ChecksumIEEE(footer[4:10]) != binary.LittleEndian.Uint32(footer[:4]) {
+			continue
+		}
+
+		size := i + footerLen
+		decoded, err := decodedSize(ctx, data[:size], compression.Xz)
+		return size, decoded, err
+	}
+
+	return 0, 0, fmt.Errorf("xz stream footer not found: %w", ErrInvalidStream)
+}
+
+// pngSize walks the chunks up to IEND and checks their crc
+func pngSize(_ context.Context, data []byte) (int, int, error) {
+	size := 8
+	for {
+		if size+12 > len(data) {
+			return 0, 0, fmt.Errorf("png chunk truncated: %w", ErrInvalidStream)
+		}
+
+		length := int(binary.BigEndian.Uint32(data[size:]))
+		if length < 0 || size+12+length > len(data) {
+			return 0, 0, fmt.Errorf("png chunk truncated: %w", ErrInvalidStream)
+		}
+
+		chunk := data[size+4 : size+8+length]
+		if crc32.ChecksumIEEE(chunk) != binary.BigEndian.Uint32(data[size+8+length:]) {
+			return 0, 0, fmt.Errorf("png chunk `%s` crc: %w", chunk[:4], ErrInvalidStream)
+		}
+
+		size += 12 + length
+		if string(chunk[:4]) == "IEND" {
+			return size, 0, nil
+		}
+	}
+}
+
+// jpegSize walks the marker segments and the entropy coded data up to the end of image
+func jpegSize(_ context.Context, data []byte) (int, int, error) {
+	size := 2
+	for size+2 <= len(data) {
+		if data[size] != 0xff {
+			return 0, 0, fmt.Errorf("jpeg marker expected at %d: %w", size, ErrInvalidStream)
+		}
+
+		marker := data[size+1]
+		switch {
+		case marker == 0xff:
+			// fill byte
+			size++
+			continue
+		case marker == 0xd9:
+			size += 2
+			if _, err := jpeg.DecodeConfig(bytes.NewReader(data[:size])); err != nil {
+				return 0, 0, fmt.Errorf("jpeg: %v: %w", err, ErrInvalidStream)
+			}
+			return size, 0, nil
+		case marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7):
+			// markers without a segment
+			size += 2
+			continue
+		}
+
+		if size+4 > len(data) {
+			break
+		}
+		size += 2 + int(binary.BigEndian.Uint16(data[size+2:]))
+
+		if marker == 0xda {
+			// entropy coded data ends at a marker that is not a restart marker or a stuffed 0
+			for size+1 < len(data) {
+				if data[size] == 0xff && data[size+1] != 0 && (data[size+1] < 0xd0 || data[size+1] > 0xd7) {
+					break
+				}
+				size++
+			}
+		}
+	}
+
+	return 0, 0, fmt.Errorf("jpeg end of image not found: %w", ErrInvalidStream)
+}
+
+// zipSize looks for the end of central directory record and decodes every file of the archive
+func zipSize(_ context.Context, data []byte) (int, int, error) {
+	eocd := []byte{'P', 'K', 0x05, 0x06}
+
+	for i := bytes.Index(data, eocd); i >= 0; {
+		if i+22 > len(data) {
+			break
+		}
+
+		size := i + 22 + int(binary.LittleEndian.Uint16(data[i+20:]))
+		if size <= len(data) {
+			if decoded, err := zipDecodedSize(data[:size]); err == nil {
+				return size, decoded, nil
+			}
+		}
+
+		next := bytes.Index(data[i+1:], eocd)
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+
+	return 0, 0, fmt.Errorf("zip end of central directory not found: %w", ErrInvalidStream)
+}
+
+func zipDecodedSize(data []byte) (int, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return 0, err
+	}
+
+	decoded := 0
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			return 0, err
+		}
+
+		n, err := io.Copy(io.Discard, r)
+		r.Close()
+		if err != nil {
+			return 0, err
+		}
+		decoded += int(n)
+	}
+
+	return decoded, nil
+}
+
+// elfSize is the end of the furthest of the header tables, the program segments and the sections
+func elfSize(_ context.Context, data []byte) (int, int, error) {
+	f, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, fmt.Errorf("elf: %v: %w", err, ErrInvalidStream)
+	}
+
+	var phOff, shOff uint64
+	var phEntSize, phNum, shEntSize, shNum uint16
+	order := f.ByteOrder
+	switch f.Class {
+	case elf.ELFCLASS64:
+		phOff, shOff = order.Uint64(data[32:]), order.Uint64(data[40:])
+		phEntSize, phNum = order.Uint16(data[54:]), order.Uint16(data[56:])
+		shEntSize, shNum = order.Uint16(data[58:]), order.Uint16(data[60:])
+	default:
+		phOff, shOff = uint64(order.Uint32(data[28:])), uint64(order.Uint32(data[32:]))
+		phEntSize, phNum = order.Uint16(data[42:]), order.Uint16(data[44:])
+		shEntSize, shNum = order.Uint16(data[46:]), order.Uint16(data[48:])
+	}
+
+	size := uint64(52)
+	if f.Class == elf.ELFCLASS64 {
+		size = 64
+	}
+	size = maxUint64(size, phOff+uint64(phEntSize)*uint64(phNum))
+	size = maxUint64(size, shOff+uint64(shEntSize)*uint64(shNum))
+	for _, p := range f.Progs {
+		size = maxUint64(size, p.Off+p.Filesz)
+	}
+	for _, s := range f.Sections {
+		if s.Type != elf.SHT_NOBITS {
+			size = maxUint64(size, s.Offset+s.FileSize)
+		}
+	}
+
+	if size > uint64(len(data)) {
+		return 0, 0, fmt.Errorf("elf truncated: %w", ErrInvalidStream)
+	}
+
+	return int(size), 0, nil
+}
+
+func maxUint64(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,147 @@
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/pierrec/lz4/v4"
+)
+
+// block formats have no framing, the whole data is coded at once
+
+type blockWriter struct {
+	w      io.Writer
+	buf    bytes.Buffer
+	encode func(src []byte) ([]byte, error)
+}
+
+// newBlockWriter buffers data and writes it coded by encode when closed
+func newBlockWriter(w io.Writer, encode func(src []byte) ([]byte, error)) io.WriteCloser {
+	return &blockWriter{w: w, encode: encode}
+}
+
+func (w *blockWriter) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
+
+func (w *blockWriter) Close() error {
+	coded, err := w.encode(w.buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	_, err = w.w.Write(coded)
+	return err
+}
+
+type blockReader struct {
+	r       io.Reader
+	decoded *bytes.Reader
+	decode  func(src []byte) ([]byte, error)
+}
+
+// newBlockReader reads all of r and decodes it with decode
+func newBlockReader(r io.Reader, decode func(src []byte) ([]byte, error)) io.Reader {
+	return &blockReader{r: r, decode: decode}
+}
+
+func (r *blockReader) Read(b []byte) (int, error) {
+	if r.decoded == nil {
+		src, err := io.ReadAll(r.r)
+		if err != nil {
+			return 0, err
+		}
+
+		data, err := r.decode(src)
+		if err != nil {
+			return 0, err
+		}
+		r.decoded = bytes.NewReader(data)
+	}
+
+	return r.decoded.Read(b)
+}
+
+// snappyEncoder codes snappy blocks, levels are default, better and best like s2
+func snappyEncoder(level int) func(src []byte) ([]byte, error) {
+	return func(src []byte) ([]byte, error) {
+		switch level {
+		case 1:
+			return s2.EncodeSnappy(nil, src), nil
+		case 2:
+			return s2.EncodeSnappyBetter(nil, src), nil
+		default:
+			return s2.EncodeSnappyBest(nil, src), nil
+		}
+	}
+}
+
+func snappyDecode(src []byte) ([]byte, error) {
+	return s2.Decode(nil, src)
+}
+
+// lz4Encoder codes lz4 blocks, level 0 is the fast compressor, 1 to 9 the high compression one
+func lz4Encoder(level int) func(src []byte) ([]byte, error) {
+	return func(src []byte) ([]byte, error) {
+		dst := make([]byte, lz4.CompressBlockBound(len(src)))
+
+		var n int
+		var err error
+		if level == 0 {
+			n, err = lz4.CompressBlock(src, dst, nil)
+		} else {
+			n, err = lz4.CompressBlockHC(src, dst, lz4Level(level), nil, nil)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return dst[:n], nil
+	}
+}
+
+// maxLZ4Ratio bounds the size of a decoded lz4 block, the block does not store it
+const maxLZ4Ratio = 255
+
+func lz4Decode(src []byte) ([]byte, error) {
+	if len(src) == 0 {
+		return nil, nil
+	}
+
+	size := 4 * len(src)
+	for {
+		dst := make([]byte, size)
+		n, err := lz4.UncompressBlock(src, dst)
+		if err == nil {
+			return dst[:n], nil
+		}
+		if size >= maxLZ4Ratio*len(src) {
+			return nil, fmt.Errorf("cannot decode lz4 block: %w", err)
+		}
+		size = min(2*size, maxLZ4Ratio*len(src))
+	}
+}
+
+func lz4Level(level int) lz4.CompressionLevel {
+	if level == 0 {
+		return lz4.Fast
+	}
+
+	return lz4.CompressionLevel(1 << (8 + level))
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
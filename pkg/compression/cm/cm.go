@@ -0,0 +1,247 @@
+// Package cm implements a bit-level adaptive binary arithmetic coder
+// driven by a mix of order-n bit contexts (a PAQ-lite context mixer)
+//
+// the stream layout is
+//
+//	magic (2 bytes) | order (1 byte) | uvarint data length | coded bits
+package cm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	DefaultOrder = 32
+	MaxOrder     = 32
+
+	// maxRatio bounds the bytes decoded per coded byte, a bit predicted with the highest
+	// probability (4095/4096) costs 0.00035 bits, so a coded byte holds at most ~2840 bytes
+	maxRatio = 4096
+)
+
+var (
+	ErrInvalidHeader = errors.New("invalid cm header")
+	ErrInvalidOrder  = errors.New("invalid cm order")
+)
+
+var magic = [2]byte{0xd2, 0xcb}
+
+type writer struct {
+	w     io.Writer
+	order int
+	buf   bytes.Buffer
+}
+
+// NewWriter returns a WriteCloser that compresses data using contexts up to order bits
+//
+// data is buffered and coded when the writer is closed
+func NewWriter(w io.Writer, order int) (io.WriteCloser, error) {
+	if order < 0 || order > MaxOrder {
+		return nil, fmt.Errorf("order %d not in [0, %d]: %w", order, MaxOrder, ErrInvalidOrder)
+	}
+
+	return &writer{w: w, order: order}, nil
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
+
+func (w *writer) Close() error {
+	data := w.buf.Bytes()
+
+	header := make([]byte, 0, len(magic)+1+binary.MaxVarintLen64)
+	header = append(header, magic[:]...)
+	header = append(header, byte(w.order))
+	header = binary.AppendUvarint(header, uint64(len(data)))
+	if _, err := w.w.Write(header); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w.w)
+	enc := &encoder{x2: 0xffffffff, w: bw}
+	p := newPredictor(w.order)
+	for _, c := range data {
+		for i := 7; i >= 0; i-- {
+			bit := int(c>>i) & 1
+			enc.encode(bit, p.p())
+			p.update(bit)
+		}
+	}
+	enc.flush()
+
+	if enc.err != nil {
+		return enc.err
+	}
+
+	return bw.Flush()
+}
+
+type reader struct {
+	r       *bufio.Reader
+	decoded *bytes.Reader
+}
+
+// NewReader returns a Reader that decompresses data written by a cm writer
+func NewReader(r io.Reader) (io.Reader, error) {
+	return &reader{r: bufio.NewReader(r)}, nil
+}
+
+func (r *reader) Read(b []byte) (int, error) {
+	if r.decoded == nil {
+		data, err := r.decode()
+		if err != nil {
+			return 0, err
+		}
+		r.decoded = bytes.NewReader(data)
+	}
+
+	return r.decoded.Read(b)
+}
+
+func (r *reader) decode() ([]byte, error) {
+	header := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		return nil, fmt.Errorf("cannot read header: %w", ErrInvalidHeader)
+	}
+	if !bytes.Equal(header[:len(magic)], magic[:]) {
+		return nil, fmt.Errorf("bad magic %x: %w", header[:len(magic)], ErrInvalidHeader)
+	}
+
+	order := int(header[len(magic)])
+	if order > MaxOrder {
+		return nil, fmt.Errorf("order %d not in [0, %d]: %w", order, MaxOrder, ErrInvalidOrder)
+	}
+
+	size, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read length: %w", ErrInvalidHeader)
+	}
+
+	dec := newDecoder(r.r)
+	p := newPredictor(order)
+	data := make([]byte, 0, min(size, 1<<20))
+	for i := uint64(0); i < size; i++ {
+		if dec.err != nil {
+			return nil, dec.err
+		}
+		if i > maxRatio*dec.n {
+			return nil, fmt.Errorf("length %d is too long for the coded data: %w", size, ErrInvalidHeader)
+		}
+
+		c := byte(0)
+		for j := 0; j < 8; j++ {
+			bit := dec.decode(p.p())
+			p.update(bit)
+			c = c<<1 | byte(bit)
+		}
+		data = append(data, c)
+	}
+
+	if dec.err != nil {
+		return nil, dec.err
+	}
+
+	return data, nil
+}
+
+// encoder is a carryless binary arithmetic coder with 32 bits precision
+type encoder struct {
+	x1, x2 uint32
+	w      io.ByteWriter
+	err    error
+}
+
+// encode narrows the range [x1, x2] according to p1, the probability of a 1 in 12 bits
+func (e *encoder) encode(bit, p1 int) {
+	xmid := e.x1 + (e.x2-e.x1)>>12*uint32(p1)
+	if bit == 1 {
+		e.x2 = xmid
+	} else {
+		e.x1 = xmid + 1
+	}
+
+	for (e.x1^e.x2)&0xff000000 == 0 {
+		e.writeByte(byte(e.x2 >> 24))
+		e.x1 <<= 8
+		e.x2 = e.x2<<8 | 0xff
+	}
+}
+
+func (e *encoder) flush() {
+	for i := 0; i < 4; i++ {
+		e.writeByte(byte(e.x1 >> 24))
+		e.x1 <<= 8
+	}
+}
+
+func (e *encoder) writeByte(b byte) {
+	if e.err == nil {
+		e.err = e.w.WriteByte(b)
+	}
+}
+
+type decoder struct {
+	x1, x2, x uint32
+	r         io.ByteReader
+	n         uint64
+	err       error
+}
+
+func newDecoder(r io.ByteReader) *decoder {
+	d := &decoder{x2: 0xffffffff, r: r}
+	for i := 0; i < 4; i++ {
+		d.x = d.x<<8 | uint32(d.readByte())
+	}
+
+	return d
+}
+
+func (d *decoder) decode(p1 int) int {
+	xmid := d.x1 + (d.x2-d.x1)>>12*uint32(p1)
+
+	bit := 0
+	if d.x <= xmid {
+		bit = 1
+		d.x2 = xmid
+	} else {
+		d.x1 = xmid + 1
+	}
+
+	for (d.x1^d.x2)&0xff000000 == 0 {
+		d.x1 <<= 8
+		d.x2 = d.x2<<8 | 0xff
+		d.x = d.x<<8 | uint32(d.readByte())
+	}
+
+	return bit
+}
+
+func (d *decoder) readByte() byte {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		// the decoder reads as many bytes as the encoder wrote, the coded data ended early
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		if d.err == nil {
+			d.err = err
+		}
+		return 0
+	}
+	d.n++
+
+	return b
+}
+
+func min(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
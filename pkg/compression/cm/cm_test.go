@@ -0,0 +1,127 @@
+package cm
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compress(t *testing.T, data []byte, order int) []byte {
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, order)
+	require.NoError(t, err)
+
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(0)).Read(random)
+
+	testCases := []struct {
+		name  string
+		data  []byte
+		order int
+	}{
+		{
+			name:  "empty",
+			data:  []byte{},
+			order: DefaultOrder,
+		}, {
+			name:  "text",
+			data:  []byte(strings.Repeat("a longer text so that compression actually does something nice ", 20)),
+			order: DefaultOrder,
+		}, {
+			name:  "random",
+			data:  random,
+			order: DefaultOrder,
+		}, {
+			name:  "order 0",
+			data:  []byte("dead beef"),
+			order: 0,
+		}, {
+			name:  "max order",
+			data:  bytes.Repeat([]byte{0xde, 0xad, 0xbe, 0xef}, 300),
+			order: MaxOrder,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			a, r := assert.New(tt), require.New(tt)
+
+			compressed := compress(tt, tc.data, tc.order)
+
+			cr, err := NewReader(bytes.NewReader(compressed))
+			r.NoError(err)
+
+			data, err := io.ReadAll(cr)
+			r.NoError(err)
+			a.Equal(tc.data, data)
+		})
+	}
+}
+
+func TestCompressionRatio(t *testing.T) {
+	a := assert.New(t)
+
+	// a period-3 bit pattern is not aligned to bytes
+	bits := strings.Repeat("011", 8*1000)
+	data := make([]byte, len(bits)/8)
+	for i := range data {
+		for j := 0; j < 8; j++ {
+			data[i] = data[i]<<1 | bits[8*i+j] - '0'
+		}
+	}
+
+	a.Less(len(compress(t, data, DefaultOrder)), len(data)/20)
+
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(random)
+	a.Less(len(compress(t, random, DefaultOrder)), len(random)+len(random)/50)
+}
+
+func TestInvalidStream(t *testing.T) {
+	r := require.New(t)
+
+	_, err := NewWriter(new(bytes.Buffer), MaxOrder+1)
+	r.ErrorIs(err, ErrInvalidOrder)
+
+	cr, err := NewReader(bytes.NewReader([]byte("not a cm stream")))
+	r.NoError(err)
+
+	_, err = io.ReadAll(cr)
+	r.ErrorIs(err, ErrInvalidHeader)
+}
+
+func TestCorruptStream(t *testing.T) {
+	data := compress(t, []byte(strings.Repeat("truncate me ", 100)), DefaultOrder)
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{name: "truncated", data: data[:len(data)-4]},
+		{name: "huge length", data: []byte{0xd2, 0xcb, 0x20, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}},
+		{name: "garbage", data: append([]byte{0xd2, 0xcb, 0x20, 0xff, 0xff, 0xff, 0xff, 0x0f}, make([]byte, 64)...)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cr, err := NewReader(bytes.NewReader(tc.data))
+			require.NoError(t, err)
+
+			_, err = io.ReadAll(cr)
+			assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		})
+	}
+}
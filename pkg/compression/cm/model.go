@@ -0,0 +1,199 @@
+package cm
+
+import "math"
+
+const (
+	// orders above directBits use a hashed table of 1<<hashBits entries
+	directBits = 16
+	hashBits   = 18
+
+	countLimit   = 255
+	learningRate = 6
+	mixerSets    = 8
+)
+
+var (
+	modelOrders = []int{0, 1, 2, 3, 4, 6, 8, 12, 16, 20, 24, 32}
+
+	stretchTable [4096]int32
+	squashTable  [4096]int32
+	dt           [1024]int64
+)
+
+func init() {
+	for i := range squashTable {
+		d := float64(i-2048) / 256
+		squashTable[i] = int32(4096 / (1 + math.Exp(-d)))
+	}
+
+	// stretch is the inverse of squash: stretch(squash(d)) ≈ d
+	pi := 0
+	for d := -2047; d <= 2047; d++ {
+		v := int(squash(int32(d)))
+		for j := pi; j <= v; j++ {
+			stretchTable[j] = int32(d)
+		}
+		pi = v + 1
+	}
+	for j := pi; j < 4096; j++ {
+		stretchTable[j] = 2047
+	}
+
+	for i := range dt {
+		dt[i] = int64(16384 / (i + i + 3))
+	}
+}
+
+// squash maps the logistic domain [-2047, 2047] to a probability in 12 bits
+func squash(d int32) int32 {
+	if d > 2047 {
+		d = 2047
+	}
+	if d < -2047 {
+		d = -2047
+	}
+
+	return squashTable[d+2048]
+}
+
+func stretch(p int32) int32 {
+	return stretchTable[p]
+}
+
+// stateMap maps a context to an adaptive probability
+//
+// each entry holds the probability of a 1 in the high 22 bits and
+// the number of observations in the low 10 bits, used to slow down the learning rate
+type stateMap struct {
+	t   []uint32
+	cxt int
+}
+
+func newStateMap(size int) *stateMap {
+	t := make([]uint32, size)
+	for i := range t {
+		t[i] = 1 << 31
+	}
+
+	return &stateMap{t: t}
+}
+
+func (s *stateMap) p(cxt int) int32 {
+	s.cxt = cxt
+	return int32(s.t[cxt] >> 20)
+}
+
+func (s *stateMap) update(bit int) {
+	t := int64(s.t[s.cxt])
+	n, p := t&1023, t>>10
+	if n < countLimit {
+		t++
+	}
+	t += ((int64(bit)<<22 - p) >> 3) * dt[n] &^ 1023
+	s.t[s.cxt] = uint32(t)
+}
+
+// mixer combines the stretched predictions with a weight set selected by a small context
+type mixer struct {
+	weights [][]int32
+	inputs  []int32
+	set     int
+	pr      int32
+}
+
+func newMixer(n, sets int) *mixer {
+	weights := make([][]int32, sets)
+	for i := range weights {
+		weights[i] = make([]int32, n)
+		for j := range weights[i] {
+			weights[i][j] = int32(65536 / n)
+		}
+	}
+
+	return &mixer{weights: weights, inputs: make([]int32, n)}
+}
+
+func (m *mixer) mix(set int) int32 {
+	m.set = set
+	w := m.weights[set]
+
+	dot := int64(0)
+	for i, x := range m.inputs {
+		dot += int64(x) * int64(w[i])
+	}
+	m.pr = squash(int32(dot >> 16))
+
+	return m.pr
+}
+
+func (m *mixer) update(bit int) {
+	err := (int64(bit)<<12 - int64(m.pr)) * learningRate
+	w := m.weights[m.set]
+	for i, x := range m.inputs {
+		w[i] += int32((int64(x) * err) >> 14)
+	}
+}
+
+// predictor estimates the probability that the next bit is a 1
+// given the previous bits, mixing one model per context order
+type predictor struct {
+	orders  []int
+	maps    []*stateMap
+	mixer   *mixer
+	history uint64
+	pr      int32
+}
+
+func newPredictor(order int) *predictor {
+	p := &predictor{}
+	for _, o := range modelOrders {
+		if o > order {
+			break
+		}
+		size := 1 << o
+		if o > directBits {
+			size = 1 << hashBits
+		}
+		p.orders = append(p.orders, o)
+		p.maps = append(p.maps, newStateMap(size))
+	}
+	p.mixer = newMixer(len(p.orders), mixerSets)
+	p.predict()
+
+	return p
+}
+
+func (p *predictor) predict() {
+	for i, o := range p.orders {
+		cxt := p.history & (1<<o - 1)
+		idx := int(cxt)
+		if o > directBits {
+			idx = int((cxt * 0x9e3779b97f4a7c15) >> (64 - hashBits))
+		}
+		p.mixer.inputs[i] = stretch(p.maps[i].p(idx))
+	}
+
+	pr := p.mixer.mix(int(p.history & (mixerSets - 1)))
+	if pr < 1 {
+		pr = 1
+	}
+	if pr > 4095 {
+		pr = 4095
+	}
+	p.pr = pr
+}
+
+// p returns the probability of a 1 in 12 bits
+func (p *predictor) p() int {
+	return int(p.pr)
+}
+
+func (p *predictor) update(bit int) {
+	for _, s := range p.maps {
+		s.update(bit)
+	}
+	p.mixer.update(bit)
+
+	p.history = p.history<<1 | uint64(bit)
+	p.predict()
+}
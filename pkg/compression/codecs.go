@@ -0,0 +1,340 @@
+package compression
+
+import (
+	"compress/gzip"
+	"image/color"
+	"io"
+
+	"github.com/andybalholm/brotli"
+	"github.com/dsnet/compress/bzip2"
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+	"github.com/ulikunitz/xz/lzma"
+
+	"github.com/fedemengo/d2bist/pkg/compression/cm"
+	"github.com/fedemengo/d2bist/pkg/compression/lzw"
+)
+
+// the builtin codecs, in the order they are compared
+func init() {
+	mustRegister(Codec{
+		Type:         Zip,
+		Aliases:      []string{"zip", "deflate", "flate"},
+		Levels:       []int{flate.BestSpeed, 6, flate.BestCompression},
+		DefaultLevel: flate.BestCompression,
+		Dict:         true,
+		NewReader: func(r io.Reader, p Params) (io.Reader, error) {
+			if len(p.Dict) > 0 {
+				return flate.NewReaderDict(r, p.Dict), nil
+			}
+			return flate.NewReader(r), nil
+		},
+		NewWriter: func(w io.Writer, p Params) (io.WriteCloser, error) {
+			if len(p.Dict) > 0 {
+				return flate.NewWriterDict(w, p.Level, p.Dict)
+			}
+			return flate.NewWriter(w, p.Level)
+		},
+		Color: color.RGBA{R: 25, G: 25, B: 112, A: 255},
+	})
+
+	mustRegister(Codec{
+		Type:         Gzip,
+		Aliases:      []string{"gz", "gzip"},
+		Magic:        [][]byte{{0x1f, 0x8b}},
+		Levels:       []int{flate.BestSpeed, 6, flate.BestCompression},
+		DefaultLevel: flate.BestCompression,
+		NewReader: func(r io.Reader, _ Params) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+		NewWriter: func(w io.Writer, p Params) (io.WriteCloser, error) {
+			return gzip.NewWriterLevel(w, p.Level)
+		},
+		Color:   color.RGBA{R: 0, G: 0, B: 255, A: 255},
+		Entropy: true,
+	})
+
+	mustRegister(Codec{
+		Type:         Brotli,
+		Aliases:      []string{"b", "brotli"},
+		Levels:       []int{brotli.BestSpeed, brotli.DefaultCompression, brotli.BestCompression},
+		DefaultLevel: brotli.BestCompression,
+		Window:       [2]int{10, 24},
+		NewReader: func(r io.Reader, _ Params) (io.Reader, error) {
+			return brotli.NewReader(r), nil
+		},
+		NewWriter: func(w io.Writer, p Params) (io.WriteCloser, error) {
+			if err := CheckLevel(Brotli, p.Level, brotli.BestSpeed, brotli.BestCompression); err != nil {
+				return nil, err
+			}
+			return brotli.NewWriterOptions(w, brotli.WriterOptions{Quality: p.Level, LGWin: p.Window}), nil
+		},
+		Color:   color.RGBA{R: 0, G: 255, B: 0, A: 255},
+		Entropy: true,
+	})
+
+	mustRegister(Codec{
+		Type:         Zstd,
+		Aliases:      []string{"zstd"},
+		Magic:        [][]byte{{0x28, 0xb5, 0x2f, 0xfd}},
+		Levels:       []int{int(zstd.SpeedFastest), int(zstd.SpeedDefault), int(zstd.SpeedBetterCompression), int(zstd.SpeedBestCompression)},
+		DefaultLevel: int(zstd.SpeedDefault),
+		Window:       [2]int{10, 29},
+		Dict:         true,
+		NewReader: func(r io.Reader, p Params) (io.Reader, error) {
+			if len(p.Dict) > 0 {
+				return zstd.NewReader(r, zstd.WithDecoderDicts(p.Dict))
+			}
+			return zstd.NewReader(r)
+		},
+		NewWriter: func(w io.Writer, p Params) (io.WriteCloser, error) {
+			zOpts := []zstd.EOption{zstd.WithEncoderLevel(zstd.EncoderLevel(p.Level))}
+			if p.Window > 0 {
+				zOpts = append(zOpts, zstd.WithWindowSize(1<<p.Window))
+			}
+			if len(p.Dict) > 0 {
+				zOpts = append(zOpts, zstd.WithEncoderDict(p.Dict))
+			}
+			return zstd.NewWriter(w, zOpts...)
+		},
+		Color: color.RGBA{R: 165, G: 42, B: 42, A: 255},
+	})
+
+	mustRegister(Codec{
+		Type:         S2,
+		Aliases:      []string{"s2"},
+		Magic:        [][]byte{append([]byte{0xff, 0x06, 0x00, 0x00}, "S2sTwO"...)},
+		Levels:       []int{1, 2, 3},
+		DefaultLevel: 3,
+		NewReader: func(r io.Reader, _ Params) (io.Reader, error) {
+			return s2.NewReader(r), nil
+		},
+		NewWriter: func(w io.Writer, p Params) (io.WriteCloser, error) {
+			if err := CheckLevel(S2, p.Level, 1, 3); err != nil {
+				return nil, err
+			}
+			return s2.NewWriter(w, s2Level(p.Level)...), nil
+		},
+		Color: color.RGBA{R: 0, G: 128, B: 128, A: 255},
+	})
+
+	mustRegister(Codec{
+		Type:    Huff,
+		Aliases: []string{"h", "huff"},
+	})
+
+	mustRegister(Codec{
+		Type:         Bzip2,
+		Aliases:      []string{"bz2", "bzip2"},
+		Magic:        [][]byte{[]byte("BZh")},
+		Levels:       []int{bzip2.BestSpeed, bzip2.DefaultCompression, bzip2.BestCompression},
+		DefaultLevel: bzip2.BestCompression,
+		NewReader: func(r io.Reader, _ Params) (io.Reader, error) {
+			return bzip2.NewReader(r, nil)
+		},
+		NewWriter: func(w io.Writer, p Params) (io.WriteCloser, error) {
+			return bzip2.NewWriter(w, &bzip2.WriterConfig{Level: p.Level})
+		},
+		Color:   color.RGBA{R: 255, G: 165, B: 0, A: 255},
+		Entropy: true,
+	})
+
+	mustRegister(Codec{
+		Type:         CM,
+		Aliases:      []string{"cm"},
+		Magic:        [][]byte{{0xd2, 0xcb}},
+		Levels:       []int{8, 16, cm.DefaultOrder},
+		DefaultLevel: cm.DefaultOrder,
+		NewReader: func(r io.Reader, _ Params) (io.Reader, error) {
+			return cm.NewReader(r)
+		},
+		NewWriter: func(w io.Writer, p Params) (io.WriteCloser, error) {
+			return cm.NewWriter(w, p.Level)
+		},
+		Color: color.RGBA{R: 128, G: 0, B: 128, A: 255},
+	})
+
+	mustRegister(Codec{
+		Type:         Zlib,
+		Aliases:      []string{"zlib", "zz"},
+		Magic:        [][]byte{{0x78, 0x01}, {0x78, 0x5e}, {0x78, 0x9c}, {0x78, 0xda}},
+		Levels:       []int{flate.BestSpeed, 6, flate.BestCompression},
+		DefaultLevel: flate.BestCompression,
+		Dict:         true,
+		NewReader: func(r io.Reader, p Params) (io.Reader, error) {
+			return zlib.NewReaderDict(r, p.Dict)
+		},
+		NewWriter: func(w io.Writer, p Params) (io.WriteCloser, error) {
+			return zlib.NewWriterLevelDict(w, p.Level, p.Dict)
+		},
+		Color: color.RGBA{R: 70, G: 130, B: 180, A: 255},
+	})
+
+	// xz and lzma levels pick the dictionary size of the xz presets
+	mustRegister(Codec{
+		Type:         Xz,
+		Aliases:      []string{"xz"},
+		Magic:        [][]byte{{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+		Levels:       []int{0, 6, 9},
+		DefaultLevel: 6,
+		Window:       [2]int{12, 30},
+		NewReader: func(r io.Reader, _ Params) (io.Reader, error) {
+			return xz.NewReader(r)
+		},
+		NewWriter: func(w io.Writer, p Params) (io.WriteCloser, error) {
+			dictCap, err := xzDictCap(Xz, p)
+			if err != nil {
+				return nil, err
+			}
+			return xz.WriterConfig{DictCap: dictCap}.NewWriter(w)
+		},
+		Color: color.RGBA{R: 0, G: 100, B: 0, A: 255},
+	})
+
+	mustRegister(Codec{
+		Type:         Lzma,
+		Aliases:      []string{"lzma"},
+		Magic:        [][]byte{{0x5d, 0x00, 0x00}},
+		Levels:       []int{0, 6, 9},
+		DefaultLevel: 6,
+		Window:       [2]int{12, 30},
+		NewReader: func(r io.Reader, _ Params) (io.Reader, error) {
+			return lzma.NewReader(r)
+		},
+		NewWriter: func(w io.Writer, p Params) (io.WriteCloser, error) {
+			dictCap, err := xzDictCap(Lzma, p)
+			if err != nil {
+				return nil, err
+			}
+			return lzma.WriterConfig{DictCap: dictCap}.NewWriter(w)
+		},
+		Color: color.RGBA{R: 85, G: 107, B: 47, A: 255},
+	})
+
+	mustRegister(Codec{
+		Type:         Lz4,
+		Aliases:      []string{"lz4"},
+		Magic:        [][]byte{{0x04, 0x22, 0x4d, 0x18}},
+		Levels:       []int{0, 5, 9},
+		DefaultLevel: 9,
+		NewReader: func(r io.Reader, _ Params) (io.Reader, error) {
+			return lz4.NewReader(r), nil
+		},
+		NewWriter: func(w io.Writer, p Params) (io.WriteCloser, error) {
+			if err := CheckLevel(Lz4, p.Level, 0, 9); err != nil {
+				return nil, err
+			}
+			lw := lz4.NewWriter(w)
+			if err := lw.Apply(lz4.CompressionLevelOption(lz4Level(p.Level))); err != nil {
+				return nil, err
+			}
+			return lw, nil
+		},
+		Color: color.RGBA{R: 210, G: 105, B: 30, A: 255},
+	})
+
+	mustRegister(Codec{
+		Type:         Lz4Block,
+		Aliases:      []string{"lz4block", "lz4b"},
+		Levels:       []int{0, 5, 9},
+		DefaultLevel: 9,
+		NewReader: func(r io.Reader, _ Params) (io.Reader, error) {
+			return newBlockReader(r, lz4Decode), nil
+		},
+		NewWriter: func(w io.Writer, p Params) (io.WriteCloser, error) {
+			if err := CheckLevel(Lz4Block, p.Level, 0, 9); err != nil {
+				return nil, err
+			}
+			return newBlockWriter(w, lz4Encoder(p.Level)), nil
+		},
+		Color: color.RGBA{R: 139, G: 69, B: 19, A: 255},
+	})
+
+	// snappy levels are default, better and best like s2
+	mustRegister(Codec{
+		Type:         Snappy,
+		Aliases:      []string{"snappy", "sz"},
+		Magic:        [][]byte{append([]byte{0xff, 0x06, 0x00, 0x00}, "sNaPpY"...)},
+		Levels:       []int{1, 2, 3},
+		DefaultLevel: 3,
+		NewReader: func(r io.Reader, _ Params) (io.Reader, error) {
+			return s2.NewReader(r), nil
+		},
+		NewWriter: func(w io.Writer, p Params) (io.WriteCloser, error) {
+			if err := CheckLevel(Snappy, p.Level, 1, 3); err != nil {
+				return nil, err
+			}
+			return s2.NewWriter(w, append(s2Level(p.Level), s2.WriterSnappyCompat())...), nil
+		},
+		Color: color.RGBA{R: 218, G: 165, B: 32, A: 255},
+	})
+
+	mustRegister(Codec{
+		Type:         SnappyRaw,
+		Aliases:      []string{"snappyraw", "rawsnappy"},
+		Levels:       []int{1, 2, 3},
+		DefaultLevel: 3,
+		NewReader: func(r io.Reader, _ Params) (io.Reader, error) {
+			return newBlockReader(r, snappyDecode), nil
+		},
+		NewWriter: func(w io.Writer, p Params) (io.WriteCloser, error) {
+			if err := CheckLevel(SnappyRaw, p.Level, 1, 3); err != nil {
+				return nil, err
+			}
+			return newBlockWriter(w, snappyEncoder(p.Level)), nil
+		},
+		Color: color.RGBA{R: 184, G: 134, B: 11, A: 255},
+	})
+
+	// lzw levels are the max code width
+	mustRegister(Codec{
+		Type:         Lzw,
+		Aliases:      []string{"lzw", "Z", "compress"},
+		Magic:        [][]byte{{0x1f, 0x9d}},
+		Levels:       []int{lzw.MinBits, 12, lzw.MaxBits},
+		DefaultLevel: lzw.DefaultBits,
+		NewReader: func(r io.Reader, _ Params) (io.Reader, error) {
+			return lzw.NewReader(r)
+		},
+		NewWriter: func(w io.Writer, p Params) (io.WriteCloser, error) {
+			return lzw.NewWriter(w, p.Level)
+		},
+		Color: color.RGBA{R: 105, G: 105, B: 105, A: 255},
+	})
+}
+
+// s2Level are the writer options of the s2 levels default, better and best
+func s2Level(level int) []s2.WriterOption {
+	switch level {
+	case 1:
+		return nil
+	case 2:
+		return []s2.WriterOption{s2.WriterBetterCompression()}
+	default:
+		return []s2.WriterOption{s2.WriterBestCompression()}
+	}
+}
+
+// xzDictSizes are the dictionary sizes of the xz presets 0 to 9
+var xzDictSizes = []int{1 << 18, 1 << 20, 1 << 21, 1 << 22, 1 << 22, 1 << 23, 1 << 23, 1 << 24, 1 << 25, 1 << 26}
+
+// xzDictCap is the dictionary size of level, overridden by the window and capped to the size hint
+func xzDictCap(cType CompressionType, p Params) (int, error) {
+	if err := CheckLevel(cType, p.Level, 0, len(xzDictSizes)-1); err != nil {
+		return 0, err
+	}
+
+	dictCap := xzDictSizes[p.Level]
+	if p.Window > 0 {
+		dictCap = 1 << p.Window
+	}
+	if p.SizeHint > 0 && p.SizeHint < dictCap {
+		dictCap = max(p.SizeHint, lzma.MinDictCap)
+	}
+
+	return dictCap, nil
+}
@@ -1,17 +1,12 @@
 package compression
 
 import (
-	"compress/gzip"
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 
-	"github.com/andybalholm/brotli"
-	"github.com/dsnet/compress/bzip2"
-	"github.com/klauspost/compress/flate"
-	"github.com/klauspost/compress/s2"
-	"github.com/klauspost/compress/zstd"
 	"github.com/rs/zerolog"
 )
 
@@ -20,7 +15,10 @@ var ErrAlgorithmNotImplemented = errors.New("algorithm not implemented")
 type CompressionType string
 
 const (
-	None   = CompressionType("None")
+	None = CompressionType("None")
+	// Auto detects the codec of a compressed reader from the magic bytes of the data
+	Auto = CompressionType("Auto")
+
 	Zip    = CompressionType("Zip")
 	Gzip   = CompressionType("Gzip")
 	Brotli = CompressionType("Brotli")
@@ -28,38 +26,48 @@ const (
 	S2     = CompressionType("S2")
 	Huff   = CompressionType("Huff")
 	Bzip2  = CompressionType("Bzip2")
+	CM     = CompressionType("CM")
+
+	Zlib      = CompressionType("Zlib")
+	Xz        = CompressionType("Xz")
+	Lzma      = CompressionType("Lzma")
+	Lz4       = CompressionType("Lz4")
+	Lz4Block  = CompressionType("Lz4Block")
+	Snappy    = CompressionType("Snappy")
+	SnappyRaw = CompressionType("SnappyRaw")
+	Lzw       = CompressionType("Lzw")
 )
 
-func NewCompressedReader(ctx context.Context, r io.Reader, cType CompressionType) (io.Reader, error) {
+// NewCompressedReader returns a reader that decompresses r with cType, only the dictionary of opts is used
+func NewCompressedReader(ctx context.Context, r io.Reader, cType CompressionType, opts ...Opt) (io.Reader, error) {
 	log := zerolog.Ctx(ctx)
-	switch cType {
-	case None:
+
+	if cType == Auto {
+		br := bufio.NewReader(r)
+		// a short read means the data is shorter than the magic
+		magic, _ := br.Peek(maxMagicLen)
+		cType, r = Detect(magic), br
+		log.Trace().Str("compression", string(cType)).Msg("detected compression")
+	}
+
+	if cType == None {
 		log.Trace().Msg("no compression")
 		return r, nil
-	case Zip:
-		log.Error().Msg("zip compression")
-		return flate.NewReader(r), nil
-	case Gzip:
-		log.Trace().Msg("gzip compression")
-		return gzip.NewReader(r)
-	case Brotli:
-		log.Trace().Msg("brotli compression")
-		return brotli.NewReader(r), nil
-	case Zstd:
-		log.Trace().Msg("zstd compression")
-		return zstd.NewReader(r)
-	case S2:
-		log.Trace().Msg("s2 compression")
-		return s2.NewReader(r), nil
-	case Huff:
-		log.Trace().Msg("huffman compression")
-		return nil, fmt.Errorf("huffman not implemented: %w", ErrAlgorithmNotImplemented)
-	case Bzip2:
-		log.Trace().Msg("bzip2 compression")
-		return bzip2.NewReader(r, nil)
-	default:
-		return nil, fmt.Errorf("compression type  %T not supported", cType)
 	}
+
+	c, err := implemented(cType)
+	if err != nil {
+		return nil, err
+	}
+
+	p := newParams(c, opts...)
+	if err := c.validateDict(p); err != nil {
+		return nil, err
+	}
+
+	log.Trace().Str("compression", string(cType)).Msg("creating compressed reader")
+
+	return c.NewReader(r, p)
 }
 
 type nopWriterCloser struct {
@@ -78,34 +86,47 @@ func (w nopWriterCloser) Close() error {
 	return nil
 }
 
-func NewCompressedWriter(ctx context.Context, w io.Writer, cType CompressionType) (io.WriteCloser, error) {
+// NewCompressedWriter returns a writer that compresses w with cType, tuned by opts
+func NewCompressedWriter(ctx context.Context, w io.Writer, cType CompressionType, opts ...Opt) (io.WriteCloser, error) {
 	log := zerolog.Ctx(ctx)
-	switch cType {
-	case None:
+
+	if cType == None {
 		log.Trace().Msg("no compression")
 		return NewNopWriterCloser(w), nil
-	case Zip:
-		log.Error().Msg("zip compression")
-		return flate.NewWriter(w, flate.BestCompression)
-	case Gzip:
-		log.Trace().Msg("gzip compression")
-		return gzip.NewWriterLevel(w, flate.BestCompression)
-	case Brotli:
-		log.Trace().Msg("brotli compression")
-		return brotli.NewWriterLevel(w, brotli.BestCompression), nil
-	case Zstd:
-		log.Trace().Msg("zstd compression")
-		return zstd.NewWriter(w)
-	case S2:
-		log.Trace().Msg("s2 compression")
-		return s2.NewWriter(w, s2.WriterBestCompression()), nil
-	case Huff:
-		log.Trace().Msg("huffman compression")
-		return nil, fmt.Errorf("huffman not implemented: %w", ErrAlgorithmNotImplemented)
-	case Bzip2:
-		log.Trace().Msg("bzip2 compression")
-		return bzip2.NewWriter(w, &bzip2.WriterConfig{Level: bzip2.BestCompression})
-	default:
-		return nil, fmt.Errorf("compression type  %T not supported", cType)
 	}
+
+	c, err := implemented(cType)
+	if err != nil {
+		return nil, err
+	}
+
+	p := newParams(c, opts...)
+	if err := c.validateWindow(p); err != nil {
+		return nil, err
+	}
+	if err := c.validateDict(p); err != nil {
+		return nil, err
+	}
+
+	log.Trace().
+		Str("compression", string(cType)).
+		Int("level", p.Level).
+		Int("window", p.Window).
+		Int("dict", len(p.Dict)).
+		Msg("creating compressed writer")
+
+	return c.NewWriter(w, p)
+}
+
+// implemented returns the codec of cType if it has a reader and a writer
+func implemented(cType CompressionType) (*Codec, error) {
+	c, ok := Lookup(cType)
+	if !ok {
+		return nil, fmt.Errorf("compression type `%s` not supported: %w", cType, ErrUnknownCodec)
+	}
+	if c.NewReader == nil || c.NewWriter == nil {
+		return nil, fmt.Errorf("%s: %w", cType, ErrAlgorithmNotImplemented)
+	}
+
+	return c, nil
 }
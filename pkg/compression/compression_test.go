@@ -0,0 +1,201 @@
+package compression
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compress(t *testing.T, data []byte, cType CompressionType, opts ...Opt) []byte {
+	buf := new(bytes.Buffer)
+	w, err := NewCompressedWriter(context.Background(), buf, cType, opts...)
+	require.NoError(t, err)
+
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func TestCodecParams(t *testing.T) {
+	data := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog ", 100))
+	dict := []byte("the quick brown fox jumps over the lazy dog")
+
+	testCases := []struct {
+		name  string
+		cType CompressionType
+		opts  []Opt
+	}{
+		{
+			name:  "zip level",
+			cType: Zip,
+			opts:  []Opt{WithLevel(1)},
+		}, {
+			name:  "zip dictionary",
+			cType: Zip,
+			opts:  []Opt{WithDictionary(dict)},
+		}, {
+			name:  "gzip level",
+			cType: Gzip,
+			opts:  []Opt{WithLevel(6)},
+		}, {
+			name:  "brotli quality and lgwin",
+			cType: Brotli,
+			opts:  []Opt{WithLevel(5), WithWindow(16)},
+		}, {
+			name:  "zstd level and window",
+			cType: Zstd,
+			opts:  []Opt{WithLevel(1), WithWindow(15)},
+		}, {
+			name:  "s2 level",
+			cType: S2,
+			opts:  []Opt{WithLevel(1)},
+		}, {
+			name:  "bzip2 level",
+			cType: Bzip2,
+			opts:  []Opt{WithLevel(1)},
+		}, {
+			name:  "cm order",
+			cType: CM,
+			opts:  []Opt{WithLevel(8)},
+		}, {
+			name:  "zlib dictionary",
+			cType: Zlib,
+			opts:  []Opt{WithLevel(6), WithDictionary(dict)},
+		}, {
+			name:  "xz level",
+			cType: Xz,
+			opts:  []Opt{WithLevel(0)},
+		}, {
+			name:  "lzma window",
+			cType: Lzma,
+			opts:  []Opt{WithWindow(16)},
+		}, {
+			name:  "lz4 fast",
+			cType: Lz4,
+			opts:  []Opt{WithLevel(0)},
+		}, {
+			name:  "lz4 block high compression",
+			cType: Lz4Block,
+			opts:  []Opt{WithLevel(9)},
+		}, {
+			name:  "snappy",
+			cType: Snappy,
+			opts:  []Opt{WithLevel(1)},
+		}, {
+			name:  "snappy block",
+			cType: SnappyRaw,
+			opts:  []Opt{WithLevel(2)},
+		}, {
+			name:  "lzw code width",
+			cType: Lzw,
+			opts:  []Opt{WithLevel(12)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			r := require.New(t)
+
+			compressed := compress(t, data, tc.cType, tc.opts...)
+			a.Less(len(compressed), len(data))
+
+			cr, err := NewCompressedReader(context.Background(), bytes.NewReader(compressed), tc.cType, tc.opts...)
+			r.NoError(err)
+
+			decompressed, err := io.ReadAll(cr)
+			r.NoError(err)
+			a.Equal(data, decompressed)
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	text := []byte(strings.Repeat("0123456789abcdef", 1000))
+
+	for _, cType := range Types() {
+		t.Run(string(cType), func(t *testing.T) {
+			r := require.New(t)
+
+			for _, data := range [][]byte{{}, text} {
+				compressed := compress(t, data, cType)
+
+				cr, err := NewCompressedReader(context.Background(), bytes.NewReader(compressed), cType)
+				r.NoError(err)
+
+				decompressed, err := io.ReadAll(cr)
+				r.NoError(err)
+				r.True(bytes.Equal(data, decompressed))
+			}
+		})
+	}
+}
+
+func TestCodecParamsChangeOutput(t *testing.T) {
+	data := []byte(strings.Repeat("0123456789abcdef", 1000))
+
+	best := compress(t, data, Brotli)
+	fast := compress(t, data, Brotli, WithLevel(0))
+
+	assert.NotEqual(t, best, fast)
+}
+
+func TestInvalidCodecParams(t *testing.T) {
+	testCases := []struct {
+		name  string
+		cType CompressionType
+		opts  []Opt
+	}{
+		{
+			name:  "gzip window",
+			cType: Gzip,
+			opts:  []Opt{WithWindow(15)},
+		}, {
+			name:  "brotli window too small",
+			cType: Brotli,
+			opts:  []Opt{WithWindow(9)},
+		}, {
+			name:  "brotli quality too high",
+			cType: Brotli,
+			opts:  []Opt{WithLevel(12)},
+		}, {
+			name:  "zstd window too large",
+			cType: Zstd,
+			opts:  []Opt{WithWindow(30)},
+		}, {
+			name:  "gzip dictionary",
+			cType: Gzip,
+			opts:  []Opt{WithDictionary([]byte("dict"))},
+		}, {
+			name:  "s2 level",
+			cType: S2,
+			opts:  []Opt{WithLevel(4)},
+		}, {
+			name:  "xz level",
+			cType: Xz,
+			opts:  []Opt{WithLevel(10)},
+		}, {
+			name:  "lz4 level",
+			cType: Lz4Block,
+			opts:  []Opt{WithLevel(10)},
+		}, {
+			name:  "snappy window",
+			cType: Snappy,
+			opts:  []Opt{WithWindow(16)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewCompressedWriter(context.Background(), io.Discard, tc.cType, tc.opts...)
+			assert.ErrorIs(t, err, ErrInvalidParam)
+		})
+	}
+}
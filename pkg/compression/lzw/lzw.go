@@ -0,0 +1,342 @@
+// Package lzw implements the LZW format of the Unix compress utility (.Z files)
+//
+// the stream layout is
+//
+//	magic (2 bytes) | block mode flag and max code width (1 byte) | codes
+//
+// codes are packed LSB first, their width grows from 9 bits to the max width,
+// and every width change or table clear pads the codes to a group of 8
+package lzw
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	MinBits     = 9
+	MaxBits     = 16
+	DefaultBits = MaxBits
+
+	blockModeFlag = 0x80
+	bitsMask      = 0x1f
+
+	clearCode = 256
+	firstCode = 257
+)
+
+var (
+	ErrInvalidHeader = errors.New("invalid lzw header")
+	ErrInvalidBits   = errors.New("invalid lzw code width")
+	ErrCorrupt       = errors.New("corrupt lzw data")
+)
+
+var magic = [2]byte{0x1f, 0x9d}
+
+type writer struct {
+	w       io.Writer
+	maxBits int
+	buf     bytes.Buffer
+}
+
+// NewWriter returns a WriteCloser that compresses data with codes up to maxBits wide, in block mode
+//
+// data is buffered and coded when the writer is closed
+func NewWriter(w io.Writer, maxBits int) (io.WriteCloser, error) {
+	if maxBits < MinBits || maxBits > MaxBits {
+		return nil, fmt.Errorf("code width %d not in [%d, %d]: %w", maxBits, MinBits, MaxBits, ErrInvalidBits)
+	}
+
+	return &writer{w: w, maxBits: maxBits}, nil
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
+
+func (w *writer) Close() error {
+	header := []byte{magic[0], magic[1], byte(w.maxBits) | blockModeFlag}
+	if _, err := w.w.Write(header); err != nil {
+		return err
+	}
+
+	data := w.buf.Bytes()
+	if len(data) == 0 {
+		return nil
+	}
+
+	bw := bufio.NewWriter(w.w)
+	enc := &encoder{w: bw, maxMaxCode: 1 << w.maxBits, maxBits: w.maxBits}
+	enc.reset()
+
+	table := make(map[int]int)
+	ent := int(data[0])
+	for _, c := range data[1:] {
+		key := ent<<8 | int(c)
+		if code, ok := table[key]; ok {
+			ent = code
+			continue
+		}
+
+		enc.output(ent)
+		ent = int(c)
+
+		if enc.freeEnt < enc.maxMaxCode {
+			table[key] = enc.freeEnt
+			enc.freeEnt++
+			continue
+		}
+
+		// the table is full, start over
+		table = make(map[int]int)
+		enc.clear()
+	}
+	enc.output(ent)
+	enc.flush()
+
+	if enc.err != nil {
+		return enc.err
+	}
+
+	return bw.Flush()
+}
+
+// encoder packs codes LSB first, following the code width of the table size
+type encoder struct {
+	w   io.ByteWriter
+	err error
+
+	acc   uint32
+	nAcc  int
+	group int
+
+	nBits      int
+	maxBits    int
+	maxCode    int
+	maxMaxCode int
+	freeEnt    int
+}
+
+func (e *encoder) reset() {
+	e.nBits = MinBits
+	e.maxCode = 1<<MinBits - 1
+	e.freeEnt = firstCode
+}
+
+// output writes code and widens the codes once the table outgrows them
+func (e *encoder) output(code int) {
+	e.writeCode(code)
+
+	if e.freeEnt > e.maxCode {
+		e.padGroup()
+		e.nBits++
+		e.maxCode = 1<<e.nBits - 1
+		if e.nBits == e.maxBits {
+			e.maxCode = e.maxMaxCode
+		}
+	}
+}
+
+// clear writes the clear code and goes back to the narrowest codes
+func (e *encoder) clear() {
+	e.writeCode(clearCode)
+	e.padGroup()
+	e.reset()
+}
+
+func (e *encoder) writeCode(code int) {
+	e.acc |= uint32(code) << e.nAcc
+	e.nAcc += e.nBits
+	for e.nAcc >= 8 {
+		e.writeByte(byte(e.acc))
+		e.acc >>= 8
+		e.nAcc -= 8
+	}
+	e.group = (e.group + 1) % 8
+}
+
+// padGroup fills the current group of 8 codes with zeros
+func (e *encoder) padGroup() {
+	for e.group != 0 {
+		e.writeCode(0)
+	}
+}
+
+func (e *encoder) flush() {
+	if e.nAcc > 0 {
+		e.writeByte(byte(e.acc))
+	}
+	e.acc, e.nAcc = 0, 0
+}
+
+func (e *encoder) writeByte(b byte) {
+	if e.err == nil {
+		e.err = e.w.WriteByte(b)
+	}
+}
+
+type reader struct {
+	r       *bufio.Reader
+	decoded *bytes.Reader
+}
+
+// NewReader returns a Reader that decompresses data written by compress
+func NewReader(r io.Reader) (io.Reader, error) {
+	return &reader{r: bufio.NewReader(r)}, nil
+}
+
+func (r *reader) Read(b []byte) (int, error) {
+	if r.decoded == nil {
+		data, err := r.decode()
+		if err != nil {
+			return 0, err
+		}
+		r.decoded = bytes.NewReader(data)
+	}
+
+	return r.decoded.Read(b)
+}
+
+func (r *reader) decode() ([]byte, error) {
+	header := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		return nil, fmt.Errorf("cannot read header: %w", ErrInvalidHeader)
+	}
+	if !bytes.Equal(header[:len(magic)], magic[:]) {
+		return nil, fmt.Errorf("bad magic %x: %w", header[:len(magic)], ErrInvalidHeader)
+	}
+
+	flags := header[len(magic)]
+	maxBits := int(flags & bitsMask)
+	if maxBits < MinBits || maxBits > MaxBits {
+		return nil, fmt.Errorf("code width %d not in [%d, %d]: %w", maxBits, MinBits, MaxBits, ErrInvalidBits)
+	}
+	blockMode := flags&blockModeFlag != 0
+
+	d := &decoder{r: r.r}
+	maxMaxCode := 1 << maxBits
+	prefix := make([]int, maxMaxCode)
+	suffix := make([]byte, maxMaxCode)
+	for i := 0; i < 256; i++ {
+		suffix[i] = byte(i)
+	}
+
+	nBits, maxCode := MinBits, 1<<MinBits-1
+	freeEnt := clearCode
+	if blockMode {
+		freeEnt = firstCode
+	}
+
+	var data, stack []byte
+	oldCode, finChar := -1, byte(0)
+	for {
+		if freeEnt > maxCode {
+			d.skipGroup(nBits)
+			nBits++
+			maxCode = 1<<nBits - 1
+			if nBits == maxBits {
+				maxCode = maxMaxCode
+			}
+		}
+
+		code, ok := d.readCode(nBits)
+		if !ok {
+			break
+		}
+
+		if oldCode == -1 {
+			if code >= 256 {
+				return nil, fmt.Errorf("first code %d is not a literal: %w", code, ErrCorrupt)
+			}
+			oldCode, finChar = code, byte(code)
+			data = append(data, finChar)
+			continue
+		}
+
+		if code == clearCode && blockMode {
+			d.skipGroup(nBits)
+			nBits, maxCode = MinBits, 1<<MinBits-1
+			// the next code adds a placeholder entry, like the first code of the stream
+			freeEnt = firstCode - 1
+			continue
+		}
+
+		inCode := code
+		stack = stack[:0]
+		if code >= freeEnt {
+			if code > freeEnt {
+				return nil, fmt.Errorf("code %d beyond table size %d: %w", code, freeEnt, ErrCorrupt)
+			}
+			stack = append(stack, finChar)
+			code = oldCode
+		}
+		for code >= 256 {
+			stack = append(stack, suffix[code])
+			code = prefix[code]
+		}
+		finChar = suffix[code]
+		stack = append(stack, finChar)
+
+		for i := len(stack) - 1; i >= 0; i-- {
+			data = append(data, stack[i])
+		}
+
+		if freeEnt < maxMaxCode {
+			prefix[freeEnt] = oldCode
+			suffix[freeEnt] = finChar
+			freeEnt++
+		}
+		oldCode = inCode
+	}
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return data, nil
+}
+
+// decoder unpacks codes LSB first, keeping track of their position in a group of 8
+type decoder struct {
+	r   io.ByteReader
+	err error
+	eof bool
+
+	acc   uint32
+	nAcc  int
+	group int
+}
+
+// readCode returns the next code of nBits, false at the end of the stream
+func (d *decoder) readCode(nBits int) (int, bool) {
+	for d.nAcc < nBits {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				d.err = err
+			}
+			d.eof = true
+			return 0, false
+		}
+		d.acc |= uint32(b) << d.nAcc
+		d.nAcc += 8
+	}
+
+	code := int(d.acc & (1<<nBits - 1))
+	d.acc >>= nBits
+	d.nAcc -= nBits
+	d.group = (d.group + 1) % 8
+
+	return code, true
+}
+
+// skipGroup drops the padding codes up to the end of the current group of 8
+func (d *decoder) skipGroup(nBits int) {
+	for d.group != 0 && !d.eof {
+		d.readCode(nBits)
+	}
+	d.group = 0
+}
@@ -0,0 +1,107 @@
+package lzw
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compress(t *testing.T, data []byte, maxBits int) []byte {
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, maxBits)
+	require.NoError(t, err)
+
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func decompress(t *testing.T, compressed []byte) []byte {
+	r, err := NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	return data
+}
+
+func TestRoundTrip(t *testing.T) {
+	random := make([]byte, 1<<17)
+	rand.New(rand.NewSource(0)).Read(random)
+
+	testCases := []struct {
+		name    string
+		data    []byte
+		maxBits int
+	}{
+		{
+			name:    "empty",
+			data:    []byte{},
+			maxBits: DefaultBits,
+		}, {
+			name:    "one byte",
+			data:    []byte("a"),
+			maxBits: DefaultBits,
+		}, {
+			name:    "text",
+			data:    []byte(strings.Repeat("a longer text so that compression actually does something nice ", 20)),
+			maxBits: DefaultBits,
+		}, {
+			name:    "random fills the table",
+			data:    random,
+			maxBits: DefaultBits,
+		}, {
+			name:    "narrow codes clear the table",
+			data:    random,
+			maxBits: 10,
+		}, {
+			name:    "min bits",
+			data:    bytes.Repeat([]byte{0xde, 0xad, 0xbe, 0xef}, 3000),
+			maxBits: MinBits,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			assert.Equal(tt, tc.data, decompress(tt, compress(tt, tc.data, tc.maxBits)))
+		})
+	}
+}
+
+func TestCompressFormat(t *testing.T) {
+	a := assert.New(t)
+
+	// decompressed by gzip -d as well
+	golden := []byte{0x1f, 0x9d, 0x90, 0x54, 0x9e, 0x8, 0x29, 0xf2, 0x44, 0x8a, 0x93, 0x27, 0x54, 0x2, 0xe, 0x2c, 0xa8, 0x90, 0xa0, 0x41, 0x84}
+	data := []byte("TOBEORNOTTOBEORTOBEORNOT")
+
+	a.Equal(golden, compress(t, data, DefaultBits))
+	a.Equal(data, decompress(t, golden))
+}
+
+func TestInvalidStream(t *testing.T) {
+	r := require.New(t)
+
+	_, err := NewWriter(new(bytes.Buffer), MaxBits+1)
+	r.ErrorIs(err, ErrInvalidBits)
+
+	lr, err := NewReader(bytes.NewReader([]byte("not a compress stream")))
+	r.NoError(err)
+
+	_, err = io.ReadAll(lr)
+	r.ErrorIs(err, ErrInvalidHeader)
+
+	lr, err = NewReader(bytes.NewReader([]byte{0x1f, 0x9d, 0x90, 0xff, 0xff}))
+	r.NoError(err)
+
+	_, err = io.ReadAll(lr)
+	r.ErrorIs(err, ErrCorrupt)
+}
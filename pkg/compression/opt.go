@@ -0,0 +1,98 @@
+package compression
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidParam = errors.New("invalid codec parameter")
+
+// Params are the parameters a codec is created with, the zero values keep the codec defaults
+type Params struct {
+	Level    int
+	Window   int
+	Dict     []byte
+	SizeHint int
+}
+
+// Opt tunes the codec of a compressed reader or writer, unset parameters keep the codec defaults
+type Opt func(*Params)
+
+// WithLevel sets the compression level, see Codec.Levels for the levels of each algorithm
+//
+// the brotli level is its quality (0-11), the cm level is the context order
+func WithLevel(level int) Opt {
+	return func(p *Params) {
+		p.Level = level
+	}
+}
+
+// WithWindow sets the base 2 logarithm of the window size
+//
+// only brotli (lgwin, 10-24), zstd (10-29), xz and lzma (12-30) support it,
+// the zip, zlib and gzip window is fixed at 32KB
+func WithWindow(log2 int) Opt {
+	return func(p *Params) {
+		p.Window = log2
+	}
+}
+
+// WithDictionary primes the codec with dict, the same dictionary must be used to decompress
+//
+// zip and zlib accept any bytes, zstd requires a dictionary trained with `zstd --train`
+func WithDictionary(dict []byte) Opt {
+	return func(p *Params) {
+		p.Dict = dict
+	}
+}
+
+// WithSizeHint tells the codec that the data is at most n bytes, so that it does not allocate
+// a dictionary larger than the data, the compressed data does not change
+func WithSizeHint(n int) Opt {
+	return func(p *Params) {
+		p.SizeHint = n
+	}
+}
+
+func newParams(c *Codec, opts ...Opt) Params {
+	p := Params{
+		Level: c.DefaultLevel,
+	}
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	return p
+}
+
+// CheckLevel validates the level of codecs that do not validate it
+func CheckLevel(cType CompressionType, level, min, max int) error {
+	if level < min || level > max {
+		return fmt.Errorf("%s level %d not in [%d, %d]: %w", cType, level, min, max, ErrInvalidParam)
+	}
+
+	return nil
+}
+
+func (c *Codec) validateWindow(p Params) error {
+	if p.Window == 0 {
+		return nil
+	}
+
+	if c.Window == [2]int{} {
+		return fmt.Errorf("%s does not support a window size: %w", c.Type, ErrInvalidParam)
+	}
+	if p.Window < c.Window[0] || p.Window > c.Window[1] {
+		return fmt.Errorf("%s window %d not in [%d, %d]: %w", c.Type, p.Window, c.Window[0], c.Window[1], ErrInvalidParam)
+	}
+
+	return nil
+}
+
+func (c *Codec) validateDict(p Params) error {
+	if len(p.Dict) == 0 || c.Dict {
+		return nil
+	}
+
+	return fmt.Errorf("%s does not support a dictionary: %w", c.Type, ErrInvalidParam)
+}
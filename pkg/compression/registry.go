@@ -0,0 +1,166 @@
+package compression
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"sync"
+)
+
+var (
+	ErrUnknownCodec = errors.New("unknown codec")
+	ErrCodecExists  = errors.New("codec already registered")
+)
+
+// Codec describes a compression format, see Register
+type Codec struct {
+	// Type is the name of the codec, it names its entropy series too
+	Type CompressionType
+	// Aliases are the names the codec is selected by in flags
+	Aliases []string
+	// Magic are the prefixes that identify a stream of the codec, used by Detect
+	Magic [][]byte
+
+	// Levels go from the fastest to the best, DefaultLevel is used when no level is set
+	Levels       []int
+	DefaultLevel int
+	// Window is the range of the window size, as base 2 logarithm, the zero value means no window
+	Window [2]int
+	// Dict is set when the codec can be primed with a dictionary
+	Dict bool
+
+	// NewReader and NewWriter are nil for codecs that are not implemented
+	NewReader func(r io.Reader, p Params) (io.Reader, error)
+	NewWriter func(w io.Writer, p Params) (io.WriteCloser, error)
+
+	// Color is the colour of the entropy series, the zero value takes a fallback colour
+	Color color.RGBA
+	// Entropy adds the codec to the entropy series calculated by default
+	Entropy bool
+}
+
+var registry = struct {
+	sync.RWMutex
+	codecs  []*Codec
+	byName  map[CompressionType]*Codec
+	byAlias map[string]*Codec
+}{
+	byName:  map[CompressionType]*Codec{},
+	byAlias: map[string]*Codec{},
+}
+
+// Register adds a codec, its type and aliases must not be taken by another codec
+func Register(c Codec) error {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if c.Type == None || c.Type == Auto {
+		return fmt.Errorf("codec `%s` is reserved: %w", c.Type, ErrCodecExists)
+	}
+	if _, ok := registry.byName[c.Type]; ok {
+		return fmt.Errorf("codec `%s`: %w", c.Type, ErrCodecExists)
+	}
+	for _, alias := range c.Aliases {
+		if other, ok := registry.byAlias[alias]; ok {
+			return fmt.Errorf("alias `%s` of %s is taken by %s: %w", alias, c.Type, other.Type, ErrCodecExists)
+		}
+	}
+
+	codec := &c
+	registry.codecs = append(registry.codecs, codec)
+	registry.byName[c.Type] = codec
+	for _, alias := range c.Aliases {
+		registry.byAlias[alias] = codec
+	}
+
+	return nil
+}
+
+func mustRegister(c Codec) {
+	if err := Register(c); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the codec of cType
+func Lookup(cType CompressionType) (*Codec, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	c, ok := registry.byName[cType]
+	return c, ok
+}
+
+// Parse returns the codec type selected by name, one of its aliases
+//
+// an empty name or `none` select no compression, `auto` detects it from the magic bytes
+func Parse(name string) (CompressionType, error) {
+	switch name {
+	case "", "none":
+		return None, nil
+	case "auto":
+		return Auto, nil
+	}
+
+	registry.RLock()
+	defer registry.RUnlock()
+
+	c, ok := registry.byAlias[name]
+	if !ok {
+		return None, fmt.Errorf("codec `%s`: %w", name, ErrUnknownCodec)
+	}
+
+	return c.Type, nil
+}
+
+// Types returns the implemented codecs, in the order they were registered
+func Types() []CompressionType {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	types := make([]CompressionType, 0, len(registry.codecs))
+	for _, c := range registry.codecs {
+		if c.NewReader != nil && c.NewWriter != nil {
+			types = append(types, c.Type)
+		}
+	}
+
+	return types
+}
+
+// EntropyTypes returns the codecs of the entropy series calculated by default
+func EntropyTypes() []CompressionType {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	types := []CompressionType{}
+	for _, c := range registry.codecs {
+		if c.Entropy {
+			types = append(types, c.Type)
+		}
+	}
+
+	return types
+}
+
+// maxMagicLen is how many bytes are peeked to detect the codec
+const maxMagicLen = 16
+
+// Detect returns the codec whose longest magic prefixes data, None when no codec matches
+func Detect(data []byte) CompressionType {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	detected, longest := None, 0
+	for _, c := range registry.codecs {
+		for _, magic := range c.Magic {
+			if len(magic) > longest && bytes.HasPrefix(data, magic) {
+				detected, longest = c.Type, len(magic)
+			}
+		}
+	}
+
+	return detected
+}
@@ -0,0 +1,136 @@
+package compression
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rot13 is a codec registered by the tests, it shifts the bytes after its magic
+const rot13 = CompressionType("Rot13")
+
+type rot13Writer struct {
+	io.Writer
+}
+
+func (w rot13Writer) Write(b []byte) (int, error) {
+	return w.Writer.Write(rot(b))
+}
+
+func (w rot13Writer) Close() error {
+	return nil
+}
+
+func rot(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		out[i] = c + 13
+	}
+
+	return out
+}
+
+func init() {
+	mustRegister(Codec{
+		Type:    rot13,
+		Aliases: []string{"rot13"},
+		Magic:   [][]byte{[]byte("ROT")},
+		NewReader: func(r io.Reader, _ Params) (io.Reader, error) {
+			data, err := io.ReadAll(r)
+			data = bytes.TrimPrefix(data, []byte("ROT"))
+			for i := range data {
+				data[i] -= 13
+			}
+			return bytes.NewReader(data), err
+		},
+		NewWriter: func(w io.Writer, _ Params) (io.WriteCloser, error) {
+			_, err := w.Write([]byte("ROT"))
+			return rot13Writer{w}, err
+		},
+	})
+}
+
+func TestRegister(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+
+	a.Contains(Types(), rot13)
+	a.NotContains(Types(), Huff)
+
+	cType, err := Parse("rot13")
+	r.NoError(err)
+	a.Equal(rot13, cType)
+
+	compressed := compress(t, []byte("data"), rot13)
+	cr, err := NewCompressedReader(context.Background(), bytes.NewReader(compressed), rot13)
+	r.NoError(err)
+
+	data, err := io.ReadAll(cr)
+	r.NoError(err)
+	a.Equal([]byte("data"), data)
+
+	a.ErrorIs(Register(Codec{Type: rot13}), ErrCodecExists)
+	a.ErrorIs(Register(Codec{Type: "Other", Aliases: []string{"gz"}}), ErrCodecExists)
+	a.ErrorIs(Register(Codec{Type: None}), ErrCodecExists)
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected CompressionType
+		err      error
+	}{
+		{name: "", expected: None},
+		{name: "none", expected: None},
+		{name: "auto", expected: Auto},
+		{name: "gz", expected: Gzip},
+		{name: "bzip2", expected: Bzip2},
+		{name: "Z", expected: Lzw},
+		{name: "lz4b", expected: Lz4Block},
+		{name: "rar", expected: None, err: ErrUnknownCodec},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cType, err := Parse(tc.name)
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.expected, cType)
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	data := []byte("some data to detect")
+
+	for _, cType := range []CompressionType{Gzip, Zstd, S2, Bzip2, CM, Zlib, Xz, Lz4, Snappy, Lzw, rot13} {
+		t.Run(string(cType), func(t *testing.T) {
+			a := assert.New(t)
+			r := require.New(t)
+
+			compressed := compress(t, data, cType)
+			a.Equal(cType, Detect(compressed))
+
+			cr, err := NewCompressedReader(context.Background(), bytes.NewReader(compressed), Auto)
+			r.NoError(err)
+
+			decompressed, err := io.ReadAll(cr)
+			r.NoError(err)
+			a.Equal(data, decompressed)
+		})
+	}
+
+	a := assert.New(t)
+	a.Equal(None, Detect([]byte("plain text")))
+	a.Equal(None, Detect(nil))
+
+	cr, err := NewCompressedReader(context.Background(), bytes.NewReader([]byte("plain")), Auto)
+	require.NoError(t, err)
+
+	plain, err := io.ReadAll(cr)
+	require.NoError(t, err)
+	a.Equal([]byte("plain"), plain)
+}
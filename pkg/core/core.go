@@ -8,7 +8,10 @@ import (
 	"github.com/rs/zerolog"
 
 	"github.com/fedemengo/d2bist/pkg/compression"
+	"github.com/fedemengo/d2bist/pkg/extract"
 	iio "github.com/fedemengo/d2bist/pkg/io"
+	"github.com/fedemengo/d2bist/pkg/linecode"
+	"github.com/fedemengo/d2bist/pkg/segment"
 	"github.com/fedemengo/d2bist/pkg/stats"
 	"github.com/fedemengo/d2bist/pkg/types"
 )
@@ -23,7 +26,13 @@ func binStrReaderToBits(ctx context.Context, r io.Reader, opts ...Opt) ([]types.
 		opt(c)
 	}
 
-	bits, err := iio.BitsFromBinStrReaderWithCap(ctx, r, c.InMaxBits)
+	maxBits := c.InMaxBits
+	if len(c.InRanges) > 0 {
+		// the ranges select the decoded bits, the cap applies to them
+		maxBits = -1
+	}
+
+	bits, err := iio.BitsFromBinStrReaderWithCap(ctx, r, maxBits)
 	if err != nil {
 		return nil, err
 	}
@@ -42,12 +51,16 @@ func binStrReaderToBits(ctx context.Context, r io.Reader, opts ...Opt) ([]types.
 			return nil, err
 		}
 
-		bits, err = readerToBits(ctx, r, WithInCompression(c.InCompressionType))
+		bits, err = readerToBits(ctx, r, WithInCompression(c.InCompressionType), WithInCodecOpts(c.InCodecOpts...))
 		if err != nil {
 			return nil, fmt.Errorf("error decoding from compressed reader: %w", err)
 		}
 	}
 
+	if len(c.InRanges) > 0 {
+		bits = iio.SelectRanges(bits, capRanges(c.InRanges, c.InMaxBits))
+	}
+
 	if c.OutMaxBits > 0 {
 		bitsCap := min(c.OutMaxBits, len(bits))
 		bits = bits[:bitsCap]
@@ -62,12 +75,17 @@ func readerToBits(ctx context.Context, r io.Reader, opts ...Opt) ([]types.Bit, e
 		opt(c)
 	}
 
-	cr, err := compression.NewCompressedReader(ctx, r, c.InCompressionType)
+	cr, err := compression.NewCompressedReader(ctx, r, c.InCompressionType, c.InCodecOpts...)
 	if err != nil {
 		return nil, err
 	}
 
-	bits, err := iio.BitsFromByteReaderWithCap(ctx, cr, c.InMaxBits)
+	var bits []types.Bit
+	if len(c.InRanges) > 0 {
+		bits, err = iio.BitsFromByteReaderRanges(ctx, cr, capRanges(c.InRanges, c.InMaxBits))
+	} else {
+		bits, err = iio.BitsFromByteReaderWithCap(ctx, cr, c.InMaxBits)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("cannot read bits from reader: %w", err)
 	}
@@ -88,6 +106,53 @@ func createResult(ctx context.Context, bits []types.Bit, opts ...Opt) (*types.Re
 		opt(c)
 	}
 
+	var lineCodeStats *types.LineCodeStats
+	if c.LineDecode != linecode.None {
+		decoded, violations, err := linecode.Decode(ctx, bits, c.LineDecode)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode line code: %w", err)
+		}
+
+		lineCodeStats = &types.LineCodeStats{
+			Code:       string(c.LineDecode),
+			InputBits:  len(bits),
+			OutputBits: len(decoded),
+		}
+		for _, v := range violations {
+			lineCodeStats.ViolationOffsets = append(lineCodeStats.ViolationOffsets, v.Offset)
+		}
+
+		bits = decoded
+	}
+
+	var extractionStats *types.ExtractionStats
+	if c.Extractor != extract.None {
+		extracted, err := extractBits(ctx, bits, c)
+		if err != nil {
+			return nil, fmt.Errorf("cannot extract bits: %w", err)
+		}
+
+		extractionStats = &types.ExtractionStats{
+			Extractor:  string(c.Extractor),
+			InputBits:  len(bits),
+			OutputBits: len(extracted),
+		}
+		if len(bits) > 0 {
+			extractionStats.Yield = float64(len(extracted)*100) / float64(len(bits))
+		}
+
+		bits = extracted
+	}
+
+	if c.LineEncode != linecode.None {
+		encoded, err := linecode.Encode(ctx, bits, c.LineEncode)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode line code: %w", err)
+		}
+
+		bits = encoded
+	}
+
 	log.Debug().
 		Int("outBitsCap", c.OutMaxBits).
 		Str("outCompression", string(c.OutCompressionType)).
@@ -104,14 +169,42 @@ func createResult(ctx context.Context, bits []types.Bit, opts ...Opt) (*types.Re
 		statsOpts = append(statsOpts, stats.WithBlockSize(c.StatsBlockSize))
 	}
 
+	if c.StatsStride > 0 {
+		statsOpts = append(statsOpts, stats.WithStride(c.StatsStride))
+	}
+
+	if c.OutCompressionType != compression.None && len(c.OutCodecOpts) > 0 {
+		statsOpts = append(statsOpts, stats.WithCodecOpts(c.OutCompressionType, c.OutCodecOpts...))
+	}
+
+	if len(c.StatsEstimators) > 0 {
+		statsOpts = append(statsOpts, stats.WithEstimators(c.StatsEstimators...))
+	}
+
+	if c.StatsApproximate {
+		statsOpts = append(statsOpts, stats.WithApproximate(c.StatsSketchWidth, c.StatsSketchDepth))
+	}
+
 	log.Trace().
 		Int("statsBlockSize", c.StatsBlockSize).
 		Int("statsMaxBlockSize", c.StatsMaxBlockSize).
 		Int("statsTopK", c.StatsTopK).
+		Bool("statsApproximate", c.StatsApproximate).
 		Msg("analizing bits")
 
 	bitsStats := stats.AnalizeBits(ctx, bits, statsOpts...)
-	bitsStats.EntropyPlotName = c.EntropyPlotName
+	bitsStats.EntropyPlotPath = c.EntropyPlotPath
+	bitsStats.EntropyChartOpts = c.EntropyChartOpts
+	bitsStats.ExtractionStats = extractionStats
+	bitsStats.LineCodeStats = lineCodeStats
+
+	if len(c.SegmentEntropy) > 0 {
+		regions, err := segmentBits(bits, bitsStats.Entropy, c)
+		if err != nil {
+			return nil, fmt.Errorf("cannot segment bits: %w", err)
+		}
+		bitsStats.Regions = regions
+	}
 
 	result := &types.Result{
 		Bits:  bits,
@@ -124,7 +217,7 @@ func createResult(ctx context.Context, bits []types.Bit, opts ...Opt) (*types.Re
 
 	log.Trace().Msg("output requires compression")
 
-	cr, err := iio.BitsToReader(ctx, bits, c.OutCompressionType)
+	cr, err := iio.BitsToReader(ctx, bits, c.OutCompressionType, c.OutCodecOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("cannot write bytes to compressed reader: %w", err)
 	}
@@ -146,9 +239,52 @@ func createResult(ctx context.Context, bits []types.Bit, opts ...Opt) (*types.Re
 	return result, nil
 }
 
+// capRanges shortens the ranges so that they select at most maxBits bits
+func capRanges(ranges []types.Range, maxBits int) []types.Range {
+	if maxBits <= 0 {
+		return ranges
+	}
+
+	capped := []types.Range{}
+	for _, rg := range ranges {
+		if maxBits == 0 {
+			break
+		}
+		if rg.Length <= 0 || rg.Length > maxBits {
+			rg.Length = maxBits
+		}
+		capped = append(capped, rg)
+		maxBits -= rg.Length
+	}
+
+	return capped
+}
+
+func extractBits(ctx context.Context, bits []types.Bit, c *Config) ([]types.Bit, error) {
+	n, m := c.ExtractToeplitzIn, c.ExtractToeplitzOut
+	extractOpts := []extract.Opt{
+		extract.WithFoldSize(c.ExtractFoldSize),
+		extract.WithToeplitzSize(n, m),
+		extract.WithSeed(extract.SeedBits(c.ExtractToeplitzSeed, n+m-1)),
+	}
+
+	return extract.Extract(ctx, bits, c.Extractor, extractOpts...)
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
 	}
 	return b
 }
+
+// segmentBits splits bits where the entropy series selected by the config changes
+func segmentBits(bits []types.Bit, entropies []*types.Entropy, c *Config) ([]types.Region, error) {
+	for _, e := range entropies {
+		if e.Name == c.SegmentEntropy {
+			return segment.Segment(bits, e, c.SegmentOpts...)
+		}
+	}
+
+	return nil, fmt.Errorf("entropy `%s` was not calculated", c.SegmentEntropy)
+}
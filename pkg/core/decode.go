@@ -23,3 +23,8 @@ func Decode(ctx context.Context, r io.Reader, opts ...Opt) (*types.Result, error
 
 	return createResult(ctx, bits, opts...)
 }
+
+// ReadBits receives byte data in a io.Reader and returns its bits, without analysing them
+func ReadBits(ctx context.Context, r io.Reader, opts ...Opt) ([]types.Bit, error) {
+	return readerToBits(ctx, r, opts...)
+}
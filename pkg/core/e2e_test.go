@@ -14,6 +14,7 @@ import (
 
 	"github.com/fedemengo/d2bist/pkg/compression"
 	iio "github.com/fedemengo/d2bist/pkg/io"
+	"github.com/fedemengo/d2bist/pkg/linecode"
 	"github.com/fedemengo/d2bist/pkg/types"
 )
 
@@ -105,6 +106,22 @@ func TestE2E(t *testing.T) {
 				{WithInCompression(compression.Brotli)},
 			},
 			expectedData: []byte("dead beef"),
+		}, {
+			name: "decode and compress with cm/encode compressed",
+			data: []byte("dead beef dead beef dead beef"),
+			ops: []op{
+				Decode,
+				Encode,
+			},
+			converters: []converter{
+				resultToBinStr,
+				basicConverter,
+			},
+			opts: [][]Opt{
+				{WithOutCompression(compression.CM)},
+				{WithInCompression(compression.CM)},
+			},
+			expectedData: []byte("dead beef dead beef dead beef"),
 		}, {
 			name: "decode and cap/encode",
 			data: []byte("dead beef"),
@@ -121,6 +138,54 @@ func TestE2E(t *testing.T) {
 				{},
 			},
 			expectedData: []byte("dead"),
+		}, {
+			name: "decode ranges/encode",
+			data: []byte("dead beef"),
+			ops: []op{
+				Decode,
+				Encode,
+			},
+			converters: []converter{
+				resultToBinStr,
+				basicConverter,
+			},
+			opts: [][]Opt{
+				{WithInRanges(types.Range{Offset: 40, Length: 32}, types.Range{Offset: 32, Length: 8})},
+				{},
+			},
+			expectedData: []byte("beef "),
+		}, {
+			name: "decode compressed ranges with cap/encode",
+			data: compressData([]byte("dead beef"), compression.Zstd),
+			ops: []op{
+				Decode,
+				Encode,
+			},
+			converters: []converter{
+				resultToBinStr,
+				basicConverter,
+			},
+			opts: [][]Opt{
+				{WithInCompression(compression.Zstd), WithInRanges(types.Range{Offset: 40}), WithInBitsCap(16)},
+				{},
+			},
+			expectedData: []byte("be"),
+		}, {
+			name: "decode/encode ranges",
+			data: []byte("dead beef"),
+			ops: []op{
+				Decode,
+				Encode,
+			},
+			converters: []converter{
+				resultToBinStr,
+				basicConverter,
+			},
+			opts: [][]Opt{
+				{},
+				{WithInRanges(types.Range{Offset: 44, Length: 8}, types.Range{Offset: 0, Length: 8})},
+			},
+			expectedData: []byte("&d"),
 		}, {
 			name: "decode and compress/encode compressed and cap",
 			data: []byte("dead beef"),
@@ -137,6 +202,22 @@ func TestE2E(t *testing.T) {
 				{WithInCompression(compression.Brotli), WithOutBitsCap(16)},
 			},
 			expectedData: []byte("de"),
+		}, {
+			name: "decode and line encode/encode line decoded",
+			data: []byte("dead beef"),
+			ops: []op{
+				Decode,
+				Encode,
+			},
+			converters: []converter{
+				resultToBinStr,
+				basicConverter,
+			},
+			opts: [][]Opt{
+				{WithLineEncode(linecode.ManchesterIEEE)},
+				{WithLineDecode(linecode.ManchesterIEEE)},
+			},
+			expectedData: []byte("dead beef"),
 		}, {
 			name: "decode compressed/encode compressed and compress 2",
 			data: compressData([]byte("a longer text so that compression actually does something nice"), compression.Brotli),
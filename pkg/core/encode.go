@@ -23,3 +23,8 @@ func Encode(ctx context.Context, r io.Reader, opts ...Opt) (*types.Result, error
 
 	return createResult(ctx, bits, opts...)
 }
+
+// ReadBinStrBits receives a bit string in a io.Reader and returns its bits, without analysing them
+func ReadBinStrBits(ctx context.Context, r io.Reader, opts ...Opt) ([]types.Bit, error) {
+	return binStrReaderToBits(ctx, r, opts...)
+}
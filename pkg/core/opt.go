@@ -2,21 +2,49 @@ package core
 
 import (
 	"github.com/fedemengo/d2bist/pkg/compression"
+	"github.com/fedemengo/d2bist/pkg/extract"
+	"github.com/fedemengo/d2bist/pkg/linecode"
+	"github.com/fedemengo/d2bist/pkg/segment"
+	"github.com/fedemengo/d2bist/pkg/stats"
+	"github.com/fedemengo/d2bist/pkg/types"
 )
 
 type Config struct {
 	InMaxBits         int                         `json:"in_max_bits"`
 	InCompressionType compression.CompressionType `json:"in_compression_type"`
+	InCodecOpts       []compression.Opt           `json:"-"`
+	InRanges          []types.Range               `json:"in_ranges,omitempty"`
 
 	OutMaxBits         int                         `json:"out_max_bits"`
 	OutCompressionType compression.CompressionType `json:"out_compression_type"`
+	OutCodecOpts       []compression.Opt           `json:"-"`
 
 	StatsBlockSize    int `json:"stats_block_size"`
 	StatsSymbolLen    int `json:"stats_symbol_len"`
+	StatsStride       int `json:"stats_stride"`
 	StatsMaxBlockSize int `json:"stats_max_block_size"`
 	StatsTopK         int `json:"stats_top_k"`
 
-	EntropyPlotName string `json:"entropy_plot_name"`
+	StatsApproximate bool `json:"stats_approximate"`
+	StatsSketchWidth int  `json:"stats_sketch_width"`
+	StatsSketchDepth int  `json:"stats_sketch_depth"`
+
+	StatsEstimators []stats.Estimator `json:"-"`
+
+	SegmentEntropy types.EntropyType `json:"segment_entropy"`
+	SegmentOpts    []segment.Opt     `json:"-"`
+
+	LineDecode linecode.Code `json:"line_decode"`
+	LineEncode linecode.Code `json:"line_encode"`
+
+	Extractor           extract.ExtractorType `json:"extractor"`
+	ExtractFoldSize     int                   `json:"extract_fold_size"`
+	ExtractToeplitzIn   int                   `json:"extract_toeplitz_in"`
+	ExtractToeplitzOut  int                   `json:"extract_toeplitz_out"`
+	ExtractToeplitzSeed int64                 `json:"extract_toeplitz_seed"`
+
+	EntropyPlotPath  string           `json:"entropy_plot_path"`
+	EntropyChartOpts []types.ChartOpt `json:"-"`
 }
 
 func NewDefaultConfig() *Config {
@@ -31,6 +59,17 @@ func NewDefaultConfig() *Config {
 		StatsTopK:         -1,
 
 		StatsSymbolLen: 2,
+
+		StatsSketchWidth: stats.DefaultSketchWidth,
+		StatsSketchDepth: stats.DefaultSketchDepth,
+
+		LineDecode: linecode.None,
+		LineEncode: linecode.None,
+
+		Extractor:          extract.None,
+		ExtractFoldSize:    2,
+		ExtractToeplitzIn:  64,
+		ExtractToeplitzOut: 32,
 	}
 }
 
@@ -54,12 +93,34 @@ func WithInBitsCap(maxBits int) Opt {
 	}
 }
 
+// WithInRanges reads only the ranges of the input, in bits and one after the other, a range
+// of length 0 ends with the input
+func WithInRanges(ranges ...types.Range) Opt {
+	return func(c *Config) {
+		c.InRanges = append(c.InRanges, ranges...)
+	}
+}
+
 func WithInCompression(ct compression.CompressionType) Opt {
 	return func(c *Config) {
 		c.InCompressionType = ct
 	}
 }
 
+// WithInCodecOpts tunes the codec that decompresses the input, e.g. with its dictionary
+func WithInCodecOpts(opts ...compression.Opt) Opt {
+	return func(c *Config) {
+		c.InCodecOpts = append(c.InCodecOpts, opts...)
+	}
+}
+
+// WithOutCodecOpts tunes the codec that compresses the output, and the entropy estimated with it
+func WithOutCodecOpts(opts ...compression.Opt) Opt {
+	return func(c *Config) {
+		c.OutCodecOpts = append(c.OutCodecOpts, opts...)
+	}
+}
+
 func WithStatsMaxBlockSize(maxBlockSize int) Opt {
 	return func(c *Config) {
 		c.StatsMaxBlockSize = maxBlockSize
@@ -78,14 +139,85 @@ func WithStatsSymbolLen(entropyChunk int) Opt {
 	}
 }
 
+// WithStatsStride calculates the entropy on windows of the block size every stride bits
+func WithStatsStride(stride int) Opt {
+	return func(c *Config) {
+		c.StatsStride = stride
+	}
+}
+
+// WithStatsApproximate estimates the most frequent substrings with a width x depth count-min sketch
+func WithStatsApproximate(width, depth int) Opt {
+	return func(c *Config) {
+		c.StatsApproximate = true
+		c.StatsSketchWidth = width
+		c.StatsSketchDepth = depth
+	}
+}
+
+// WithStatsEstimators replaces the default estimators of the entropy series
+func WithStatsEstimators(estimators ...stats.Estimator) Opt {
+	return func(c *Config) {
+		c.StatsEstimators = append(c.StatsEstimators, estimators...)
+	}
+}
+
 func WithStatsTopK(topK int) Opt {
 	return func(c *Config) {
 		c.StatsTopK = topK
 	}
 }
 
-func WithEntropyPlotName(name string) Opt {
+// WithSegments splits the bits in regions where the entropy series eType changes, eType must be calculated
+func WithSegments(eType types.EntropyType, opts ...segment.Opt) Opt {
+	return func(c *Config) {
+		c.SegmentEntropy = eType
+		c.SegmentOpts = append(c.SegmentOpts, opts...)
+	}
+}
+
+// WithEntropyPlotPath writes the entropy chart to path, its extension selects the format (png, svg, csv or json)
+func WithEntropyPlotPath(path string) Opt {
+	return func(c *Config) {
+		c.EntropyPlotPath = path
+	}
+}
+
+// WithEntropyChartOpts styles the entropy chart
+func WithEntropyChartOpts(opts ...types.ChartOpt) Opt {
+	return func(c *Config) {
+		c.EntropyChartOpts = append(c.EntropyChartOpts, opts...)
+	}
+}
+
+func WithLineDecode(code linecode.Code) Opt {
+	return func(c *Config) {
+		c.LineDecode = code
+	}
+}
+
+func WithLineEncode(code linecode.Code) Opt {
+	return func(c *Config) {
+		c.LineEncode = code
+	}
+}
+
+func WithExtractor(et extract.ExtractorType) Opt {
+	return func(c *Config) {
+		c.Extractor = et
+	}
+}
+
+func WithExtractFoldSize(k int) Opt {
+	return func(c *Config) {
+		c.ExtractFoldSize = k
+	}
+}
+
+func WithExtractToeplitz(n, m int, seed int64) Opt {
 	return func(c *Config) {
-		c.EntropyPlotName = name
+		c.ExtractToeplitzIn = n
+		c.ExtractToeplitzOut = m
+		c.ExtractToeplitzSeed = seed
 	}
 }
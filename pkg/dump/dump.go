@@ -0,0 +1,263 @@
+package dump
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+
+	"github.com/fedemengo/d2bist/pkg/engine"
+	"github.com/fedemengo/d2bist/pkg/image"
+	iio "github.com/fedemengo/d2bist/pkg/io"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+const (
+	DefaultBitsPerRow = 64
+	DefaultGroup      = 8
+
+	ansiReset = "\x1b[0m"
+)
+
+// ansiHighlights are the styles of the sets of highlights without colour, reverse video and underline
+var ansiHighlights = []string{"\x1b[7m", "\x1b[4m"}
+
+type config struct {
+	bitsPerRow int
+	group      int
+	color      bool
+	pixelLen   int
+	highlights []highlight
+}
+
+type highlight struct {
+	ranges []types.Range
+	color  color.RGBA
+}
+
+type Opt func(c *config)
+
+// WithBitsPerRow sets how many bits each row shows, 64 by default
+func WithBitsPerRow(n int) Opt {
+	return func(c *config) {
+		c.bitsPerRow = n
+	}
+}
+
+// WithGroup separates the bits of a row in groups of n bits, 8 by default
+func WithGroup(n int) Opt {
+	return func(c *config) {
+		c.group = n
+	}
+}
+
+// WithColor colours the background of each bit with its png colour, the value of the pixelLen bits starting at it
+func WithColor(pixelLen int) Opt {
+	return func(c *config) {
+		c.color = true
+		c.pixelLen = pixelLen
+	}
+}
+
+// WithHighlights highlights the bits in ranges, blended with color when the bits are coloured,
+// in reverse video otherwise, the bytes of the hex and ascii panes with a highlighted bit are in reverse video
+//
+// the styles of the sets of highlights alternate between reverse video and underline, so that
+// consecutive sets stay apart without colour
+func WithHighlights(ranges []types.Range, c color.RGBA) Opt {
+	return func(conf *config) {
+		conf.highlights = append(conf.highlights, highlight{ranges: ranges, color: c})
+	}
+}
+
+// style is how a bit or byte is printed, the zero style is plain text
+type style struct {
+	bg *color.RGBA
+	// highlight is 1 + the index of the last set of highlights of the bit, 0 when it has none
+	highlight int
+}
+
+// Write writes bits like `xxd -b`: a row per bitsPerRow bits with the bit offset of the row,
+// the bits, the hex of the bytes that end in the row and their printable ASCII characters
+//
+// the bytes are aligned to the start of the bits, rows whose size is not a multiple of 8 split bytes
+// across rows and show only the bytes that end in them
+func Write(w io.Writer, bits []types.Bit, opts ...Opt) error {
+	c := &config{
+		bitsPerRow: DefaultBitsPerRow,
+		group:      DefaultGroup,
+		pixelLen:   1,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.bitsPerRow < 1 || c.group < 1 {
+		return fmt.Errorf("bits per row %d and group %d must be positive", c.bitsPerRow, c.group)
+	}
+
+	styles, err := bitStyles(bits, c)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+
+	offsetWidth := len(fmt.Sprint(max(len(bits)-1, 0)))
+	bitsWidth := c.bitsPerRow + (c.bitsPerRow-1)/c.group
+	bytesPerRow := (c.bitsPerRow + 7) / 8
+
+	for start := 0; start < len(bits); start += c.bitsPerRow {
+		end := min(start+c.bitsPerRow, len(bits))
+
+		fmt.Fprintf(bw, "%0*d: ", offsetWidth, start)
+
+		rowBits := iio.BitsToString(bits[start:end], iio.WithSep(' '), iio.WithSepDistance(c.group))
+		writeStyled(bw, rowBits, styles[start:end])
+		bw.WriteString(strings.Repeat(" ", bitsWidth-len(rowBits)))
+
+		// the bytes that end in the row, the trailing bits that do not fill a byte are not shown
+		firstByte, lastByte := start/8, end/8
+
+		hex, ascii := strings.Builder{}, strings.Builder{}
+		var byteBits [8]types.Bit
+		for i := firstByte; i < lastByte; i++ {
+			copy(byteBits[:], bits[i*8:i*8+8])
+			b := engine.BitsToByte(byteBits)
+
+			// the byte has the highlight of its first highlighted bit
+			highlight := 0
+			for _, s := range styles[i*8 : i*8+8] {
+				if highlight == 0 {
+					highlight = s.highlight
+				}
+			}
+
+			ch := "."
+			if b >= 0x20 && b < 0x7f {
+				ch = string(rune(b))
+			}
+			if highlight > 0 {
+				hl := ansiHighlight(highlight)
+				hex.WriteString(hl + fmt.Sprintf("%02x", b) + ansiReset + " ")
+				ascii.WriteString(hl + ch + ansiReset)
+			} else {
+				hex.WriteString(fmt.Sprintf("%02x ", b))
+				ascii.WriteString(ch)
+			}
+		}
+		padding := bytesPerRow - (lastByte - firstByte)
+
+		fmt.Fprintf(bw, "  %s%s %s\n", hex.String(), strings.Repeat("   ", max(padding, 0)), ascii.String())
+	}
+
+	return bw.Flush()
+}
+
+// bitStyles returns the style of each bit, the colour of its pixel blended with the colours of its highlights
+func bitStyles(bits []types.Bit, c *config) ([]style, error) {
+	styles := make([]style, len(bits))
+
+	if c.color {
+		if c.pixelLen < 1 {
+			return nil, fmt.Errorf("pixel length must be greater than 0")
+		}
+
+		// like the png, the colour of a bit is the value of the pixelLen bits window starting at it
+		bw := engine.NewBitsWindow(bits, c.pixelLen)
+		for i := 0; i+c.pixelLen <= len(bits); i++ {
+			bg, err := image.PixelColor(bw.ToInt(), c.pixelLen)
+			if err != nil {
+				return nil, err
+			}
+			styles[i].bg = &bg
+
+			// the window cannot slide past the last bit
+			_ = bw.Slide()
+		}
+	}
+
+	for hi, h := range c.highlights {
+		for _, r := range h.ranges {
+			for i := max(r.Offset, 0); i < r.Offset+r.Length && i < len(bits); i++ {
+				styles[i].highlight = hi + 1
+				if styles[i].bg != nil {
+					bg := image.Blend(*styles[i].bg, h.color)
+					styles[i].bg = &bg
+				}
+			}
+		}
+	}
+
+	return styles, nil
+}
+
+// writeStyled writes the bit string s, whose bits have the styles, the separators are plain
+func writeStyled(w *bufio.Writer, s string, styles []style) {
+	bit := 0
+	current := style{}
+	for _, r := range s {
+		next := style{}
+		if r == '0' || r == '1' {
+			next = styles[bit]
+			bit++
+		}
+
+		if !sameStyle(current, next) {
+			if current != (style{}) {
+				w.WriteString(ansiReset)
+			}
+			w.WriteString(ansiStyle(next))
+			current = next
+		}
+		w.WriteRune(r)
+	}
+	if current != (style{}) {
+		w.WriteString(ansiReset)
+	}
+}
+
+func sameStyle(s1, s2 style) bool {
+	if s1.highlight != s2.highlight || (s1.bg == nil) != (s2.bg == nil) {
+		return false
+	}
+
+	return s1.bg == nil || *s1.bg == *s2.bg
+}
+
+// ansiStyle is the escape sequence of a style, a truecolor background with a readable
+// foreground, or the style of its set of highlights without colour
+func ansiStyle(s style) string {
+	switch {
+	case s.bg != nil:
+		fg := "\x1b[38;2;0;0;0m"
+		// the perceived brightness of the background
+		if 299*int(s.bg.R)+587*int(s.bg.G)+114*int(s.bg.B) < 128*1000 {
+			fg = "\x1b[38;2;255;255;255m"
+		}
+		return fmt.Sprintf("\x1b[48;2;%d;%d;%dm%s", s.bg.R, s.bg.G, s.bg.B, fg)
+	case s.highlight > 0:
+		return ansiHighlight(s.highlight)
+	default:
+		return ""
+	}
+}
+
+// ansiHighlight is the style of the set of highlights of a bit, see style.highlight
+func ansiHighlight(highlight int) string {
+	return ansiHighlights[(highlight-1)%len(ansiHighlights)]
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,134 @@
+package dump
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fedemengo/d2bist/pkg/engine"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+func bytesToBits(data []byte) []types.Bit {
+	bits := make([]types.Bit, 0, len(data)*8)
+	for _, b := range data {
+		byteBits := engine.ByteToBits(b)
+		bits = append(bits, byteBits[:]...)
+	}
+
+	return bits
+}
+
+func TestWrite(t *testing.T) {
+	testCases := []struct {
+		name     string
+		bits     []types.Bit
+		opts     []Opt
+		expected []string
+	}{
+		{
+			name: "default",
+			bits: bytesToBits([]byte("Hello, dump!\x00")),
+			expected: []string{
+				"000: 01001000 01100101 01101100 01101100 01101111 00101100 00100000 01100100  48 65 6c 6c 6f 2c 20 64  Hello, d",
+				"064: 01110101 01101101 01110000 00100001 00000000                             75 6d 70 21 00           ump!.",
+			},
+		},
+		{
+			name: "row and group",
+			bits: bytesToBits([]byte("Hey")),
+			opts: []Opt{WithBitsPerRow(16), WithGroup(4)},
+			expected: []string{
+				"00: 0100 1000 0110 0101  48 65  He",
+				"16: 0111 1001            79     y",
+			},
+		},
+		{
+			name: "bytes across rows",
+			bits: bytesToBits([]byte("Hey")),
+			opts: []Opt{WithBitsPerRow(12)},
+			expected: []string{
+				"00: 01001000 0110  48     H",
+				"12: 01010111 1001  65 79  ey",
+			},
+		},
+		{
+			name: "trailing bits",
+			bits: append(bytesToBits([]byte("H")), 1, 0, 1),
+			expected: []string{
+				"00: 01001000 101" + strings.Repeat(" ", 61) + "48" + strings.Repeat(" ", 23) + "H",
+			},
+		},
+		{
+			name:     "empty",
+			bits:     []types.Bit{},
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			require.NoError(t, Write(buf, tc.bits, tc.opts...))
+
+			lines := []string{}
+			if buf.Len() > 0 {
+				lines = strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			}
+			assert.Equal(t, tc.expected, lines)
+		})
+	}
+}
+
+func TestWriteHighlights(t *testing.T) {
+	buf := &bytes.Buffer{}
+	hl := WithHighlights([]types.Range{{Offset: 6, Length: 4}}, color.RGBA{R: 255, A: 255})
+	require.NoError(t, Write(buf, bytesToBits([]byte("Hey")), WithBitsPerRow(24), hl))
+
+	expected := "00: 010010\x1b[7m00\x1b[0m \x1b[7m01\x1b[0m100101 01111001  " +
+		"\x1b[7m48\x1b[0m \x1b[7m65\x1b[0m 79  \x1b[7mH\x1b[0m\x1b[7me\x1b[0my\n"
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWriteHighlightSets(t *testing.T) {
+	buf := &bytes.Buffer{}
+	red, blue := color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255}
+	opts := []Opt{
+		WithBitsPerRow(16),
+		WithHighlights([]types.Range{{Offset: 0, Length: 4}}, red),
+		WithHighlights([]types.Range{{Offset: 4, Length: 8}}, blue),
+	}
+	require.NoError(t, Write(buf, bytesToBits([]byte("He")), opts...))
+
+	// consecutive sets are reverse video and underlined, not merged in one block
+	expected := "00: \x1b[7m0100\x1b[0m\x1b[4m1000\x1b[0m \x1b[4m0110\x1b[0m0101  " +
+		"\x1b[7m48\x1b[0m \x1b[4m65\x1b[0m  \x1b[7mH\x1b[0m\x1b[4me\x1b[0m\n"
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWriteColor(t *testing.T) {
+	a := assert.New(t)
+
+	red := color.RGBA{R: 255, A: 255}
+	buf := &bytes.Buffer{}
+	hl := WithHighlights([]types.Range{{Offset: 1, Length: 1}}, red)
+	require.NoError(t, Write(buf, []types.Bit{0, 0, 1}, WithColor(1), hl))
+
+	// 0 is white with a black foreground, 1 is black with a white foreground, the highlighted 0 is pink
+	white := "\x1b[48;2;255;255;255m\x1b[38;2;0;0;0m"
+	pink := "\x1b[48;2;255;127;127m\x1b[38;2;0;0;0m"
+	black := "\x1b[48;2;0;0;0m\x1b[38;2;255;255;255m"
+	a.True(strings.HasPrefix(buf.String(), "0: "+white+"0"+ansiReset+pink+"0"+ansiReset+black+"1"+ansiReset), buf.String())
+}
+
+func TestWriteErrors(t *testing.T) {
+	bits := bytesToBits([]byte("Hey"))
+
+	assert.Error(t, Write(&bytes.Buffer{}, bits, WithBitsPerRow(0)))
+	assert.Error(t, Write(&bytes.Buffer{}, bits, WithGroup(0)))
+	assert.Error(t, Write(&bytes.Buffer{}, bits, WithColor(5)))
+}
@@ -114,6 +114,21 @@ func BitsToString(bits []types.Bit) string {
 	return string(s)
 }
 
+// PackBits packs bits in 64 bits words, the first bit is the most significant bit of the first word
+//
+// the last word is padded with 0s
+func PackBits(bits []types.Bit) []uint64 {
+	words := make([]uint64, (len(bits)+63)/64)
+	for i, b := range bits {
+		words[i/64] |= uint64(b) << (63 - uint(i%64))
+	}
+
+	return words
+}
+
+// BitsWindow is a window sliding over bits
+//
+// ToInt packs the window in a uint64, so it's only meaningful for windows up to 64 bits
 type BitsWindow interface {
 	Slide() error
 	SlideBy(n int) error
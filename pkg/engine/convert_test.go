@@ -23,3 +23,14 @@ func TestIntBitsToByte(t *testing.T) {
 	b = BitsToByte([8]types.Bit{0, 0, 0, 0, 0, 0, 0, 1})
 	a.Equal(string(byte(1)), string(b))
 }
+
+func TestPackBits(t *testing.T) {
+	a := assert.New(t)
+
+	a.Empty(PackBits(nil))
+
+	bits := make([]types.Bit, 65)
+	bits[0], bits[63], bits[64] = 1, 1, 1
+
+	a.Equal([]uint64{1<<63 | 1, 1 << 63}, PackBits(bits))
+}
@@ -0,0 +1,219 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/rs/zerolog"
+
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+var ErrInvalidSeed = errors.New("invalid seed")
+
+type ExtractorType string
+
+const (
+	None                = ExtractorType("None")
+	VonNeumannExtractor = ExtractorType("VonNeumann")
+	PeresExtractor      = ExtractorType("Peres")
+	XORFoldExtractor    = ExtractorType("XORFold")
+	ToeplitzExtractor   = ExtractorType("Toeplitz")
+)
+
+const (
+	defaultPeresDepth  = 16
+	defaultFoldSize    = 2
+	defaultToeplitzIn  = 64
+	defaultToeplitzOut = 32
+)
+
+type config struct {
+	peresDepth  int
+	foldSize    int
+	toeplitzIn  int
+	toeplitzOut int
+	seed        []types.Bit
+}
+
+type Opt func(c *config)
+
+// WithPeresDepth limits the number of recursion levels of the Peres extractor
+func WithPeresDepth(depth int) Opt {
+	return func(c *config) {
+		c.peresDepth = depth
+	}
+}
+
+// WithFoldSize sets the number of bits XORed together by the XOR-folding extractor
+func WithFoldSize(k int) Opt {
+	return func(c *config) {
+		c.foldSize = k
+	}
+}
+
+// WithToeplitzSize sets the input block length n and output block length m of the Toeplitz extractor
+func WithToeplitzSize(n, m int) Opt {
+	return func(c *config) {
+		c.toeplitzIn = n
+		c.toeplitzOut = m
+	}
+}
+
+// WithSeed sets the bits defining the Toeplitz matrix, n+m-1 are required
+func WithSeed(seed []types.Bit) Opt {
+	return func(c *config) {
+		c.seed = seed
+	}
+}
+
+// Extract debiases bits with the extractor eType
+func Extract(ctx context.Context, bits []types.Bit, eType ExtractorType, opts ...Opt) ([]types.Bit, error) {
+	log := zerolog.Ctx(ctx)
+
+	c := &config{
+		peresDepth:  defaultPeresDepth,
+		foldSize:    defaultFoldSize,
+		toeplitzIn:  defaultToeplitzIn,
+		toeplitzOut: defaultToeplitzOut,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	switch eType {
+	case None:
+		log.Trace().Msg("no extractor")
+		return bits, nil
+	case VonNeumannExtractor:
+		log.Trace().Msg("von neumann extractor")
+		return VonNeumann(bits), nil
+	case PeresExtractor:
+		log.Trace().Int("depth", c.peresDepth).Msg("peres extractor")
+		return Peres(bits, c.peresDepth), nil
+	case XORFoldExtractor:
+		log.Trace().Int("foldSize", c.foldSize).Msg("xor-folding extractor")
+		return XORFold(bits, c.foldSize)
+	case ToeplitzExtractor:
+		log.Trace().
+			Int("in", c.toeplitzIn).
+			Int("out", c.toeplitzOut).
+			Msg("toeplitz extractor")
+		seed := c.seed
+		if seed == nil {
+			seed = SeedBits(0, c.toeplitzIn+c.toeplitzOut-1)
+		}
+		return Toeplitz(bits, seed, c.toeplitzIn, c.toeplitzOut)
+	default:
+		return nil, fmt.Errorf("extractor type %s not supported", eType)
+	}
+}
+
+// VonNeumann reads the bits in pairs, 01 outputs 0, 10 outputs 1 and 00, 11 are discarded
+func VonNeumann(bits []types.Bit) []types.Bit {
+	out := make([]types.Bit, 0, len(bits)/4)
+	for i := 0; i+1 < len(bits); i += 2 {
+		if bits[i] != bits[i+1] {
+			out = append(out, bits[i])
+		}
+	}
+
+	return out
+}
+
+// Peres is the iterated von Neumann extractor
+//
+// on top of the von Neumann output, it recursively extracts from the sequence
+// of pairs XOR (u) and from the value of the discarded equal pairs (v)
+//
+//	Ψ(x) = VN(x) || Ψ(u) || Ψ(v)
+func Peres(bits []types.Bit, depth int) []types.Bit {
+	if depth <= 0 || len(bits) < 2 {
+		return nil
+	}
+
+	out := make([]types.Bit, 0, len(bits)/4)
+	u := make([]types.Bit, 0, len(bits)/2)
+	v := make([]types.Bit, 0, len(bits)/4)
+	for i := 0; i+1 < len(bits); i += 2 {
+		a, b := bits[i], bits[i+1]
+		if a != b {
+			out = append(out, a)
+		} else {
+			v = append(v, a)
+		}
+		u = append(u, a^b)
+	}
+
+	out = append(out, Peres(u, depth-1)...)
+	out = append(out, Peres(v, depth-1)...)
+
+	return out
+}
+
+// XORFold outputs the XOR of each consecutive k bits, trailing bits are discarded
+func XORFold(bits []types.Bit, k int) ([]types.Bit, error) {
+	if k < 1 {
+		return nil, fmt.Errorf("fold size must be greater than 0")
+	}
+
+	out := make([]types.Bit, 0, len(bits)/k)
+	for i := 0; i+k <= len(bits); i += k {
+		x := types.Bit(0)
+		for _, b := range bits[i : i+k] {
+			x ^= b
+		}
+		out = append(out, x)
+	}
+
+	return out, nil
+}
+
+// Toeplitz multiplies each block of n bits by the m×n Toeplitz matrix defined by seed
+//
+// the matrix is constant along its diagonals so n+m-1 seed bits define it
+//
+//	T[i][j] = seed[i-j+n-1]
+func Toeplitz(bits, seed []types.Bit, n, m int) ([]types.Bit, error) {
+	if n < 1 || m < 1 {
+		return nil, fmt.Errorf("toeplitz matrix size must be greater than 0")
+	}
+	if m > n {
+		return nil, fmt.Errorf("toeplitz output length %d cannot be greater than input length %d", m, n)
+	}
+	if len(seed) != n+m-1 {
+		return nil, fmt.Errorf("%d seed bits, %d required: %w", len(seed), n+m-1, ErrInvalidSeed)
+	}
+
+	out := make([]types.Bit, 0, len(bits)/n*m)
+	for k := 0; k+n <= len(bits); k += n {
+		block := bits[k : k+n]
+		for i := 0; i < m; i++ {
+			y := types.Bit(0)
+			for j, b := range block {
+				y ^= seed[i-j+n-1] & b
+			}
+			out = append(out, y)
+		}
+	}
+
+	return out, nil
+}
+
+// SeedBits deterministically expands an integer seed to n bits
+func SeedBits(seed int64, n int) []types.Bit {
+	if n < 1 {
+		return nil
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	bits := make([]types.Bit, n)
+	for i := range bits {
+		bits[i] = types.Bit(rng.Intn(2))
+	}
+
+	return bits
+}
@@ -0,0 +1,101 @@
+package extract
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+func TestVonNeumann(t *testing.T) {
+	testCases := []struct {
+		name         string
+		bits         []types.Bit
+		expectedBits []types.Bit
+	}{
+		{
+			name:         "empty",
+			bits:         []types.Bit{},
+			expectedBits: []types.Bit{},
+		}, {
+			name:         "all pairs",
+			bits:         []types.Bit{0, 0, 0, 1, 1, 0, 1, 1},
+			expectedBits: []types.Bit{0, 1},
+		}, {
+			name:         "odd length",
+			bits:         []types.Bit{1, 0, 1, 0, 1},
+			expectedBits: []types.Bit{1, 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			a := assert.New(tt)
+
+			a.Equal(tc.expectedBits, VonNeumann(tc.bits))
+		})
+	}
+}
+
+func TestPeres(t *testing.T) {
+	a := assert.New(t)
+
+	// pairs: 00 01 11 10
+	// vn: 0 1
+	// u: 0 1 0 1 -> vn: 0 0, u: 1 1 -> v: 1, v: (empty)
+	// v: 0 1     -> vn: 0
+	bits := []types.Bit{0, 0, 0, 1, 1, 1, 1, 0}
+
+	a.Equal([]types.Bit{0, 1}, Peres(bits, 1))
+	a.Equal([]types.Bit{0, 1, 0, 0, 0}, Peres(bits, 2))
+	a.Len(Peres(bits, 16), 5)
+	a.Equal(VonNeumann(bits), Peres(bits, 1))
+}
+
+func TestXORFold(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	bits, err := XORFold([]types.Bit{1, 1, 0, 1, 0, 0, 1, 1, 1, 0}, 3)
+	r.NoError(err)
+	a.Equal([]types.Bit{0, 1, 1}, bits)
+
+	_, err = XORFold([]types.Bit{1}, 0)
+	r.Error(err)
+}
+
+func TestToeplitz(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	// identity-like matrix: only the main diagonal is set
+	n, m := 4, 2
+	seed := []types.Bit{0, 0, 0, 1, 0}
+
+	bits, err := Toeplitz([]types.Bit{1, 0, 1, 1, 0, 1, 0, 0}, seed, n, m)
+	r.NoError(err)
+	a.Equal([]types.Bit{1, 0, 0, 1}, bits)
+
+	_, err = Toeplitz(bits, seed[1:], n, m)
+	r.ErrorIs(err, ErrInvalidSeed)
+}
+
+func TestExtractYield(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+	ctx := context.Background()
+
+	bits := SeedBits(42, 4096)
+	for _, eType := range []ExtractorType{None, VonNeumannExtractor, PeresExtractor, XORFoldExtractor, ToeplitzExtractor} {
+		out, err := Extract(ctx, bits, eType)
+		r.NoError(err)
+		a.LessOrEqual(len(out), len(bits), string(eType))
+		a.NotEmpty(out, string(eType))
+	}
+
+	vn, err := Extract(ctx, bits, VonNeumannExtractor)
+	r.NoError(err)
+	peres, err := Extract(ctx, bits, PeresExtractor)
+	r.NoError(err)
+	a.Greater(len(peres), len(vn))
+}
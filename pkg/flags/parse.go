@@ -1,12 +1,23 @@
 package flags
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"strconv"
+	"strings"
 	"unicode"
 
 	"github.com/fedemengo/d2bist/pkg/compression"
+	"github.com/fedemengo/d2bist/pkg/extract"
+	"github.com/fedemengo/d2bist/pkg/image"
+	"github.com/fedemengo/d2bist/pkg/intcode"
+	"github.com/fedemengo/d2bist/pkg/linecode"
+	"github.com/fedemengo/d2bist/pkg/segment"
+	"github.com/fedemengo/d2bist/pkg/stats"
+	"github.com/fedemengo/d2bist/pkg/types"
 )
 
 var (
@@ -29,25 +40,228 @@ var (
 	ErrInvalidFlag = errors.New("flag is not valid")
 )
 
-func ParseCompressionFlag(fc string) compression.CompressionType {
-	switch fc {
-	case "zip":
-		return compression.Zip
-	case "gz", "gzip":
-		return compression.Gzip
-	case "b", "brotli":
-		return compression.Brotli
-	case "zstd":
-		return compression.Zstd
-	case "s2":
-		return compression.S2
-	case "h", "huff":
-		return compression.Huff
+// ParseCompressionFlag returns the codec named by one of its aliases, see compression.Register
+func ParseCompressionFlag(fc string) (compression.CompressionType, error) {
+	cType, err := compression.Parse(fc)
+	if err != nil {
+		return compression.None, fmt.Errorf("compression `%s` is not supported: %w", fc, ErrInvalidFlag)
+	}
+
+	return cType, nil
+}
+
+func ParseExtractorFlag(fe string) (extract.ExtractorType, error) {
+	switch fe {
+	case "":
+		return extract.None, nil
+	case "vn", "vonneumann":
+		return extract.VonNeumannExtractor, nil
+	case "peres":
+		return extract.PeresExtractor, nil
+	case "xor":
+		return extract.XORFoldExtractor, nil
+	case "toeplitz":
+		return extract.ToeplitzExtractor, nil
+	default:
+		return extract.None, fmt.Errorf("extractor `%s` is not supported: %w", fe, ErrInvalidFlag)
+	}
+}
+
+func ParseLineCodeFlag(fl string) (linecode.Code, error) {
+	switch fl {
+	case "":
+		return linecode.None, nil
+	case "nrzi":
+		return linecode.NRZI, nil
+	case "manchester", "ieee":
+		return linecode.ManchesterIEEE, nil
+	case "thomas":
+		return linecode.ManchesterThomas, nil
+	case "diffmanchester", "dm":
+		return linecode.DiffManchester, nil
+	case "hdlc":
+		return linecode.HDLC, nil
+	case "usb":
+		return linecode.USB, nil
+	default:
+		return linecode.None, fmt.Errorf("line code `%s` is not supported: %w", fl, ErrInvalidFlag)
+	}
+}
+
+func ParseIntCodeFlag(fi string) (intcode.Code, error) {
+	switch fi {
+	case "unary":
+		return intcode.Unary, nil
+	case "gamma":
+		return intcode.Gamma, nil
+	case "delta":
+		return intcode.Delta, nil
+	case "omega":
+		return intcode.Omega, nil
+	case "fib", "fibonacci":
+		return intcode.Fibonacci, nil
+	case "golomb":
+		return intcode.Golomb, nil
+	case "rice":
+		return intcode.Rice, nil
+	case "lev", "levenshtein":
+		return intcode.Levenshtein, nil
+	default:
+		return "", fmt.Errorf("integer code `%s` is not supported: %w", fi, ErrInvalidFlag)
+	}
+}
+
+// ParseEntropyFlag returns the series of an estimator, see ParseEstimatorsFlag
+func ParseEntropyFlag(fe string) (types.EntropyType, error) {
+	e, err := stats.NewEstimator(fe, 1)
+	if err != nil {
+		return "", fmt.Errorf("entropy `%s` is not supported: %w", fe, ErrInvalidFlag)
+	}
+
+	return e.Name(), nil
+}
+
+// ParseEstimatorsFlag parses a comma separated list of estimators, shannon, lz76, a registered
+// estimator or a codec, an empty list selects the default estimators
+func ParseEstimatorsFlag(fe string, symbolLen int) ([]stats.Estimator, error) {
+	if len(strings.TrimSpace(fe)) == 0 {
+		return nil, nil
+	}
+
+	estimators := []stats.Estimator{}
+	for _, es := range strings.Split(fe, ",") {
+		name, params, ok := strings.Cut(strings.TrimSpace(es), ":")
+		if !ok {
+			e, err := stats.NewEstimator(name, symbolLen)
+			if err != nil {
+				return nil, fmt.Errorf("estimator `%s` is not supported: %w", name, ErrInvalidFlag)
+			}
+			estimators = append(estimators, e)
+			continue
+		}
+
+		cType, err := compression.Parse(name)
+		if err != nil || cType == compression.None || cType == compression.Auto {
+			return nil, fmt.Errorf("estimator `%s` is not a codec, only codecs have parameters: %w", name, ErrInvalidFlag)
+		}
+		opts, err := ParseCodecParams(params)
+		if err != nil {
+			return nil, fmt.Errorf("estimator `%s`: %w", name, err)
+		}
+		// the codec checks its parameters when it is created
+		w, err := compression.NewCompressedWriter(context.Background(), io.Discard, cType, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("estimator `%s`: %v: %w", es, err, ErrInvalidFlag)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("estimator `%s`: %v: %w", es, err, ErrInvalidFlag)
+		}
+		estimators = append(estimators, stats.CompressionEstimator(cType, opts...))
+	}
+
+	return estimators, nil
+}
+
+// ParseCodecParams parses the colon separated codec parameters of an estimator,
+// level=N, window=N and dict=PATH, e.g. `level=19:window=23`
+func ParseCodecParams(fp string) ([]compression.Opt, error) {
+	opts := []compression.Opt{}
+	for _, param := range strings.Split(fp, ":") {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok || len(value) == 0 {
+			return nil, fmt.Errorf("codec parameter `%s` is not key=value: %w", param, ErrInvalidFlag)
+		}
+
+		switch key {
+		case "level", "window":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("codec %s `%s` is not a non-negative number: %w", key, value, ErrInvalidFlag)
+			}
+			if key == "level" {
+				opts = append(opts, compression.WithLevel(n))
+			} else {
+				opts = append(opts, compression.WithWindow(n))
+			}
+		case "dict":
+			dict, err := os.ReadFile(value)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read dictionary: %w", err)
+			}
+			opts = append(opts, compression.WithDictionary(dict))
+		default:
+			return nil, fmt.Errorf("codec parameter `%s` is not supported: %w", key, ErrInvalidFlag)
+		}
+	}
+
+	return opts, nil
+}
+
+func ParseSegmentMethodFlag(fs string) (segment.Method, error) {
+	switch fs {
+	case "", "pelt":
+		return segment.PELT, nil
+	case "binseg":
+		return segment.BinSeg, nil
+	case "cusum":
+		return segment.CUSUM, nil
+	default:
+		return "", fmt.Errorf("segmentation method `%s` is not supported: %w", fs, ErrInvalidFlag)
+	}
+}
+
+func ParseHeatmapModeFlag(fh string) (image.HeatmapMode, error) {
+	switch fh {
+	case "", "overlay":
+		return image.HeatmapOverlay, nil
+	case "side":
+		return image.HeatmapSideBySide, nil
 	default:
-		return compression.None
+		return "", fmt.Errorf("heatmap mode `%s` is not supported: %w", fh, ErrInvalidFlag)
 	}
 }
 
+// ParseRangeFlag parses a range in the form `min:max`
+func ParseRangeFlag(fr string) (float64, float64, error) {
+	minStr, maxStr, ok := strings.Cut(fr, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("range `%s` is not in the form min:max: %w", fr, ErrInvalidFlag)
+	}
+
+	min, err := strconv.ParseFloat(minStr, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s is not a valid number: %w", minStr, ErrInvalidFlag)
+	}
+	max, err := strconv.ParseFloat(maxStr, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s is not a valid number: %w", maxStr, ErrInvalidFlag)
+	}
+	if min >= max {
+		return 0, 0, fmt.Errorf("range `%s` must have min < max: %w", fr, ErrInvalidFlag)
+	}
+
+	return min, max, nil
+}
+
+// ParseSizeFlag parses a size in pixels in the form `WxH`
+func ParseSizeFlag(fs string) (int, int, error) {
+	wStr, hStr, ok := strings.Cut(fs, "x")
+	if !ok {
+		return 0, 0, fmt.Errorf("size `%s` is not in the form WxH: %w", fs, ErrInvalidFlag)
+	}
+
+	w, err := strconv.Atoi(wStr)
+	if err != nil || w < 1 {
+		return 0, 0, fmt.Errorf("%s is not a valid width: %w", wStr, ErrInvalidFlag)
+	}
+	h, err := strconv.Atoi(hStr)
+	if err != nil || h < 1 {
+		return 0, 0, fmt.Errorf("%s is not a valid height: %w", hStr, ErrInvalidFlag)
+	}
+
+	return w, h, nil
+}
+
 func ParseDataCapToBitsCount(dataCap string) (int, error) {
 	if dataCap == "" {
 		return -1, nil
@@ -90,3 +304,66 @@ func ParseDataCapToBitsCount(dataCap string) (int, error) {
 
 	return i * multiplier, nil
 }
+
+// ParseBitRangesFlag parses comma separated offsets and lengths, in bits or with the units of
+// ParseDataCapToBitsCount, into the ranges they select
+//
+// the offsets default to the start and the lengths to the end of the data, a single
+// length applies to every offset
+func ParseBitRangesFlag(offsets, lengths string) ([]types.Range, error) {
+	if len(strings.TrimSpace(offsets)) == 0 && len(strings.TrimSpace(lengths)) == 0 {
+		return nil, nil
+	}
+
+	offsetBits, err := ParseBitOffsetsFlag(offsets)
+	if err != nil {
+		return nil, err
+	}
+	if len(offsetBits) == 0 {
+		offsetBits = []int{0}
+	}
+	lengthBits, err := ParseBitOffsetsFlag(lengths)
+	if err != nil {
+		return nil, err
+	}
+	if len(lengthBits) == 0 {
+		lengthBits = []int{0}
+	}
+
+	if len(lengthBits) == 1 {
+		for len(lengthBits) < len(offsetBits) {
+			lengthBits = append(lengthBits, lengthBits[0])
+		}
+	}
+	if len(offsetBits) != len(lengthBits) {
+		return nil, fmt.Errorf("%d offsets and %d lengths do not match: %w", len(offsetBits), len(lengthBits), ErrInvalidFlag)
+	}
+
+	ranges := make([]types.Range, len(offsetBits))
+	for i := range ranges {
+		ranges[i] = types.Range{Offset: offsetBits[i], Length: lengthBits[i]}
+	}
+
+	return ranges, nil
+}
+
+// ParseBitOffsetsFlag parses comma separated offsets, in bits or with the units of ParseDataCapToBitsCount
+func ParseBitOffsetsFlag(fo string) ([]int, error) {
+	if len(strings.TrimSpace(fo)) == 0 {
+		return nil, nil
+	}
+
+	offsets := []int{}
+	for _, o := range strings.Split(fo, ",") {
+		bits, err := ParseDataCapToBitsCount(strings.TrimSpace(o))
+		if err != nil {
+			return nil, err
+		}
+		if bits < 0 {
+			return nil, fmt.Errorf("`%s` is not a valid offset: %w", fo, ErrInvalidFlag)
+		}
+		offsets = append(offsets, bits)
+	}
+
+	return offsets, nil
+}
@@ -1,10 +1,18 @@
 package flags
 
 import (
+	"context"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/fedemengo/d2bist/pkg/compression"
+	"github.com/fedemengo/d2bist/pkg/engine"
+	"github.com/fedemengo/d2bist/pkg/extract"
+	"github.com/fedemengo/d2bist/pkg/segment"
+	"github.com/fedemengo/d2bist/pkg/types"
 )
 
 func TestDataCapParsing(t *testing.T) {
@@ -53,3 +61,252 @@ func TestDataCapParsing(t *testing.T) {
 	}
 
 }
+
+func TestExtractorParsing(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	eType, err := ParseExtractorFlag("vn")
+	r.NoError(err)
+	a.Equal(extract.VonNeumannExtractor, eType)
+
+	eType, err = ParseExtractorFlag("")
+	r.NoError(err)
+	a.Equal(extract.None, eType)
+
+	_, err = ParseExtractorFlag("md5")
+	r.ErrorIs(err, ErrInvalidFlag)
+}
+
+func TestRangeParsing(t *testing.T) {
+	testCases := []struct {
+		name           string
+		flag           string
+		expectedMin    float64
+		expectedMax    float64
+		expectedToFail bool
+	}{
+		{
+			name:        "integers",
+			flag:        "0:100",
+			expectedMin: 0,
+			expectedMax: 100,
+		}, {
+			name:        "floats",
+			flag:        "0.25:0.75",
+			expectedMin: 0.25,
+			expectedMax: 0.75,
+		}, {
+			name:           "missing separator",
+			flag:           "100",
+			expectedToFail: true,
+		}, {
+			name:           "not a number",
+			flag:           "a:1",
+			expectedToFail: true,
+		}, {
+			name:           "empty range",
+			flag:           "1:1",
+			expectedToFail: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			a, r := assert.New(tt), require.New(tt)
+
+			min, max, err := ParseRangeFlag(tc.flag)
+			if tc.expectedToFail {
+				r.ErrorIs(err, ErrInvalidFlag)
+				return
+			}
+
+			r.NoError(err)
+			a.Equal(tc.expectedMin, min)
+			a.Equal(tc.expectedMax, max)
+		})
+	}
+}
+
+func TestSizeParsing(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	w, h, err := ParseSizeFlag("640x480")
+	r.NoError(err)
+	a.Equal(640, w)
+	a.Equal(480, h)
+
+	for _, fs := range []string{"640", "0x480", "640x-1", "ax480"} {
+		_, _, err := ParseSizeFlag(fs)
+		a.ErrorIs(err, ErrInvalidFlag, fs)
+	}
+}
+
+func TestCompressionParsing(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	for fc, expected := range map[string]compression.CompressionType{
+		"":      compression.None,
+		"auto":  compression.Auto,
+		"bzip2": compression.Bzip2,
+		"xz":    compression.Xz,
+		"Z":     compression.Lzw,
+	} {
+		cType, err := ParseCompressionFlag(fc)
+		r.NoError(err, fc)
+		a.Equal(expected, cType, fc)
+	}
+
+	_, err := ParseCompressionFlag("rar")
+	a.ErrorIs(err, ErrInvalidFlag)
+
+	eType, err := ParseEntropyFlag("bz2")
+	r.NoError(err)
+	a.Equal(types.Bzip2Entropy, eType)
+
+	for _, fe := range []string{"", "auto", "rar"} {
+		_, err := ParseEntropyFlag(fe)
+		a.ErrorIs(err, ErrInvalidFlag, fe)
+	}
+}
+
+func TestEstimatorsParsing(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	estimators, err := ParseEstimatorsFlag("shannon, zstd,lz76", 2)
+	r.NoError(err)
+
+	names := []types.EntropyType{}
+	for _, e := range estimators {
+		names = append(names, e.Name())
+	}
+	a.Equal([]types.EntropyType{types.ShannonEntropy, types.ZstdEntropy, types.LZ76Complexity}, names)
+
+	estimators, err = ParseEstimatorsFlag("", 2)
+	r.NoError(err)
+	a.Empty(estimators)
+
+	for _, fe := range []string{"shannon,", "auto", "shannon,rar", "shannon:level=1", "zstd:level", "zstd:speed=1", "zstd:level=-1", "zstd:level=9", "brotli:window=40"} {
+		_, err := ParseEstimatorsFlag(fe, 2)
+		a.ErrorIs(err, ErrInvalidFlag, fe)
+	}
+
+	// the parameters tune the codec of the estimator
+	data := []byte(strings.Repeat("tune the codec of the estimator, ", 64))
+	bits := make([]types.Bit, 0, len(data)*8)
+	for _, b := range data {
+		byteBits := engine.ByteToBits(b)
+		bits = append(bits, byteBits[:]...)
+	}
+
+	estimators, err = ParseEstimatorsFlag("brotli:level=0,brotli:level=11:window=16", 2)
+	r.NoError(err)
+	r.Len(estimators, 2)
+	a.Equal(types.BrotliEntropy, estimators[1].Name())
+	fast, err := estimators[0].Estimate(context.Background(), bits)
+	r.NoError(err)
+	best, err := estimators[1].Estimate(context.Background(), bits)
+	r.NoError(err)
+	a.Greater(fast, best)
+
+	_, err = ParseEstimatorsFlag("zstd:dict=/does/not/exist", 2)
+	a.Error(err)
+
+	eType, err := ParseEntropyFlag("lz76")
+	r.NoError(err)
+	a.Equal(types.LZ76Complexity, eType)
+}
+
+func TestSegmentMethodParsing(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	method, err := ParseSegmentMethodFlag("")
+	r.NoError(err)
+	a.Equal(segment.PELT, method)
+
+	method, err = ParseSegmentMethodFlag("cusum")
+	r.NoError(err)
+	a.Equal(segment.CUSUM, method)
+
+	_, err = ParseSegmentMethodFlag("kmeans")
+	r.ErrorIs(err, ErrInvalidFlag)
+}
+
+func TestBitRangesParsing(t *testing.T) {
+	testCases := []struct {
+		name           string
+		offsets        string
+		lengths        string
+		expected       []types.Range
+		expectedToFail bool
+	}{
+		{
+			name: "no range",
+		}, {
+			name:     "offset to the end",
+			offsets:  "1K",
+			expected: []types.Range{{Offset: 8 * 1024}},
+		}, {
+			name:     "length from the start",
+			lengths:  "12B",
+			expected: []types.Range{{Length: 12 * 8}},
+		}, {
+			name:     "bits and bytes",
+			offsets:  "3",
+			lengths:  "2B",
+			expected: []types.Range{{Offset: 3, Length: 16}},
+		}, {
+			name:     "same length for every offset",
+			offsets:  "0,1B,2B",
+			lengths:  "4",
+			expected: []types.Range{{Offset: 0, Length: 4}, {Offset: 8, Length: 4}, {Offset: 16, Length: 4}},
+		}, {
+			name:     "length per offset",
+			offsets:  "1K, 8K",
+			lengths:  "512B, 1K",
+			expected: []types.Range{{Offset: 8 * 1024, Length: 8 * 512}, {Offset: 8 * 8 * 1024, Length: 8 * 1024}},
+		}, {
+			name:           "mismatched lengths",
+			offsets:        "0,1,2",
+			lengths:        "4,5",
+			expectedToFail: true,
+		}, {
+			name:           "bad offset",
+			offsets:        "1X",
+			expectedToFail: true,
+		}, {
+			name:           "empty offset",
+			offsets:        "1,,2",
+			expectedToFail: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			a, r := assert.New(tt), require.New(tt)
+
+			ranges, err := ParseBitRangesFlag(tc.offsets, tc.lengths)
+			if tc.expectedToFail {
+				r.ErrorIs(err, ErrInvalidFlag)
+				return
+			}
+
+			r.NoError(err)
+			a.Equal(tc.expected, ranges)
+		})
+	}
+}
+
+func TestBitOffsetsParsing(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	offsets, err := ParseBitOffsetsFlag("")
+	r.NoError(err)
+	a.Empty(offsets)
+
+	offsets, err = ParseBitOffsetsFlag("3, 2B,1K")
+	r.NoError(err)
+	a.Equal([]int{3, 16, 8 * 1024}, offsets)
+
+	_, err = ParseBitOffsetsFlag("3,x")
+	r.ErrorIs(err, ErrInvalidFlag)
+}
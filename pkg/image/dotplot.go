@@ -0,0 +1,212 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+
+	"github.com/fedemengo/d2bist/pkg/engine"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+const DefaultDotPlotSize = 1_000
+
+var (
+	dotPlotBackground = color.RGBA{255, 255, 255, 255} // White
+	dotPlotMatch      = color.RGBA{0, 0, 0, 255}       // Black
+)
+
+type dotPlotConfig struct {
+	size    int
+	step    int
+	reverse *color.RGBA
+}
+
+type DotPlotOpt func(c *dotPlotConfig)
+
+// WithDotPlotSize sets the max side of the image, when there are more blocks each pixel is a cell of blocks
+func WithDotPlotSize(size int) DotPlotOpt {
+	return func(c *dotPlotConfig) {
+		c.size = size
+	}
+}
+
+// WithDotPlotStep compares the blocks every step bits, use the block length for aligned blocks
+func WithDotPlotStep(step int) DotPlotOpt {
+	return func(c *dotPlotConfig) {
+		c.step = step
+	}
+}
+
+// WithReverseMatches lits with color the pixels where a block equals the other one reversed
+func WithReverseMatches(c color.RGBA) DotPlotOpt {
+	return func(conf *dotPlotConfig) {
+		conf.reverse = &c
+	}
+}
+
+// blockIDs returns, for the block at every step bits, the id of the block and the id of its
+// reversed block (-1 if the reversed block never occurs)
+func blockIDs(bits []types.Bit, blockLen, step int) ([]int, []int) {
+	if blockLen > len(bits) {
+		return nil, nil
+	}
+
+	n := (len(bits)-blockLen)/step + 1
+
+	byKey := map[string]int{}
+	ids := make([]int, n)
+	for i := range ids {
+		p := i * step
+		key := engine.BitsToString(bits[p : p+blockLen])
+		id, ok := byKey[key]
+		if !ok {
+			id = len(byKey)
+			byKey[key] = id
+		}
+		ids[i] = id
+	}
+
+	reversedIDs := make([]int, n)
+	reversed := make([]types.Bit, blockLen)
+	for i := range reversedIDs {
+		p := i * step
+		for j := 0; j < blockLen; j++ {
+			reversed[j] = bits[p+blockLen-1-j]
+		}
+
+		reversedIDs[i] = -1
+		if id, ok := byKey[engine.BitsToString(reversed)]; ok {
+			reversedIDs[i] = id
+		}
+	}
+
+	return ids, reversedIDs
+}
+
+// dotPlotCells is the self-similarity matrix max-pooled in cells of stride x stride blocks,
+// row y has a bit per cell x
+type dotPlotCells struct {
+	size     int
+	matches  [][]uint64
+	reverses [][]uint64
+}
+
+// at reports whether any block of cell x equals any block of cell y, or its reverse
+func (d *dotPlotCells) at(x, y int) (bool, bool) {
+	word, bit := x/64, uint64(1)<<(x%64)
+
+	return d.matches[y][word]&bit != 0, d.reverses[y][word]&bit != 0
+}
+
+// dotPlot compares every block and lits a cell when any pair of its blocks matches, so repeats
+// at any distance are drawn however the blocks are downsampled
+func dotPlot(bits []types.Bit, blockLen int, c *dotPlotConfig) *dotPlotCells {
+	ids, reversedIDs := blockIDs(bits, blockLen, c.step)
+	n := len(ids)
+	if n == 0 {
+		return &dotPlotCells{}
+	}
+
+	stride := (n + c.size - 1) / c.size
+	d := &dotPlotCells{size: (n + stride - 1) / stride}
+
+	words := (d.size + 63) / 64
+	d.matches, d.reverses = make([][]uint64, d.size), make([][]uint64, d.size)
+	for y := range d.matches {
+		d.matches[y], d.reverses[y] = make([]uint64, words), make([]uint64, words)
+	}
+
+	// the increasing cells where each id occurs, as the block itself or reversed
+	cells, reversedCells := map[int][]int{}, map[int][]int{}
+	appendCell := func(m map[int][]int, id, cell int) {
+		if l := m[id]; len(l) == 0 || l[len(l)-1] != cell {
+			m[id] = append(l, cell)
+		}
+	}
+	for i := range ids {
+		appendCell(cells, ids[i], i/stride)
+		if reversedIDs[i] >= 0 {
+			appendCell(reversedCells, reversedIDs[i], i/stride)
+		}
+	}
+
+	row := make([]uint64, words)
+	orRows := func(rows [][]uint64, ys, xs []int) {
+		for i := range row {
+			row[i] = 0
+		}
+		for _, x := range xs {
+			row[x/64] |= 1 << (x % 64)
+		}
+		for _, y := range ys {
+			for i, w := range row {
+				rows[y][i] |= w
+			}
+		}
+	}
+
+	for id, ys := range cells {
+		orRows(d.matches, ys, ys)
+		if xs, ok := reversedCells[id]; ok {
+			orRows(d.reverses, ys, xs)
+		}
+	}
+
+	return d
+}
+
+// WriteDotPlot renders the self-similarity matrix of the blocks of blockLen bits:
+// pixel (x, y) is lit when the block at position x equals the block at position y
+//
+// repeats show up as diagonals parallel to the main one, periodic structures as a grid,
+// and reversals, with WithReverseMatches, as anti-diagonals
+func WriteDotPlot(bits []types.Bit, filename string, blockLen int, opts ...DotPlotOpt) error {
+	c := &dotPlotConfig{
+		size: DefaultDotPlotSize,
+		step: 1,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if blockLen < 1 {
+		return fmt.Errorf("block length must be greater than 0")
+	}
+	if c.step < 1 {
+		return fmt.Errorf("step must be greater than 0")
+	}
+	if c.size < 1 || c.size > maxW {
+		return fmt.Errorf("size must be in [1, %d]", maxW)
+	}
+	if blockLen > len(bits) {
+		return fmt.Errorf("block length %d is greater than the %d bits", blockLen, len(bits))
+	}
+
+	d := dotPlot(bits, blockLen, c)
+
+	img := image.NewRGBA(image.Rect(0, 0, d.size, d.size))
+	for y := 0; y < d.size; y++ {
+		for x := 0; x < d.size; x++ {
+			match, reverse := d.at(x, y)
+			switch {
+			case match:
+				img.Set(x, y, dotPlotMatch)
+			case c.reverse != nil && reverse:
+				img.Set(x, y, *c.reverse)
+			default:
+				img.Set(x, y, dotPlotBackground)
+			}
+		}
+	}
+
+	f, err := os.Create(fmt.Sprintf("%s.png", filename))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return png.Encode(f, img)
+}
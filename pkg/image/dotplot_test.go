@@ -0,0 +1,98 @@
+package image
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+func TestBlockIDs(t *testing.T) {
+	testCases := []struct {
+		name             string
+		bits             []types.Bit
+		blockLen         int
+		step             int
+		expectedIDs      []int
+		expectedReversed []int
+	}{
+		{
+			name:             "every offset",
+			bits:             []types.Bit{0, 0, 1, 0, 0, 1},
+			blockLen:         2,
+			step:             1,
+			expectedIDs:      []int{0, 1, 2, 0, 1},
+			expectedReversed: []int{0, 2, 1, 0, 2},
+		}, {
+			name:             "aligned blocks",
+			bits:             []types.Bit{1, 1, 0, 0, 1, 1, 0, 1},
+			blockLen:         2,
+			step:             2,
+			expectedIDs:      []int{0, 1, 0, 2},
+			expectedReversed: []int{0, 1, 0, -1},
+		}, {
+			name:     "block longer than bits",
+			bits:     []types.Bit{0, 1},
+			blockLen: 3,
+			step:     1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			a := assert.New(tt)
+
+			ids, reversed := blockIDs(tc.bits, tc.blockLen, tc.step)
+			a.Equal(tc.expectedIDs, ids)
+			a.Equal(tc.expectedReversed, reversed)
+		})
+	}
+}
+
+func TestDotPlot(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	rnd := rand.New(rand.NewSource(1))
+	bits := make([]types.Bit, 64)
+	for i := range bits {
+		bits[i] = types.Bit(rnd.Intn(2))
+	}
+	// a repeat at a distance that is not a multiple of the stride
+	copy(bits[21:29], bits[0:8])
+
+	blockLen := 8
+	conf := &dotPlotConfig{size: 29, step: 1}
+
+	// 57 blocks in cells of 2 blocks
+	d := dotPlot(bits, blockLen, conf)
+	r.Equal(29, d.size)
+
+	match, _ := d.at(10, 0)
+	a.True(match)
+	match, _ = d.at(0, 10)
+	a.True(match)
+
+	// a cell is lit when any pair of its blocks matches
+	ids, reversedIDs := blockIDs(bits, blockLen, conf.step)
+	for y := 0; y < d.size; y++ {
+		for x := 0; x < d.size; x++ {
+			expectedMatch, expectedReverse := false, false
+			for i := 2 * x; i < 2*x+2 && i < len(ids); i++ {
+				for j := 2 * y; j < 2*y+2 && j < len(ids); j++ {
+					expectedMatch = expectedMatch || ids[i] == ids[j]
+					expectedReverse = expectedReverse || reversedIDs[i] == ids[j]
+				}
+			}
+
+			match, reverse := d.at(x, y)
+			a.Equal(expectedMatch, match, "match at %d, %d", x, y)
+			a.Equal(expectedReverse, reverse, "reverse at %d, %d", x, y)
+		}
+	}
+
+	d = dotPlot(bits[:4], blockLen, conf)
+	a.Zero(d.size)
+}
@@ -5,6 +5,7 @@ import (
 	"image"
 	"image/color"
 	"image/png"
+	"io"
 	"math"
 	"os"
 
@@ -58,6 +59,16 @@ var colorsMap = map[int]map[uint64]color.RGBA{
 	},
 }
 
+// PixelColor returns the colour of a pixel of pixelLen bits with value v
+func PixelColor(v uint64, pixelLen int) (color.RGBA, error) {
+	c, ok := colorsMap[pixelLen][v]
+	if !ok {
+		return color.RGBA{}, fmt.Errorf("no colour for value %d of a %d bits pixel", v, pixelLen)
+	}
+
+	return c, nil
+}
+
 func bitsToColors(bits []types.Bit, pixelLen int) ([]color.RGBA, error) {
 	if pixelLen < 1 {
 		return nil, fmt.Errorf("pixel length must be greater than 0")
@@ -73,7 +84,163 @@ func bitsToColors(bits []types.Bit, pixelLen int) ([]color.RGBA, error) {
 	return colors, nil
 }
 
-func WriteToPNG(bits []types.Bit, filename string, pixelLen int) error {
+type HeatmapMode string
+
+const (
+	// HeatmapOverlay tints each bit pixel with the entropy of its chunk
+	HeatmapOverlay = HeatmapMode("overlay")
+	// HeatmapSideBySide draws the entropy of each chunk on the right of the bits image
+	HeatmapSideBySide = HeatmapMode("side")
+)
+
+// heatmapWeight is how much the entropy colour covers the bits in overlay mode
+const heatmapWeight = 0.6
+
+type config struct {
+	highlights []highlight
+	overlays   []overlay
+	heatmap    *heatmap
+}
+
+type heatmap struct {
+	values   []float64
+	chunkLen int
+	mode     HeatmapMode
+}
+
+type highlight struct {
+	ranges []types.Range
+	color  color.RGBA
+}
+
+type overlay struct {
+	values []float64
+	color  color.RGBA
+}
+
+type Opt func(c *config)
+
+// WithHighlights blends the pixels of the bits in ranges with color
+func WithHighlights(ranges []types.Range, c color.RGBA) Opt {
+	return func(conf *config) {
+		conf.highlights = append(conf.highlights, highlight{ranges: ranges, color: c})
+	}
+}
+
+// WithOverlay mixes the pixel of each bit with color, proportionally to its value in [0, 1]
+func WithOverlay(values []float64, c color.RGBA) Opt {
+	return func(conf *config) {
+		conf.overlays = append(conf.overlays, overlay{values: values, color: c})
+	}
+}
+
+// WithHeatmap colours the pixels of each chunk of chunkLen bits according to its entropy in [0, 1]
+func WithHeatmap(values []float64, chunkLen int, mode HeatmapMode) Opt {
+	return func(conf *config) {
+		conf.heatmap = &heatmap{values: values, chunkLen: chunkLen, mode: mode}
+	}
+}
+
+// HeatColor maps a value in [0, 1] to a colour from blue (low) through green and yellow to red (high)
+func HeatColor(v float64) color.RGBA {
+	stops := []color.RGBA{
+		{0, 0, 255, 255},   // Blue
+		{0, 255, 0, 255},   // Green
+		{255, 255, 0, 255}, // Yellow
+		{255, 0, 0, 255},   // Red
+	}
+
+	if v <= 0 {
+		return stops[0]
+	}
+	if v >= 1 {
+		return stops[len(stops)-1]
+	}
+
+	pos := v * float64(len(stops)-1)
+	i := int(pos)
+
+	return mix(stops[i], stops[i+1], pos-float64(i))
+}
+
+// heatColors returns the entropy colour of each of the n pixels, pixel i is the window starting
+// at bit i, see bitsToColors
+func (h *heatmap) heatColors(n int) []color.RGBA {
+	colors := make([]color.RGBA, n)
+	for i := range colors {
+		chunk := i / h.chunkLen
+		if chunk >= len(h.values) {
+			chunk = len(h.values) - 1
+		}
+		colors[i] = HeatColor(h.values[chunk])
+	}
+
+	return colors
+}
+
+func mix(c1, c2 color.RGBA, w float64) color.RGBA {
+	if w < 0 {
+		w = 0
+	}
+	if w > 1 {
+		w = 1
+	}
+
+	return color.RGBA{
+		R: uint8(float64(c1.R)*(1-w) + float64(c2.R)*w),
+		G: uint8(float64(c1.G)*(1-w) + float64(c2.G)*w),
+		B: uint8(float64(c1.B)*(1-w) + float64(c2.B)*w),
+		A: 255,
+	}
+}
+
+func applyOverlays(colors []color.RGBA, overlays []overlay) {
+	for _, o := range overlays {
+		for i, v := range o.values {
+			if i >= len(colors) {
+				break
+			}
+			colors[i] = mix(colors[i], o.color, v)
+		}
+	}
+}
+
+// Blend is the colour of a highlighted pixel of colour c1, highlighted with c2
+func Blend(c1, c2 color.RGBA) color.RGBA {
+	return mix(c1, c2, 0.5)
+}
+
+func applyHighlights(colors []color.RGBA, highlights []highlight) {
+	for _, h := range highlights {
+		for _, r := range h.ranges {
+			for i := r.Offset; i < r.Offset+r.Length && i < len(colors); i++ {
+				colors[i] = Blend(colors[i], h.color)
+			}
+		}
+	}
+}
+
+func WriteToPNG(bits []types.Bit, filename string, pixelLen int, opts ...Opt) error {
+	f, err := os.Create(fmt.Sprintf("%s.png", filename))
+	if err != nil {
+		return err
+	}
+
+	if err := EncodePNG(f, bits, pixelLen, opts...); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// EncodePNG writes to w the png image of bits, with pixelLen bits per pixel
+func EncodePNG(w io.Writer, bits []types.Bit, pixelLen int, opts ...Opt) error {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
 	n := int(math.Min(math.Sqrt(float64(len(bits))), maxW))
 
 	currW, currH := n+1, n+1
@@ -86,6 +253,30 @@ func WriteToPNG(bits []types.Bit, filename string, pixelLen int) error {
 		return fmt.Errorf("error converting bits to colors: %w", err)
 	}
 
+	applyOverlays(colors, c.overlays)
+	applyHighlights(colors, c.highlights)
+
+	var heatColors []color.RGBA
+	if c.heatmap != nil {
+		if c.heatmap.chunkLen < 1 || len(c.heatmap.values) == 0 {
+			return fmt.Errorf("heatmap needs the entropy of chunks of at least 1 bit")
+		}
+
+		heatColors = c.heatmap.heatColors(len(colors))
+		switch c.heatmap.mode {
+		case HeatmapOverlay:
+			for i := range colors {
+				colors[i] = mix(colors[i], heatColors[i], heatmapWeight)
+			}
+		case HeatmapSideBySide:
+			// leave a column between the two images
+			lowRight.X = 2*currW + 1
+			img = image.NewRGBA(image.Rectangle{upLeft, lowRight})
+		default:
+			return fmt.Errorf("heatmap mode `%s` is not supported", c.heatmap.mode)
+		}
+	}
+
 	for x := 0; x < currW; x++ {
 		for y := 0; y < currH; y++ {
 			idx := y*currW + x
@@ -94,13 +285,11 @@ func WriteToPNG(bits []types.Bit, filename string, pixelLen int) error {
 			}
 
 			img.Set(x, y, colors[idx])
+			if c.heatmap != nil && c.heatmap.mode == HeatmapSideBySide {
+				img.Set(currW+1+x, y, heatColors[idx])
+			}
 		}
 	}
 
-	f, err := os.Create(fmt.Sprintf("%s.png", filename))
-	if err != nil {
-		return err
-	}
-
-	return png.Encode(f, img)
+	return png.Encode(w, img)
 }
@@ -0,0 +1,68 @@
+package image
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeatColor(t *testing.T) {
+	testCases := []struct {
+		name          string
+		value         float64
+		expectedColor color.RGBA
+	}{
+		{name: "below range", value: -1, expectedColor: color.RGBA{0, 0, 255, 255}},
+		{name: "zero", value: 0, expectedColor: color.RGBA{0, 0, 255, 255}},
+		{name: "first stop", value: 1.0 / 3, expectedColor: color.RGBA{0, 255, 0, 255}},
+		{name: "between stops", value: 0.5, expectedColor: color.RGBA{127, 255, 0, 255}},
+		{name: "one", value: 1, expectedColor: color.RGBA{255, 0, 0, 255}},
+		{name: "above range", value: 2, expectedColor: color.RGBA{255, 0, 0, 255}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			assert.Equal(tt, tc.expectedColor, HeatColor(tc.value))
+		})
+	}
+}
+
+func TestHeatmapColors(t *testing.T) {
+	testCases := []struct {
+		name           string
+		heatmap        heatmap
+		pixels         int
+		expectedColors []color.RGBA
+	}{
+		{
+			name:    "pixel per bit",
+			heatmap: heatmap{values: []float64{0, 1}, chunkLen: 2},
+			pixels:  4,
+			expectedColors: []color.RGBA{
+				HeatColor(0), HeatColor(0), HeatColor(1), HeatColor(1),
+			},
+		}, {
+			name:    "pixel per bit of wide chunks",
+			heatmap: heatmap{values: []float64{0, 1}, chunkLen: 4},
+			pixels:  8,
+			expectedColors: []color.RGBA{
+				HeatColor(0), HeatColor(0), HeatColor(0), HeatColor(0),
+				HeatColor(1), HeatColor(1), HeatColor(1), HeatColor(1),
+			},
+		}, {
+			name:    "pixels past the last chunk",
+			heatmap: heatmap{values: []float64{1}, chunkLen: 2},
+			pixels:  3,
+			expectedColors: []color.RGBA{
+				HeatColor(1), HeatColor(1), HeatColor(1),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			assert.Equal(tt, tc.expectedColors, tc.heatmap.heatColors(tc.pixels))
+		})
+	}
+}
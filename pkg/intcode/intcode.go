@@ -0,0 +1,487 @@
+package intcode
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/fedemengo/d2bist/pkg/engine"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+var (
+	ErrNotEncodable = errors.New("value cannot be encoded")
+	ErrTruncated    = errors.New("truncated codeword")
+	ErrInvalidParam = errors.New("invalid code parameter")
+)
+
+type Code string
+
+const (
+	Unary       = Code("Unary")
+	Gamma       = Code("Gamma")
+	Delta       = Code("Delta")
+	Omega       = Code("Omega")
+	Fibonacci   = Code("Fibonacci")
+	Golomb      = Code("Golomb")
+	Rice        = Code("Rice")
+	Levenshtein = Code("Levenshtein")
+)
+
+const defaultParam = 4
+
+type config struct {
+	m uint64
+}
+
+type Opt func(c *config)
+
+// WithParameter sets the divisor m of Golomb and Rice codes, Rice requires a power of 2
+func WithParameter(m uint64) Opt {
+	return func(c *config) {
+		c.m = m
+	}
+}
+
+func newConfig(code Code, opts ...Opt) (*config, error) {
+	c := &config{
+		m: defaultParam,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if (code == Golomb || code == Rice) && c.m == 0 {
+		return nil, fmt.Errorf("parameter must be greater than 0: %w", ErrInvalidParam)
+	}
+	if code == Rice && c.m&(c.m-1) != 0 {
+		return nil, fmt.Errorf("rice parameter %d is not a power of 2: %w", c.m, ErrInvalidParam)
+	}
+
+	return c, nil
+}
+
+// Encode concatenates the codewords of values
+func Encode(values []uint64, code Code, opts ...Opt) ([]types.Bit, error) {
+	c, err := newConfig(code, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var enc func(*bitWriter, uint64) error
+	switch code {
+	case Unary:
+		enc = encodeUnary
+	case Gamma:
+		enc = encodeGamma
+	case Delta:
+		enc = encodeDelta
+	case Omega:
+		enc = encodeOmega
+	case Fibonacci:
+		enc = encodeFibonacci
+	case Golomb, Rice:
+		enc = func(w *bitWriter, n uint64) error {
+			return encodeGolomb(w, n, c.m)
+		}
+	case Levenshtein:
+		enc = encodeLevenshtein
+	default:
+		return nil, fmt.Errorf("integer code %s not supported", code)
+	}
+
+	w := &bitWriter{}
+	for i, n := range values {
+		if err := enc(w, n); err != nil {
+			return nil, fmt.Errorf("cannot encode value %d at index %d: %w", n, i, err)
+		}
+	}
+
+	return w.bits, nil
+}
+
+// Decode reads codewords until the bits are exhausted
+//
+// when the last codeword is incomplete, the values decoded so far are returned with ErrTruncated
+func Decode(bits []types.Bit, code Code, opts ...Opt) ([]uint64, error) {
+	c, err := newConfig(code, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var dec func(*bitReader) (uint64, error)
+	switch code {
+	case Unary:
+		dec = decodeUnary
+	case Gamma:
+		dec = decodeGamma
+	case Delta:
+		dec = decodeDelta
+	case Omega:
+		dec = decodeOmega
+	case Fibonacci:
+		dec = decodeFibonacci
+	case Golomb, Rice:
+		dec = func(r *bitReader) (uint64, error) {
+			return decodeGolomb(r, c.m)
+		}
+	case Levenshtein:
+		dec = decodeLevenshtein
+	default:
+		return nil, fmt.Errorf("integer code %s not supported", code)
+	}
+
+	values := []uint64{}
+	r := &bitReader{bits: bits}
+	for r.pos < len(bits) {
+		start := r.pos
+		n, err := dec(r)
+		if err != nil {
+			return values, fmt.Errorf("cannot decode codeword at offset %d: %w", start, err)
+		}
+		values = append(values, n)
+	}
+
+	return values, nil
+}
+
+type bitWriter struct {
+	bits []types.Bit
+}
+
+func (w *bitWriter) writeBit(b types.Bit) {
+	w.bits = append(w.bits, b)
+}
+
+func (w *bitWriter) writeN(b types.Bit, n uint64) {
+	for i := uint64(0); i < n; i++ {
+		w.bits = append(w.bits, b)
+	}
+}
+
+// writeInt writes the width least significant bits of n
+func (w *bitWriter) writeInt(n uint64, width int) {
+	if width == 0 {
+		return
+	}
+	n &= ^uint64(0) >> (64 - width)
+	b, _ := engine.IntToBits(n, width)
+	w.bits = append(w.bits, b...)
+}
+
+type bitReader struct {
+	bits []types.Bit
+	pos  int
+}
+
+func (r *bitReader) readBit() (types.Bit, error) {
+	if r.pos >= len(r.bits) {
+		return 0, ErrTruncated
+	}
+	b := r.bits[r.pos]
+	r.pos++
+
+	return b, nil
+}
+
+func (r *bitReader) readInt(width int) (uint64, error) {
+	if width > 64 {
+		return 0, fmt.Errorf("%d bits value overflows: %w", width, ErrNotEncodable)
+	}
+	if r.pos+width > len(r.bits) {
+		return 0, ErrTruncated
+	}
+	n, err := engine.BitsToInt(r.bits[r.pos : r.pos+width])
+	r.pos += width
+
+	return n, err
+}
+
+// countOnes reads bits up to the first 0, and returns the number of 1s
+func (r *bitReader) countOnes() (uint64, error) {
+	n := uint64(0)
+	for {
+		b, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if b == 0 {
+			return n, nil
+		}
+		n++
+	}
+}
+
+// unary: n 1s followed by a 0
+func encodeUnary(w *bitWriter, n uint64) error {
+	w.writeN(1, n)
+	w.writeBit(0)
+
+	return nil
+}
+
+func decodeUnary(r *bitReader) (uint64, error) {
+	return r.countOnes()
+}
+
+// gamma: L-1 0s followed by the L bits of n
+func encodeGamma(w *bitWriter, n uint64) error {
+	if n == 0 {
+		return fmt.Errorf("gamma cannot encode 0: %w", ErrNotEncodable)
+	}
+	l := bits.Len64(n)
+	w.writeN(0, uint64(l-1))
+	w.writeInt(n, l)
+
+	return nil
+}
+
+func decodeGamma(r *bitReader) (uint64, error) {
+	zeros := 0
+	for {
+		b, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if b == 1 {
+			break
+		}
+		zeros++
+	}
+
+	tail, err := r.readInt(zeros)
+	if err != nil {
+		return 0, err
+	}
+	if zeros == 64 {
+		return 0, fmt.Errorf("%d bits value overflows: %w", zeros+1, ErrNotEncodable)
+	}
+
+	return 1<<zeros | tail, nil
+}
+
+// delta: gamma code of L followed by the L-1 bits of n without the leading 1
+func encodeDelta(w *bitWriter, n uint64) error {
+	if n == 0 {
+		return fmt.Errorf("delta cannot encode 0: %w", ErrNotEncodable)
+	}
+	l := bits.Len64(n)
+	if err := encodeGamma(w, uint64(l)); err != nil {
+		return err
+	}
+	w.writeInt(n, l-1)
+
+	return nil
+}
+
+func decodeDelta(r *bitReader) (uint64, error) {
+	l, err := decodeGamma(r)
+	if err != nil {
+		return 0, err
+	}
+	if l > 64 {
+		return 0, fmt.Errorf("%d bits value overflows: %w", l, ErrNotEncodable)
+	}
+
+	tail, err := r.readInt(int(l - 1))
+	if err != nil {
+		return 0, err
+	}
+
+	return 1<<(l-1) | tail, nil
+}
+
+// omega: recursively prepend the binary of n, n = L-1 until n = 1, terminated by a 0
+func encodeOmega(w *bitWriter, n uint64) error {
+	if n == 0 {
+		return fmt.Errorf("omega cannot encode 0: %w", ErrNotEncodable)
+	}
+
+	groups := [][]types.Bit{}
+	for n > 1 {
+		l := bits.Len64(n)
+		g, _ := engine.IntToBits(n, l)
+		groups = append(groups, g)
+		n = uint64(l - 1)
+	}
+
+	for i := len(groups) - 1; i >= 0; i-- {
+		w.bits = append(w.bits, groups[i]...)
+	}
+	w.writeBit(0)
+
+	return nil
+}
+
+func decodeOmega(r *bitReader) (uint64, error) {
+	n := uint64(1)
+	for {
+		b, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if b == 0 {
+			return n, nil
+		}
+		if n >= 64 {
+			return 0, fmt.Errorf("%d bits value overflows: %w", n+1, ErrNotEncodable)
+		}
+
+		tail, err := r.readInt(int(n))
+		if err != nil {
+			return 0, err
+		}
+		n = 1<<n | tail
+	}
+}
+
+// fibonacci: Zeckendorf representation from F(2) upward, terminated by an additional 1
+func encodeFibonacci(w *bitWriter, n uint64) error {
+	if n == 0 {
+		return fmt.Errorf("fibonacci cannot encode 0: %w", ErrNotEncodable)
+	}
+
+	fibs := []uint64{1, 2}
+	for fibs[len(fibs)-1] <= n-fibs[len(fibs)-2] {
+		fibs = append(fibs, fibs[len(fibs)-1]+fibs[len(fibs)-2])
+	}
+
+	top := len(fibs) - 1
+	for fibs[top] > n {
+		top--
+	}
+
+	code := make([]types.Bit, top+2)
+	for i := top; i >= 0; i-- {
+		if fibs[i] <= n {
+			code[i] = 1
+			n -= fibs[i]
+		}
+	}
+	code[top+1] = 1
+	w.bits = append(w.bits, code...)
+
+	return nil
+}
+
+func decodeFibonacci(r *bitReader) (uint64, error) {
+	n := uint64(0)
+	prev := types.Bit(0)
+	a, b := uint64(1), uint64(2)
+	for {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if bit == 1 && prev == 1 {
+			return n, nil
+		}
+		if bit == 1 {
+			if n+a < n {
+				return 0, fmt.Errorf("fibonacci value overflows: %w", ErrNotEncodable)
+			}
+			n += a
+		}
+		prev = bit
+		a, b = b, a+b
+	}
+}
+
+// golomb: unary quotient n/m followed by the truncated binary remainder n%m
+func encodeGolomb(w *bitWriter, n, m uint64) error {
+	q, rem := n/m, n%m
+	if err := encodeUnary(w, q); err != nil {
+		return err
+	}
+
+	b := bits.Len64(m - 1)
+	cutoff := uint64(1)<<b - m
+	if rem < cutoff {
+		w.writeInt(rem, b-1)
+	} else {
+		w.writeInt(rem+cutoff, b)
+	}
+
+	return nil
+}
+
+func decodeGolomb(r *bitReader, m uint64) (uint64, error) {
+	q, err := decodeUnary(r)
+	if err != nil {
+		return 0, err
+	}
+
+	b := bits.Len64(m - 1)
+	cutoff := uint64(1)<<b - m
+
+	rem := uint64(0)
+	if b > 0 {
+		rem, err = r.readInt(b - 1)
+		if err != nil {
+			return 0, err
+		}
+		if rem >= cutoff {
+			bit, err := r.readBit()
+			if err != nil {
+				return 0, err
+			}
+			rem = (rem<<1 | uint64(bit)) - cutoff
+		}
+	}
+
+	return q*m + rem, nil
+}
+
+// levenshtein: C 1s and a 0, followed by the recursive binary of n without leading 1s
+func encodeLevenshtein(w *bitWriter, n uint64) error {
+	if n == 0 {
+		w.writeBit(0)
+		return nil
+	}
+
+	c := uint64(1)
+	groups := [][]types.Bit{}
+	for {
+		m := bits.Len64(n) - 1
+		g, _ := engine.IntToBits(n, m+1)
+		groups = append(groups, g[1:])
+		if m == 0 {
+			break
+		}
+		c++
+		n = uint64(m)
+	}
+
+	w.writeN(1, c)
+	w.writeBit(0)
+	for i := len(groups) - 1; i >= 0; i-- {
+		w.bits = append(w.bits, groups[i]...)
+	}
+
+	return nil
+}
+
+func decodeLevenshtein(r *bitReader) (uint64, error) {
+	c, err := r.countOnes()
+	if err != nil {
+		return 0, err
+	}
+	if c == 0 {
+		return 0, nil
+	}
+
+	n := uint64(1)
+	for i := uint64(1); i < c; i++ {
+		if n >= 64 {
+			return 0, fmt.Errorf("%d bits value overflows: %w", n+1, ErrNotEncodable)
+		}
+		tail, err := r.readInt(int(n))
+		if err != nil {
+			return 0, err
+		}
+		n = 1<<n | tail
+	}
+
+	return n, nil
+}
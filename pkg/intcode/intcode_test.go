@@ -0,0 +1,123 @@
+package intcode
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fedemengo/d2bist/pkg/engine"
+)
+
+func TestCodewords(t *testing.T) {
+	testCases := []struct {
+		name      string
+		code      Code
+		param     uint64
+		values    []uint64
+		codewords string
+	}{
+		{
+			name:      "unary",
+			code:      Unary,
+			values:    []uint64{0, 1, 3},
+			codewords: "0" + "10" + "1110",
+		}, {
+			name:      "gamma",
+			code:      Gamma,
+			values:    []uint64{1, 2, 5},
+			codewords: "1" + "010" + "00101",
+		}, {
+			name:      "delta",
+			code:      Delta,
+			values:    []uint64{1, 2, 10},
+			codewords: "1" + "0100" + "00100010",
+		}, {
+			name:      "omega",
+			code:      Omega,
+			values:    []uint64{1, 2, 4, 17},
+			codewords: "0" + "100" + "101000" + "10100100010",
+		}, {
+			name:      "fibonacci",
+			code:      Fibonacci,
+			values:    []uint64{1, 2, 4, 11},
+			codewords: "11" + "011" + "1011" + "001011",
+		}, {
+			name:      "golomb",
+			code:      Golomb,
+			param:     3,
+			values:    []uint64{0, 1, 2, 7},
+			codewords: "00" + "010" + "011" + "11010",
+		}, {
+			name:      "rice",
+			code:      Rice,
+			param:     4,
+			values:    []uint64{0, 5, 9},
+			codewords: "000" + "1001" + "11001",
+		}, {
+			name:      "levenshtein",
+			code:      Levenshtein,
+			values:    []uint64{0, 1, 2, 5, 16},
+			codewords: "0" + "10" + "1100" + "1110001" + "111100000000",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			a, r := assert.New(tt), require.New(tt)
+
+			opts := []Opt{}
+			if tc.param > 0 {
+				opts = append(opts, WithParameter(tc.param))
+			}
+
+			bits, err := Encode(tc.values, tc.code, opts...)
+			r.NoError(err)
+			a.Equal(tc.codewords, engine.BitsToString(bits))
+
+			values, err := Decode(bits, tc.code, opts...)
+			r.NoError(err)
+			a.Equal(tc.values, values)
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	values := make([]uint64, 1000)
+	for i := range values {
+		values[i] = uint64(rng.Int63n(1<<uint(rng.Intn(40)))) + 1
+	}
+	values = append(values, 1<<63, 1<<64-1)
+
+	for _, code := range []Code{Gamma, Delta, Omega, Fibonacci, Levenshtein} {
+		t.Run(string(code), func(tt *testing.T) {
+			a, r := assert.New(tt), require.New(tt)
+
+			bits, err := Encode(values, code)
+			r.NoError(err)
+
+			decoded, err := Decode(bits, code)
+			r.NoError(err)
+			a.Equal(values, decoded)
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	r := require.New(t)
+
+	_, err := Encode([]uint64{0}, Gamma)
+	r.ErrorIs(err, ErrNotEncodable)
+
+	_, err = Encode([]uint64{1}, Rice, WithParameter(3))
+	r.ErrorIs(err, ErrInvalidParam)
+
+	bits, err := Encode([]uint64{5, 6}, Gamma)
+	r.NoError(err)
+
+	values, err := Decode(bits[:len(bits)-1], Gamma)
+	r.ErrorIs(err, ErrTruncated)
+	r.Equal([]uint64{5}, values)
+}
@@ -153,7 +153,7 @@ func BitsFromReader(ctx context.Context, r io.Reader, opts ...opt) ([]types.Bit,
 	return bits, nil
 }
 
-func BitsToReader(ctx context.Context, bits []types.Bit, compType compression.CompressionType) (ReaderWithSize, error) {
+func BitsToReader(ctx context.Context, bits []types.Bit, compType compression.CompressionType, opts ...compression.Opt) (ReaderWithSize, error) {
 	log := zerolog.Ctx(ctx).
 		With().
 		Str("compression", string(compType)).
@@ -162,7 +162,9 @@ func BitsToReader(ctx context.Context, bits []types.Bit, compType compression.Co
 	log.Trace().
 		Msg("creating writer with compression")
 	buf := new(bytes.Buffer)
-	cw, err := compression.NewCompressedWriter(ctx, buf, compType)
+	// the size hint comes first, opts can override it
+	opts = append([]compression.Opt{compression.WithSizeHint((len(bits) + 7) / 8)}, opts...)
+	cw, err := compression.NewCompressedWriter(ctx, buf, compType, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("cannot get compressed writer: %w", err)
 	}
@@ -186,3 +188,87 @@ func BitsToReader(ctx context.Context, bits []types.Bit, compType compression.Co
 
 	return NewReaderWithSize(cr, buf.Len()), nil
 }
+
+// BitsFromByteReaderRanges returns the bits of the ranges of r, one after the other, a range of
+// length 0 ends with the data
+//
+// when r can seek, as files can, only the bytes of the ranges are read, otherwise r is read up to the
+// end of the last range
+func BitsFromByteReaderRanges(ctx context.Context, r io.Reader, ranges []types.Range) ([]types.Bit, error) {
+	log := zerolog.Ctx(ctx)
+
+	rs, ok := r.(io.ReadSeeker)
+	var start int64
+	if ok {
+		// pipes are files too, but cannot seek
+		var err error
+		if start, err = rs.Seek(0, io.SeekCurrent); err != nil {
+			ok = false
+		}
+	}
+
+	if !ok {
+		log.Trace().Msg("reader cannot seek, reading the ranges")
+
+		bits, err := BitsFromByteReaderWithCap(ctx, r, RangesEnd(ranges))
+		if err != nil {
+			return nil, err
+		}
+
+		return SelectRanges(bits, ranges), nil
+	}
+
+	bits := []types.Bit{}
+	for _, rg := range ranges {
+		if _, err := rs.Seek(start+int64(rg.Offset/8), io.SeekStart); err != nil {
+			return nil, fmt.Errorf("cannot seek to offset %d: %w", rg.Offset, err)
+		}
+
+		maxBits := -1
+		if rg.Length > 0 {
+			maxBits = rg.Offset%8 + rg.Length
+		}
+
+		rangeBits, err := BitsFromByteReaderWithCap(ctx, rs, maxBits)
+		if err != nil {
+			return nil, err
+		}
+		if shift := rg.Offset % 8; shift < len(rangeBits) {
+			bits = append(bits, rangeBits[shift:]...)
+		}
+
+		log.Trace().Int("offset", rg.Offset).Int("length", rg.Length).Msg("range read")
+	}
+
+	return bits, nil
+}
+
+// SelectRanges returns the bits of the ranges, one after the other, a range of length 0
+// ends with the bits
+func SelectRanges(bits []types.Bit, ranges []types.Range) []types.Bit {
+	selected := []types.Bit{}
+	for _, rg := range ranges {
+		start, end := min(rg.Offset, len(bits)), len(bits)
+		if rg.Length > 0 {
+			end = min(rg.Offset+rg.Length, len(bits))
+		}
+		selected = append(selected, bits[start:end]...)
+	}
+
+	return selected
+}
+
+// RangesEnd returns the bit after the last range, or -1 when a range ends with the data
+func RangesEnd(ranges []types.Range) int {
+	end := 0
+	for _, rg := range ranges {
+		if rg.Length <= 0 {
+			return -1
+		}
+		if rg.Offset+rg.Length > end {
+			end = rg.Offset + rg.Length
+		}
+	}
+
+	return end
+}
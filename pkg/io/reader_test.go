@@ -3,6 +3,7 @@ package io
 import (
 	"bytes"
 	"context"
+	"io"
 	"strings"
 	"testing"
 
@@ -122,3 +123,85 @@ func TestBitStringToBit(t *testing.T) {
 	}
 
 }
+
+// countingReader counts the bytes read
+type countingReader struct {
+	*bytes.Reader
+	read int
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.Reader.Read(b)
+	r.read += n
+	return n, err
+}
+
+func TestBitsFromByteReaderRanges(t *testing.T) {
+	data := []byte(strings.Repeat("0123456789abcdef", 64))
+
+	allBits, err := BitsFromByteReader(context.Background(), bytes.NewReader(data))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		ranges []types.Range
+	}{
+		{
+			name:   "byte aligned",
+			ranges: []types.Range{{Offset: 80, Length: 16}},
+		}, {
+			name:   "bit offset",
+			ranges: []types.Range{{Offset: 83, Length: 13}},
+		}, {
+			name:   "to the end",
+			ranges: []types.Range{{Offset: 8000}},
+		}, {
+			name:   "several ranges",
+			ranges: []types.Range{{Offset: 800, Length: 64}, {Offset: 3, Length: 5}, {Offset: 801, Length: 7}},
+		}, {
+			name:   "past the end",
+			ranges: []types.Range{{Offset: len(allBits) - 4, Length: 16}, {Offset: len(allBits) + 8, Length: 16}},
+		},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			a, r := assert.New(tt), require.New(tt)
+
+			expected := SelectRanges(allBits, tc.ranges)
+
+			seeker := &countingReader{Reader: bytes.NewReader(data)}
+			bits, err := BitsFromByteReaderRanges(ctx, seeker, tc.ranges)
+			r.NoError(err)
+			a.Equal(expected, bits)
+
+			// readers that cannot seek read up to the end of the ranges
+			bits, err = BitsFromByteReaderRanges(ctx, struct{ io.Reader }{bytes.NewReader(data)}, tc.ranges)
+			r.NoError(err)
+			a.Equal(expected, bits)
+		})
+	}
+}
+
+func TestBitsFromByteReaderRangesSeeks(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	data := make([]byte, 1<<20)
+	data[1<<19] = 0xff
+
+	seeker := &countingReader{Reader: bytes.NewReader(data)}
+	bits, err := BitsFromByteReaderRanges(context.Background(), seeker, []types.Range{{Offset: 8<<19 - 4, Length: 8}})
+	r.NoError(err)
+	a.Equal([]types.Bit{0, 0, 0, 0, 1, 1, 1, 1}, bits)
+	a.Less(seeker.read, 64)
+}
+
+func TestSelectRanges(t *testing.T) {
+	bits := []types.Bit{0, 1, 1, 0, 1, 0, 0, 1}
+
+	assert.Equal(t, []types.Bit{1, 0, 1, 0, 1}, SelectRanges(bits, []types.Range{{Offset: 2, Length: 3}, {Offset: 6}}))
+	assert.Equal(t, []types.Bit{}, SelectRanges(bits, []types.Range{{Offset: 10, Length: 3}}))
+	assert.Equal(t, 9, RangesEnd([]types.Range{{Offset: 2, Length: 3}, {Offset: 6, Length: 3}}))
+	assert.Equal(t, -1, RangesEnd([]types.Range{{Offset: 2, Length: 3}, {Offset: 6}}))
+}
@@ -0,0 +1,273 @@
+package linecode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog"
+
+	"github.com/fedemengo/d2bist/pkg/engine"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+var ErrCodeViolation = errors.New("line code violation")
+
+type Code string
+
+const (
+	None = Code("None")
+	// NRZI encodes a 1 as a level transition and a 0 as no transition
+	NRZI = Code("NRZI")
+	// ManchesterIEEE (IEEE 802.3) encodes a 0 as 10 and a 1 as 01
+	ManchesterIEEE = Code("ManchesterIEEE")
+	// ManchesterThomas (G.E. Thomas) encodes a 0 as 01 and a 1 as 10
+	ManchesterThomas = Code("ManchesterThomas")
+	// DiffManchester always transitions mid-bit, a 0 also transitions at the start of the bit
+	DiffManchester = Code("DiffManchester")
+	// HDLC stuffs a 0 after five consecutive 1s
+	HDLC = Code("HDLC")
+	// USB stuffs a 0 after six consecutive 1s
+	USB = Code("USB")
+)
+
+// Violation is an invalid symbol found while decoding, Offset is the position in the input bits
+type Violation struct {
+	Offset int
+	Bits   string
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("invalid symbol `%s` at offset %d", v.Bits, v.Offset)
+}
+
+// Decode removes the line code from bits, invalid symbols are skipped and reported as violations
+func Decode(ctx context.Context, bits []types.Bit, code Code) ([]types.Bit, []Violation, error) {
+	log := zerolog.Ctx(ctx)
+
+	var out []types.Bit
+	var violations []Violation
+
+	switch code {
+	case None:
+		log.Trace().Msg("no line code")
+		return bits, nil, nil
+	case NRZI:
+		log.Trace().Msg("nrzi decoding")
+		out = decodeNRZI(bits)
+	case ManchesterIEEE:
+		log.Trace().Msg("ieee manchester decoding")
+		out, violations = decodeManchester(bits, 1)
+	case ManchesterThomas:
+		log.Trace().Msg("thomas manchester decoding")
+		out, violations = decodeManchester(bits, 0)
+	case DiffManchester:
+		log.Trace().Msg("differential manchester decoding")
+		out, violations = decodeDiffManchester(bits)
+	case HDLC:
+		log.Trace().Msg("hdlc bit destuffing")
+		out, violations = destuff(bits, 5)
+	case USB:
+		log.Trace().Msg("usb bit destuffing")
+		out, violations = destuff(bits, 6)
+	default:
+		return nil, nil, fmt.Errorf("line code %s not supported", code)
+	}
+
+	for _, v := range violations {
+		log.Warn().Err(ErrCodeViolation).Msg(v.Error())
+	}
+
+	return out, violations, nil
+}
+
+// Encode applies the line code to bits
+func Encode(ctx context.Context, bits []types.Bit, code Code) ([]types.Bit, error) {
+	log := zerolog.Ctx(ctx)
+
+	switch code {
+	case None:
+		log.Trace().Msg("no line code")
+		return bits, nil
+	case NRZI:
+		log.Trace().Msg("nrzi encoding")
+		return encodeNRZI(bits), nil
+	case ManchesterIEEE:
+		log.Trace().Msg("ieee manchester encoding")
+		return encodeManchester(bits, 1), nil
+	case ManchesterThomas:
+		log.Trace().Msg("thomas manchester encoding")
+		return encodeManchester(bits, 0), nil
+	case DiffManchester:
+		log.Trace().Msg("differential manchester encoding")
+		return encodeDiffManchester(bits), nil
+	case HDLC:
+		log.Trace().Msg("hdlc bit stuffing")
+		return stuff(bits, 5), nil
+	case USB:
+		log.Trace().Msg("usb bit stuffing")
+		return stuff(bits, 6), nil
+	default:
+		return nil, fmt.Errorf("line code %s not supported", code)
+	}
+}
+
+// the line level is assumed to be 0 before the first bit
+func decodeNRZI(bits []types.Bit) []types.Bit {
+	out := make([]types.Bit, len(bits))
+	level := types.Bit(0)
+	for i, b := range bits {
+		out[i] = b ^ level
+		level = b
+	}
+
+	return out
+}
+
+func encodeNRZI(bits []types.Bit) []types.Bit {
+	out := make([]types.Bit, len(bits))
+	level := types.Bit(0)
+	for i, b := range bits {
+		level ^= b
+		out[i] = level
+	}
+
+	return out
+}
+
+// decodeManchester maps the pair 01 to one and 10 to 1-one
+func decodeManchester(bits []types.Bit, one types.Bit) ([]types.Bit, []Violation) {
+	out := make([]types.Bit, 0, len(bits)/2)
+	var violations []Violation
+	for i := 0; i+1 < len(bits); i += 2 {
+		a, b := bits[i], bits[i+1]
+		if a == b {
+			violations = append(violations, Violation{Offset: i, Bits: engine.BitsToString(bits[i : i+2])})
+			continue
+		}
+
+		if a == 0 {
+			out = append(out, one)
+		} else {
+			out = append(out, 1-one)
+		}
+	}
+
+	if len(bits)%2 != 0 {
+		violations = append(violations, Violation{Offset: len(bits) - 1, Bits: engine.BitsToString(bits[len(bits)-1:])})
+	}
+
+	return out, violations
+}
+
+func encodeManchester(bits []types.Bit, one types.Bit) []types.Bit {
+	out := make([]types.Bit, 0, 2*len(bits))
+	for _, b := range bits {
+		if b == one {
+			out = append(out, 0, 1)
+		} else {
+			out = append(out, 1, 0)
+		}
+	}
+
+	return out
+}
+
+// decodeDiffManchester reads pairs of half-bits, the line level is assumed to be 0 before the first pair
+//
+// a pair without the mid-bit transition is a violation
+func decodeDiffManchester(bits []types.Bit) ([]types.Bit, []Violation) {
+	out := make([]types.Bit, 0, len(bits)/2)
+	var violations []Violation
+	level := types.Bit(0)
+	for i := 0; i+1 < len(bits); i += 2 {
+		a, b := bits[i], bits[i+1]
+		if a == b {
+			violations = append(violations, Violation{Offset: i, Bits: engine.BitsToString(bits[i : i+2])})
+			level = b
+			continue
+		}
+
+		if a == level {
+			out = append(out, 1)
+		} else {
+			out = append(out, 0)
+		}
+		level = b
+	}
+
+	if len(bits)%2 != 0 {
+		violations = append(violations, Violation{Offset: len(bits) - 1, Bits: engine.BitsToString(bits[len(bits)-1:])})
+	}
+
+	return out, violations
+}
+
+func encodeDiffManchester(bits []types.Bit) []types.Bit {
+	out := make([]types.Bit, 0, 2*len(bits))
+	level := types.Bit(0)
+	for _, b := range bits {
+		first := level
+		if b == 0 {
+			first = 1 - level
+		}
+		level = 1 - first
+		out = append(out, first, level)
+	}
+
+	return out
+}
+
+// destuff removes the 0 following run consecutive 1s, a 1 in its place is a violation
+func destuff(bits []types.Bit, run int) ([]types.Bit, []Violation) {
+	out := make([]types.Bit, 0, len(bits))
+	var violations []Violation
+	ones := 0
+	for i := 0; i < len(bits); i++ {
+		b := bits[i]
+		out = append(out, b)
+		if b == 0 {
+			ones = 0
+			continue
+		}
+
+		ones++
+		if ones < run {
+			continue
+		}
+
+		ones = 0
+		if i+1 >= len(bits) {
+			break
+		}
+
+		i++
+		if bits[i] != 0 {
+			violations = append(violations, Violation{Offset: i - run, Bits: engine.BitsToString(bits[i-run : i+1])})
+			out = append(out, bits[i])
+			ones = 1
+		}
+	}
+
+	return out, violations
+}
+
+func stuff(bits []types.Bit, run int) []types.Bit {
+	out := make([]types.Bit, 0, len(bits)+len(bits)/run)
+	ones := 0
+	for _, b := range bits {
+		out = append(out, b)
+		if b == 0 {
+			ones = 0
+			continue
+		}
+
+		ones++
+		if ones == run {
+			out = append(out, 0)
+			ones = 0
+		}
+	}
+
+	return out
+}
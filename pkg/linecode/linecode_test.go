@@ -0,0 +1,127 @@
+package linecode
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	bits := []types.Bit{1, 1, 1, 1, 1, 1, 1, 0, 0, 1, 0, 1, 1, 1, 1, 1, 0, 0, 0, 1}
+
+	testCases := []struct {
+		name        string
+		code        Code
+		bits        []types.Bit
+		encodedBits []types.Bit
+	}{
+		{
+			name:        "nrzi",
+			code:        NRZI,
+			bits:        []types.Bit{1, 0, 1, 1, 0},
+			encodedBits: []types.Bit{1, 1, 0, 1, 1},
+		}, {
+			name:        "manchester ieee",
+			code:        ManchesterIEEE,
+			bits:        []types.Bit{1, 0, 0},
+			encodedBits: []types.Bit{0, 1, 1, 0, 1, 0},
+		}, {
+			name:        "manchester thomas",
+			code:        ManchesterThomas,
+			bits:        []types.Bit{1, 0, 0},
+			encodedBits: []types.Bit{1, 0, 0, 1, 0, 1},
+		}, {
+			name:        "differential manchester",
+			code:        DiffManchester,
+			bits:        []types.Bit{0, 1, 1, 0},
+			encodedBits: []types.Bit{1, 0, 0, 1, 1, 0, 1, 0},
+		}, {
+			name:        "hdlc",
+			code:        HDLC,
+			bits:        []types.Bit{0, 1, 1, 1, 1, 1, 1, 0},
+			encodedBits: []types.Bit{0, 1, 1, 1, 1, 1, 0, 1, 0},
+		}, {
+			name:        "usb",
+			code:        USB,
+			bits:        []types.Bit{1, 1, 1, 1, 1, 1, 0},
+			encodedBits: []types.Bit{1, 1, 1, 1, 1, 1, 0, 0},
+		},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			a, r := assert.New(tt), require.New(tt)
+
+			encoded, err := Encode(ctx, tc.bits, tc.code)
+			r.NoError(err)
+			a.Equal(tc.encodedBits, encoded)
+
+			decoded, violations, err := Decode(ctx, encoded, tc.code)
+			r.NoError(err)
+			a.Empty(violations)
+			a.Equal(tc.bits, decoded)
+
+			encoded, err = Encode(ctx, bits, tc.code)
+			r.NoError(err)
+			decoded, violations, err = Decode(ctx, encoded, tc.code)
+			r.NoError(err)
+			a.Empty(violations)
+			a.Equal(bits, decoded)
+		})
+	}
+}
+
+func TestViolations(t *testing.T) {
+	testCases := []struct {
+		name               string
+		code               Code
+		bits               []types.Bit
+		expectedBits       []types.Bit
+		expectedViolations []Violation
+	}{
+		{
+			name:         "manchester invalid pairs",
+			code:         ManchesterIEEE,
+			bits:         []types.Bit{0, 1, 1, 1, 1, 0, 0, 0, 1},
+			expectedBits: []types.Bit{1, 0},
+			expectedViolations: []Violation{
+				{Offset: 2, Bits: "11"},
+				{Offset: 6, Bits: "00"},
+				{Offset: 8, Bits: "1"},
+			},
+		}, {
+			name:         "differential manchester missing transition",
+			code:         DiffManchester,
+			bits:         []types.Bit{1, 0, 0, 0, 1, 0},
+			expectedBits: []types.Bit{0, 0},
+			expectedViolations: []Violation{
+				{Offset: 2, Bits: "00"},
+			},
+		}, {
+			name:         "hdlc six ones",
+			code:         HDLC,
+			bits:         []types.Bit{0, 1, 1, 1, 1, 1, 1, 0},
+			expectedBits: []types.Bit{0, 1, 1, 1, 1, 1, 1, 0},
+			expectedViolations: []Violation{
+				{Offset: 1, Bits: "111111"},
+			},
+		},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			a, r := assert.New(tt), require.New(tt)
+
+			decoded, violations, err := Decode(ctx, tc.bits, tc.code)
+			r.NoError(err)
+			a.Equal(tc.expectedBits, decoded)
+			a.Equal(tc.expectedViolations, violations)
+		})
+	}
+}
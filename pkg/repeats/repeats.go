@@ -0,0 +1,292 @@
+package repeats
+
+import (
+	"container/heap"
+	"sort"
+
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+// SuffixArray holds the suffixes of a bit string in lexicographic order
+//
+// SA[i] is the offset of the i-th smallest suffix, Rank is its inverse and
+// LCP[i] is the length of the longest common prefix of the suffixes SA[i-1] and SA[i]
+type SuffixArray struct {
+	Bits []types.Bit
+	SA   []int32
+	Rank []int32
+	LCP  []int32
+}
+
+// Repeat is a substring occurring at least twice
+type Repeat struct {
+	Length  int   `json:"length"`
+	Offsets []int `json:"offsets"`
+}
+
+// NewSuffixArray builds the suffix array by prefix doubling with radix sort in O(N*log(L)),
+// where L is the length of the longest repeat, and the LCP array with Kasai's algorithm
+func NewSuffixArray(bits []types.Bit) *SuffixArray {
+	n := len(bits)
+	s := &SuffixArray{
+		Bits: bits,
+		SA:   make([]int32, n),
+		Rank: make([]int32, n),
+		LCP:  make([]int32, n),
+	}
+	if n == 0 {
+		return s
+	}
+
+	sa, rank := s.SA, s.Rank
+	tmp := make([]int32, n)
+
+	// sort by the first bit
+	p := 0
+	for bit := types.Bit(0); bit <= 1; bit++ {
+		for i, b := range bits {
+			if b == bit {
+				sa[p] = int32(i)
+				p++
+			}
+		}
+	}
+	for i, b := range bits {
+		rank[i] = int32(b)
+	}
+	maxRank := int32(1)
+
+	counts := make([]int32, n+2)
+	for k := 1; ; k <<= 1 {
+		// order by the second key: suffixes shorter than k come first
+		p := 0
+		for i := n - k; i < n; i++ {
+			if i >= 0 {
+				tmp[p] = int32(i)
+				p++
+			}
+		}
+		for _, i := range sa {
+			if int(i) >= k {
+				tmp[p] = i - int32(k)
+				p++
+			}
+		}
+
+		// stable counting sort by the first key
+		for i := range counts[:maxRank+2] {
+			counts[i] = 0
+		}
+		for _, i := range tmp {
+			counts[rank[i]+1]++
+		}
+		for r := int32(1); r <= maxRank+1; r++ {
+			counts[r] += counts[r-1]
+		}
+		for _, i := range tmp {
+			sa[counts[rank[i]]] = i
+			counts[rank[i]]++
+		}
+
+		// rank the (first, second) pairs
+		key := func(i int32) int32 {
+			if int(i)+k < n {
+				return rank[int(i)+k]
+			}
+			return -1
+		}
+		tmp[sa[0]] = 0
+		for j := 1; j < n; j++ {
+			prev, curr := sa[j-1], sa[j]
+			tmp[curr] = tmp[prev]
+			if rank[prev] != rank[curr] || key(prev) != key(curr) {
+				tmp[curr]++
+			}
+		}
+		copy(rank, tmp)
+		maxRank = rank[sa[n-1]]
+
+		if int(maxRank) == n-1 || k >= n {
+			break
+		}
+	}
+
+	s.kasai()
+
+	return s
+}
+
+func (s *SuffixArray) kasai() {
+	n := len(s.Bits)
+	h := 0
+	for i := 0; i < n; i++ {
+		r := s.Rank[i]
+		if r == 0 {
+			h = 0
+			continue
+		}
+
+		j := int(s.SA[r-1])
+		for i+h < n && j+h < n && s.Bits[i+h] == s.Bits[j+h] {
+			h++
+		}
+		s.LCP[r] = int32(h)
+
+		if h > 0 {
+			h--
+		}
+	}
+}
+
+// LongestRepeats returns up to k maximal repeated substrings, longest first
+func (s *SuffixArray) LongestRepeats(k int) []Repeat {
+	idx := make([]int, 0, len(s.LCP))
+	for i, l := range s.LCP {
+		if l > 0 {
+			idx = append(idx, i)
+		}
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		return s.LCP[idx[a]] > s.LCP[idx[b]]
+	})
+
+	repeats := []Repeat{}
+	seen := map[[2]int]bool{}
+	for _, i := range idx {
+		if k > 0 && len(repeats) >= k {
+			break
+		}
+
+		length := s.LCP[i]
+
+		// all the suffixes sharing the prefix form a contiguous interval of the suffix array
+		lo, hi := i-1, i
+		for lo > 0 && s.LCP[lo] >= length {
+			lo--
+		}
+		for hi+1 < len(s.LCP) && s.LCP[hi+1] >= length {
+			hi++
+		}
+		// a shorter prefix shared by the same suffixes is the same repeat
+		if seen[[2]int{lo, hi}] {
+			continue
+		}
+		seen[[2]int{lo, hi}] = true
+
+		offsets := make([]int, 0, hi-lo+1)
+		for j := lo; j <= hi; j++ {
+			offsets = append(offsets, int(s.SA[j]))
+		}
+		sort.Ints(offsets)
+
+		// when all the occurrences are preceded by the same bit, the repeat is
+		// part of a longer one, shifted by one
+		if !s.leftMaximal(offsets) {
+			continue
+		}
+
+		repeats = append(repeats, Repeat{Length: int(length), Offsets: offsets})
+	}
+
+	return repeats
+}
+
+func (s *SuffixArray) leftMaximal(offsets []int) bool {
+	if offsets[0] == 0 {
+		return true
+	}
+
+	prev := s.Bits[offsets[0]-1]
+	for _, o := range offsets[1:] {
+		if s.Bits[o-1] != prev {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DistinctSubstrings returns the number of distinct substrings of length 1 to maxLen
+//
+// each suffix introduces the prefixes longer than its LCP with the previous suffix
+func (s *SuffixArray) DistinctSubstrings(maxLen int) []int {
+	n := len(s.Bits)
+	diff := make([]int, maxLen+2)
+	for i, start := range s.SA {
+		from := int(s.LCP[i]) + 1
+		to := min(n-int(start), maxLen)
+		if from > to {
+			continue
+		}
+		diff[from]++
+		diff[to+1]--
+	}
+
+	counts := make([]int, maxLen)
+	acc := 0
+	for l := 1; l <= maxLen; l++ {
+		acc += diff[l]
+		counts[l-1] = acc
+	}
+
+	return counts
+}
+
+// Coverage returns, for each bit, the length of the longest repeated substring covering it,
+// repeats shorter than minLen are ignored
+func (s *SuffixArray) Coverage(minLen int) []int {
+	n := len(s.Bits)
+	coverage := make([]int, n)
+
+	active := &repeatHeap{}
+	for p := 0; p < n; p++ {
+		// longest repeat starting at p
+		r := s.Rank[p]
+		length := int(s.LCP[r])
+		if int(r)+1 < n && int(s.LCP[r+1]) > length {
+			length = int(s.LCP[r+1])
+		}
+		if length > 0 && length >= minLen {
+			heap.Push(active, activeRepeat{length: length, end: p + length})
+		}
+
+		for active.Len() > 0 && (*active)[0].end <= p {
+			heap.Pop(active)
+		}
+		if active.Len() > 0 {
+			coverage[p] = (*active)[0].length
+		}
+	}
+
+	return coverage
+}
+
+type activeRepeat struct {
+	length, end int
+}
+
+// repeatHeap is a max heap on the repeat length
+type repeatHeap []activeRepeat
+
+func (h repeatHeap) Len() int           { return len(h) }
+func (h repeatHeap) Less(i, j int) bool { return h[i].length > h[j].length }
+func (h repeatHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *repeatHeap) Push(x any) {
+	*h = append(*h, x.(activeRepeat))
+}
+
+func (h *repeatHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return x
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
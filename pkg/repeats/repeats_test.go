@@ -0,0 +1,92 @@
+package repeats
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fedemengo/d2bist/pkg/engine"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+func randomBits(rng *rand.Rand, n int) []types.Bit {
+	bits := make([]types.Bit, n)
+	for i := range bits {
+		bits[i] = types.Bit(rng.Intn(2))
+	}
+
+	return bits
+}
+
+func TestSuffixArray(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+
+	inputs := [][]types.Bit{
+		{},
+		{1},
+		{0, 0, 0, 0, 0, 0},
+		{0, 1, 0, 1, 0, 1, 0},
+	}
+	for _, n := range []int{10, 100, 1000} {
+		inputs = append(inputs, randomBits(rng, n))
+	}
+
+	for _, bits := range inputs {
+		a := assert.New(t)
+		s := engine.BitsToString(bits)
+
+		expected := make([]int32, len(bits))
+		for i := range expected {
+			expected[i] = int32(i)
+		}
+		sort.Slice(expected, func(i, j int) bool {
+			return s[expected[i]:] < s[expected[j]:]
+		})
+
+		sa := NewSuffixArray(bits)
+		a.Equal(expected, sa.SA, s)
+
+		for i := 1; i < len(bits); i++ {
+			x, y := s[sa.SA[i-1]:], s[sa.SA[i]:]
+			l := 0
+			for l < len(x) && l < len(y) && x[l] == y[l] {
+				l++
+			}
+			a.Equal(int32(l), sa.LCP[i])
+		}
+	}
+}
+
+func TestDistinctSubstrings(t *testing.T) {
+	a := assert.New(t)
+	rng := rand.New(rand.NewSource(1))
+
+	bits := randomBits(rng, 300)
+	s := engine.BitsToString(bits)
+
+	maxLen := 12
+	counts := NewSuffixArray(bits).DistinctSubstrings(maxLen)
+	for l := 1; l <= maxLen; l++ {
+		distinct := map[string]bool{}
+		for i := 0; i+l <= len(s); i++ {
+			distinct[s[i:i+l]] = true
+		}
+		a.Equal(len(distinct), counts[l-1], "length %d", l)
+	}
+}
+
+func TestLongestRepeats(t *testing.T) {
+	a := assert.New(t)
+
+	// 1101 0 1101
+	bits := []types.Bit{1, 1, 0, 1, 0, 1, 1, 0, 1}
+	sa := NewSuffixArray(bits)
+
+	repeats := sa.LongestRepeats(1)
+	a.Equal([]Repeat{{Length: 4, Offsets: []int{0, 5}}}, repeats)
+
+	coverage := sa.Coverage(4)
+	a.Equal([]int{4, 4, 4, 4, 0, 4, 4, 4, 4}, coverage)
+}
@@ -0,0 +1,213 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/fedemengo/d2bist/pkg/analyze"
+	"github.com/fedemengo/d2bist/pkg/compression"
+	"github.com/fedemengo/d2bist/pkg/image"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+// maxSubstrRows caps the rows of a substrings table, the most frequent are kept
+const maxSubstrRows = 1_000
+
+type config struct {
+	title       string
+	pixelLen    int
+	imageOpts   []image.Opt
+	chartOpts   []types.ChartOpt
+	analyzeOpts []analyze.Opt
+}
+
+type Opt func(c *config)
+
+// WithTitle sets the title of the report, usually the name of the input
+func WithTitle(title string) Opt {
+	return func(c *config) {
+		c.title = title
+	}
+}
+
+// WithPixelLen sets the bits per pixel of the bit image
+func WithPixelLen(pixelLen int) Opt {
+	return func(c *config) {
+		c.pixelLen = pixelLen
+	}
+}
+
+// WithImageOpts sets the options of the bit image
+func WithImageOpts(opts ...image.Opt) Opt {
+	return func(c *config) {
+		c.imageOpts = append(c.imageOpts, opts...)
+	}
+}
+
+// WithChartOpts styles the entropy chart
+func WithChartOpts(opts ...types.ChartOpt) Opt {
+	return func(c *config) {
+		c.chartOpts = append(c.chartOpts, opts...)
+	}
+}
+
+// WithAnalyzeOpts sets the options of the compression comparison
+func WithAnalyzeOpts(opts ...analyze.Opt) Opt {
+	return func(c *config) {
+		c.analyzeOpts = append(c.analyzeOpts, opts...)
+	}
+}
+
+type compressionRow struct {
+	Algorithm string
+	Bits      int
+	Ratio     float64
+}
+
+type substrRow struct {
+	Bits  string
+	Count int
+	Freq  float64
+}
+
+type substrTable struct {
+	Length      int
+	Approximate bool
+	Truncated   int
+	Rows        []substrRow
+}
+
+type page struct {
+	Title string
+	Bits  int
+	Bytes int
+	Ones  float64
+
+	BitImage     template.URL
+	EntropyChart template.URL
+
+	Entropy        []entropyRow
+	Compressions   []compressionRow
+	OutCompression *types.CompressionStats
+
+	Substrs []substrTable
+
+	LineCode   *types.LineCodeStats
+	Extraction *types.ExtractionStats
+}
+
+type entropyRow struct {
+	Estimator string
+	Mean      float64
+}
+
+// WriteFile writes the report of res to path
+func WriteFile(ctx context.Context, path string, res *types.Result, opts ...Opt) error {
+	var buf bytes.Buffer
+	if err := Write(ctx, &buf, res, opts...); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// Write writes to w a self-contained html page with everything computed on res
+//
+// images are inlined as data URIs
+func Write(ctx context.Context, w io.Writer, res *types.Result, opts ...Opt) error {
+	c := &config{title: "d2bist report", pixelLen: 1}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	p := page{
+		Title:          c.title,
+		Bits:           len(res.Bits),
+		Bytes:          len(res.Bits) / 8,
+		OutCompression: res.Stats.CompressionStats,
+		LineCode:       res.Stats.LineCodeStats,
+		Extraction:     res.Stats.ExtractionStats,
+	}
+
+	if len(res.Bits) > 0 {
+		var img bytes.Buffer
+		if err := image.EncodePNG(&img, res.Bits, c.pixelLen, c.imageOpts...); err != nil {
+			return fmt.Errorf("cannot render bit image: %w", err)
+		}
+		p.BitImage = pngDataURI(img.Bytes())
+	}
+
+	if len(res.Stats.Entropy) > 0 {
+		var chart bytes.Buffer
+		sets := []types.EntropySet{{Entropy: res.Stats.Entropy}}
+		if err := types.EncodeEntropyChart(&chart, "png", sets, c.chartOpts...); err != nil {
+			return fmt.Errorf("cannot render entropy chart: %w", err)
+		}
+		p.EntropyChart = pngDataURI(chart.Bytes())
+	}
+
+	// the top substrings are already in the stats
+	analyzeOpts := append([]analyze.Opt{}, c.analyzeOpts...)
+	summary := analyze.Summarize(ctx, c.title, res.Bits, append(analyzeOpts, analyze.WithTopSubstrs(0, 0))...)
+	p.Ones = summary.Ones
+
+	for name, v := range summary.Entropy {
+		p.Entropy = append(p.Entropy, entropyRow{Estimator: string(name), Mean: v})
+	}
+	sort.Slice(p.Entropy, func(i, j int) bool { return p.Entropy[i].Estimator < p.Entropy[j].Estimator })
+
+	for _, cType := range compression.Types() {
+		ratio, ok := summary.Compression[cType]
+		if !ok {
+			continue
+		}
+		p.Compressions = append(p.Compressions, compressionRow{
+			Algorithm: string(cType),
+			Bits:      int(ratio * float64(len(res.Bits))),
+			Ratio:     ratio,
+		})
+	}
+
+	for _, group := range res.Stats.SubstrsCount {
+		p.Substrs = append(p.Substrs, newSubstrTable(group))
+	}
+
+	return reportPage.Execute(w, p)
+}
+
+func pngDataURI(data []byte) template.URL {
+	return template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(data))
+}
+
+// newSubstrTable returns the counted substrings of group, the most frequent first
+func newSubstrTable(group types.SubstrCount) substrTable {
+	t := substrTable{Length: group.Length, Approximate: group.Approximate}
+
+	total := 0
+	for _, count := range group.Counts {
+		total += count
+	}
+
+	for bitStr, count := range group.Counts {
+		t.Rows = append(t.Rows, substrRow{Bits: bitStr, Count: count, Freq: float64(count) / float64(total)})
+	}
+	sort.Slice(t.Rows, func(i, j int) bool {
+		if t.Rows[i].Count != t.Rows[j].Count {
+			return t.Rows[i].Count > t.Rows[j].Count
+		}
+		return t.Rows[i].Bits < t.Rows[j].Bits
+	})
+
+	if len(t.Rows) > maxSubstrRows {
+		t.Truncated = len(t.Rows) - maxSubstrRows
+		t.Rows = t.Rows[:maxSubstrRows]
+	}
+
+	return t
+}
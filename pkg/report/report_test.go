@@ -0,0 +1,55 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fedemengo/d2bist/pkg/stats"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+func TestWrite(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+	ctx := context.Background()
+
+	bits := make([]types.Bit, 0, 1024)
+	for i := 0; i < 256; i++ {
+		bits = append(bits, 1, 1, 0, 1)
+	}
+	res := &types.Result{
+		Bits:  bits,
+		Stats: stats.AnalizeBits(ctx, bits, stats.WithBlockSize(256), stats.WithSymbolLen(2)),
+	}
+
+	var buf bytes.Buffer
+	r.NoError(Write(ctx, &buf, res, WithTitle("pattern")))
+	html := buf.String()
+
+	a.Contains(html, "<title>pattern</title>")
+	a.Equal(2, strings.Count(html, `src="data:image/png;base64,`))
+	a.Contains(html, "<td>Gzip</td>")
+	a.Contains(html, "<h3>length 256</h3>")
+	a.Contains(html, `<th class="sortable" data-type="num">count</th>`)
+}
+
+func TestNewSubstrTable(t *testing.T) {
+	group := types.SubstrCount{
+		Length: 2,
+		Counts: map[string]int{"00": 1, "01": 3, "10": 3, "11": 1},
+	}
+
+	table := newSubstrTable(group)
+
+	assert.Equal(t, []substrRow{
+		{Bits: "01", Count: 3, Freq: 0.375},
+		{Bits: "10", Count: 3, Freq: 0.375},
+		{Bits: "00", Count: 1, Freq: 0.125},
+		{Bits: "11", Count: 1, Freq: 0.125},
+	}, table.Rows)
+	assert.Zero(t, table.Truncated)
+}
@@ -0,0 +1,105 @@
+package report
+
+import "html/template"
+
+var reportPage = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; font-family: monospace; }
+th { background: #eee; }
+th.sortable { cursor: pointer; }
+td:first-child { text-align: left; }
+img { max-width: 100%; border: 1px solid #ccc; }
+.substrs { display: inline-block; vertical-align: top; margin-right: 2em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+
+<h2>Overview</h2>
+<table>
+<tr><td>bits</td><td>{{.Bits}}</td></tr>
+<tr><td>bytes</td><td>{{.Bytes}}</td></tr>
+<tr><td>ones</td><td>{{printf "%.5f" .Ones}}</td></tr>
+</table>
+
+{{with .BitImage}}
+<h2>Bits</h2>
+<img src="{{.}}" alt="bits">
+{{end}}
+
+{{if .Entropy}}
+<h2>Entropy</h2>
+<table>
+<thead><tr><th class="sortable">estimator</th><th class="sortable" data-type="num">mean</th></tr></thead>
+<tbody>
+{{range .Entropy}}<tr><td>{{.Estimator}}</td><td>{{printf "%.5f" .Mean}}</td></tr>
+{{end}}</tbody>
+</table>
+{{with .EntropyChart}}<img src="{{.}}" alt="entropy chart">{{end}}
+{{end}}
+
+{{if .Compressions}}
+<h2>Compression</h2>
+<table>
+<thead><tr><th class="sortable">algorithm</th><th class="sortable" data-type="num">bits</th><th class="sortable" data-type="num">ratio</th></tr></thead>
+<tbody>
+{{range .Compressions}}<tr><td>{{.Algorithm}}</td><td>{{.Bits}}</td><td>{{printf "%.5f" .Ratio}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+{{with .OutCompression}}
+<p>output compressed with {{.CompressionAlgorithm}}, compression ratio {{printf "%.3f" .CompressionRatio}}</p>
+{{end}}
+
+{{with .LineCode}}
+<h2>Line code</h2>
+<p>{{.Code}} ({{.InputBits}} -> {{.OutputBits}} bits), {{len .ViolationOffsets}} violations</p>
+{{end}}
+
+{{with .Extraction}}
+<h2>Extraction</h2>
+<p>{{.Extractor}}, yield {{printf "%.3f" .Yield}} ({{.InputBits}} -> {{.OutputBits}} bits)</p>
+{{end}}
+
+{{if .Substrs}}
+<h2>Substrings</h2>
+{{range .Substrs}}
+<div class="substrs">
+<h3>length {{.Length}}{{if .Approximate}} (approximate){{end}}</h3>
+<table>
+<thead><tr><th class="sortable">bits</th><th class="sortable" data-type="num">count</th><th class="sortable" data-type="num">freq</th></tr></thead>
+<tbody>
+{{range .Rows}}<tr><td>{{.Bits}}</td><td>{{.Count}}</td><td>{{printf "%.5f" .Freq}}</td></tr>
+{{end}}</tbody>
+</table>
+{{if .Truncated}}<p>{{.Truncated}} less frequent substrings not shown</p>{{end}}
+</div>
+{{end}}
+{{end}}
+
+<script>
+document.querySelectorAll("th.sortable").forEach(function (th) {
+  th.addEventListener("click", function () {
+    var body = th.closest("table").tBodies[0];
+    var col = th.cellIndex, num = th.dataset.type === "num";
+    var asc = th.dataset.order !== "asc";
+    var rows = Array.from(body.rows);
+    rows.sort(function (a, b) {
+      var x = a.cells[col].textContent, y = b.cells[col].textContent;
+      var cmp = num ? parseFloat(x) - parseFloat(y) : x.localeCompare(y);
+      return asc ? cmp : -cmp;
+    });
+    th.dataset.order = asc ? "asc" : "desc";
+    rows.forEach(function (r) { body.appendChild(r); });
+  });
+});
+</script>
+</body>
+</html>
+`))
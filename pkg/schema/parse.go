@@ -0,0 +1,218 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fedemengo/d2bist/pkg/engine"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+// Value is a decoded field, offset and width are in bits
+//
+// the value is a uint64, int64, bool or string, an enum is the name of its value or the
+// number when it has no name, and a repeated field is the list of its values
+type Value struct {
+	Name   string `json:"name"`
+	Offset int    `json:"offset"`
+	Width  int    `json:"width"`
+	Value  any    `json:"value"`
+}
+
+// Record is a pass of the schema over the bits
+type Record struct {
+	Offset int     `json:"offset"`
+	Fields []Value `json:"fields"`
+}
+
+// Result holds the records parsed from the bits and the trailing bits that do not fill a record
+type Result struct {
+	Schema  string      `json:"schema,omitempty"`
+	Bits    int         `json:"bits"`
+	Records []Record    `json:"records"`
+	Unused  types.Range `json:"unused"`
+}
+
+type config struct {
+	maxRecords int
+}
+
+type Opt func(c *config)
+
+// WithMaxRecords stops after n records, by default records are parsed until the bits end
+func WithMaxRecords(n int) Opt {
+	return func(c *config) {
+		c.maxRecords = n
+	}
+}
+
+// Parse applies the schema to bits, record after record, until a record does not fit in the remaining bits
+func Parse(bits []types.Bit, s *Schema, opts ...Opt) (*Result, error) {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	enums := enumNames(s)
+
+	res := &Result{
+		Schema:  s.Name,
+		Bits:    len(bits),
+		Records: []Record{},
+	}
+
+	offset := 0
+	for offset < len(bits) && (c.maxRecords <= 0 || len(res.Records) < c.maxRecords) {
+		record, n, err := parseRecord(bits, offset, s, enums)
+		if errors.Is(err, ErrNotEnoughBits) {
+			// the last record is incomplete, its bits are unused
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			// the fields are all repeated 0 times, the next records would be empty as well
+			break
+		}
+
+		res.Records = append(res.Records, record)
+		offset += n
+	}
+
+	res.Unused = types.Range{Offset: offset, Length: len(bits) - offset}
+
+	return res, nil
+}
+
+// parseRecord decodes the fields starting at offset and returns how many bits they take
+func parseRecord(bits []types.Bit, offset int, s *Schema, enums map[string]map[uint64]string) (Record, int, error) {
+	record := Record{Offset: offset}
+	counts := map[string]uint64{}
+
+	pos := offset
+	for _, f := range s.Fields {
+		repeat, err := repeatCount(f, counts, len(bits)-pos)
+		if err != nil {
+			return Record{}, 0, err
+		}
+
+		if repeat < 0 {
+			v, err := decodeField(bits, pos, f, enums[f.Name])
+			if err != nil {
+				return Record{}, 0, err
+			}
+			if f.Type == Uint {
+				counts[f.Name] = v.(uint64)
+			}
+
+			record.Fields = append(record.Fields, Value{Name: f.Name, Offset: pos, Width: f.Width, Value: v})
+			pos += f.Width
+			continue
+		}
+
+		values := make([]any, 0, repeat)
+		for i := 0; i < repeat; i++ {
+			v, err := decodeField(bits, pos+i*f.Width, f, enums[f.Name])
+			if err != nil {
+				return Record{}, 0, err
+			}
+			values = append(values, v)
+		}
+
+		record.Fields = append(record.Fields, Value{Name: f.Name, Offset: pos, Width: repeat * f.Width, Value: values})
+		pos += repeat * f.Width
+	}
+
+	return record, pos - offset, nil
+}
+
+// repeatCount returns how many times the field repeats, or -1 when it is not repeated
+func repeatCount(f Field, counts map[string]uint64, remaining int) (int, error) {
+	switch {
+	case len(f.Repeat) == 0:
+		return -1, nil
+	case f.Repeat == EOS:
+		return remaining / f.Width, nil
+	}
+
+	n, ok := counts[f.Repeat]
+	if c, err := strconv.ParseUint(f.Repeat, 10, 64); err == nil {
+		n, ok = c, true
+	}
+	if !ok {
+		return 0, fmt.Errorf("field `%s` repeat `%s` is not a count: %w", f.Name, f.Repeat, ErrInvalidSchema)
+	}
+
+	if n > uint64(remaining/f.Width) {
+		return 0, fmt.Errorf("field `%s` repeats %d times: %w", f.Name, n, ErrNotEnoughBits)
+	}
+
+	return int(n), nil
+}
+
+func decodeField(bits []types.Bit, offset int, f Field, names map[uint64]string) (any, error) {
+	if offset+f.Width > len(bits) {
+		return nil, fmt.Errorf("field `%s` at %d: %w", f.Name, offset, ErrNotEnoughBits)
+	}
+	fieldBits := bits[offset : offset+f.Width]
+
+	if f.Type == String {
+		var byteBits [8]types.Bit
+		text := make([]byte, 0, f.Width/8)
+		for i := 0; i < f.Width; i += 8 {
+			copy(byteBits[:], fieldBits[i:i+8])
+			text = append(text, engine.BitsToByte(byteBits))
+		}
+
+		return strings.TrimRight(string(text), "\x00"), nil
+	}
+
+	v, err := engine.BitsToInt(fieldBits)
+	if err != nil {
+		return nil, err
+	}
+
+	switch f.Type {
+	case Int:
+		if f.Width < 64 && v>>(f.Width-1) == 1 {
+			return int64(v) - 1<<f.Width, nil
+		}
+		return int64(v), nil
+	case Bool:
+		return v != 0, nil
+	case Enum:
+		if name, ok := names[v]; ok {
+			return name, nil
+		}
+		return v, nil
+	default:
+		return v, nil
+	}
+}
+
+// enumNames parses the values of the enums, which may be decimal, hex (0x) or binary (0b),
+// they are checked by Validate
+func enumNames(s *Schema) map[string]map[uint64]string {
+	enums := map[string]map[uint64]string{}
+	for _, f := range s.Fields {
+		if f.Type != Enum {
+			continue
+		}
+
+		names := map[uint64]string{}
+		for k, name := range f.Values {
+			v, _ := strconv.ParseUint(k, 0, 64)
+			names[v] = name
+		}
+		enums[f.Name] = names
+	}
+
+	return enums
+}
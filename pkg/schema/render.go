@@ -0,0 +1,51 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// WriteTable writes a row per field of each record, with the unused bits at the end
+func WriteTable(w io.Writer, res *Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "record\toffset\twidth\tfield\tvalue")
+	for i, r := range res.Records {
+		for _, v := range r.Fields {
+			fmt.Fprintf(tw, "%d\t%d\t%d\t%s\t%s\n", i, v.Offset, v.Width, v.Name, formatValue(v.Value))
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nrecords: %d\nunused: %d bits at offset %d\n", len(res.Records), res.Unused.Length, res.Unused.Offset)
+
+	return nil
+}
+
+// WriteJSON writes the result as json
+func WriteJSON(w io.Writer, res *Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(res)
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, e := range v {
+			values = append(values, formatValue(e))
+		}
+		return "[" + strings.Join(values, " ") + "]"
+	default:
+		return fmt.Sprint(v)
+	}
+}
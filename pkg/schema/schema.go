@@ -0,0 +1,162 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrInvalidSchema = errors.New("invalid schema")
+	ErrNotEnoughBits = errors.New("not enough bits")
+)
+
+// Type is how the bits of a field are decoded
+type Type string
+
+const (
+	// Uint is an unsigned big endian integer, up to 64 bits
+	Uint = Type("uint")
+	// Int is a two's complement signed big endian integer, up to 64 bits
+	Int = Type("int")
+	// Bool is true when any of its bits is 1
+	Bool = Type("bool")
+	// Enum is an unsigned integer with a name for its values
+	Enum = Type("enum")
+	// String is text of 8 bits characters, the trailing NULs are dropped
+	String = Type("string")
+)
+
+// EOS repeats a field until the end of the bits
+const EOS = "eos"
+
+// Field is a sequence of Width bits, decoded as Type
+//
+// Repeat is how many times the field follows itself: a count, EOS, or the name of a previous
+// uint field that holds the count, the field is not repeated when Repeat is empty
+type Field struct {
+	Name   string            `json:"name" yaml:"name"`
+	Width  int               `json:"width" yaml:"width"`
+	Type   Type              `json:"type" yaml:"type"`
+	Repeat string            `json:"repeat,omitempty" yaml:"repeat,omitempty"`
+	Values map[string]string `json:"values,omitempty" yaml:"values,omitempty"`
+}
+
+// Schema is the sequence of fields of a record, records follow each other until the bits end
+type Schema struct {
+	Name   string  `json:"name,omitempty" yaml:"name,omitempty"`
+	Fields []Field `json:"fields" yaml:"fields"`
+}
+
+// Load reads a schema from a yaml or json file, json documents are valid yaml
+func Load(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Unmarshal(data)
+}
+
+// Unmarshal decodes a yaml or json schema and validates it
+func Unmarshal(data []byte) (*Schema, error) {
+	s := &Schema{}
+	if err := yaml.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("%v: %w", err, ErrInvalidSchema)
+	}
+
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Validate checks the widths, types and repeats of the fields, a field without a type is a uint
+// and a bool without a width has 1 bit
+func (s *Schema) Validate() error {
+	if len(s.Fields) == 0 {
+		return fmt.Errorf("no fields: %w", ErrInvalidSchema)
+	}
+
+	seen := map[string]*Field{}
+	for i := range s.Fields {
+		f := &s.Fields[i]
+
+		if len(f.Name) == 0 {
+			return fmt.Errorf("field %d has no name: %w", i, ErrInvalidSchema)
+		}
+		if _, ok := seen[f.Name]; ok {
+			return fmt.Errorf("field `%s` is defined twice: %w", f.Name, ErrInvalidSchema)
+		}
+
+		if len(f.Type) == 0 {
+			f.Type = Uint
+		}
+		if f.Type == Bool && f.Width == 0 {
+			f.Width = 1
+		}
+		if f.Width < 1 {
+			return fmt.Errorf("field `%s` width %d must be positive: %w", f.Name, f.Width, ErrInvalidSchema)
+		}
+
+		switch f.Type {
+		case Uint, Int, Bool, Enum:
+			if f.Width > 64 {
+				return fmt.Errorf("field `%s` width %d is more than 64 bits: %w", f.Name, f.Width, ErrInvalidSchema)
+			}
+		case String:
+			if f.Width%8 != 0 {
+				return fmt.Errorf("string `%s` width %d is not a multiple of 8: %w", f.Name, f.Width, ErrInvalidSchema)
+			}
+		default:
+			return fmt.Errorf("field `%s` type `%s` is not supported: %w", f.Name, f.Type, ErrInvalidSchema)
+		}
+
+		for k := range f.Values {
+			if _, err := strconv.ParseUint(k, 0, 64); err != nil {
+				return fmt.Errorf("enum `%s` value `%s` is not a number: %w", f.Name, k, ErrInvalidSchema)
+			}
+		}
+
+		if err := validateRepeat(f, seen, i == len(s.Fields)-1); err != nil {
+			return err
+		}
+
+		seen[f.Name] = f
+	}
+
+	return nil
+}
+
+func validateRepeat(f *Field, seen map[string]*Field, last bool) error {
+	switch {
+	case len(f.Repeat) == 0:
+		return nil
+	case f.Repeat == EOS:
+		if !last {
+			return fmt.Errorf("field `%s` repeats to the end but is not the last: %w", f.Name, ErrInvalidSchema)
+		}
+		return nil
+	}
+
+	if n, err := strconv.Atoi(f.Repeat); err == nil {
+		if n < 0 {
+			return fmt.Errorf("field `%s` repeat %d is negative: %w", f.Name, n, ErrInvalidSchema)
+		}
+		return nil
+	}
+
+	count, ok := seen[f.Repeat]
+	if !ok || count.Type != Uint {
+		return fmt.Errorf("field `%s` repeat `%s` is not a count, %s or a previous uint field: %w", f.Name, f.Repeat, EOS, ErrInvalidSchema)
+	}
+	if len(count.Repeat) > 0 {
+		return fmt.Errorf("field `%s` repeat `%s` is a repeated field: %w", f.Name, f.Repeat, ErrInvalidSchema)
+	}
+
+	return nil
+}
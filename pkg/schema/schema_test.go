@@ -0,0 +1,200 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fedemengo/d2bist/pkg/engine"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+const packetSchema = `
+name: packet
+fields:
+  - name: version
+    width: 3
+  - name: ack
+    type: bool
+  - name: kind
+    width: 4
+    type: enum
+    values:
+      0: ping
+      0x2: data
+  - name: temp
+    width: 6
+    type: int
+  - name: len
+    width: 2
+  - name: payload
+    width: 8
+    type: string
+    repeat: len
+`
+
+func bitsOf(s string) []types.Bit {
+	bits := []types.Bit{}
+	for _, c := range strings.ReplaceAll(s, " ", "") {
+		bits = append(bits, types.Bit(c-'0'))
+	}
+
+	return bits
+}
+
+func TestUnmarshal(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	s, err := Unmarshal([]byte(packetSchema))
+	r.NoError(err)
+	a.Equal("packet", s.Name)
+	r.Len(s.Fields, 6)
+	a.Equal(Uint, s.Fields[0].Type)
+	a.Equal(1, s.Fields[1].Width)
+	a.Equal(map[string]string{"0": "ping", "0x2": "data"}, s.Fields[2].Values)
+
+	// json is valid yaml
+	js, err := json.Marshal(s)
+	r.NoError(err)
+	fromJSON, err := Unmarshal(js)
+	r.NoError(err)
+	a.Equal(s, fromJSON)
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		fields []Field
+	}{
+		{name: "no fields"},
+		{name: "no name", fields: []Field{{Width: 3}}},
+		{name: "no width", fields: []Field{{Name: "a"}}},
+		{name: "too wide", fields: []Field{{Name: "a", Width: 65}}},
+		{name: "unknown type", fields: []Field{{Name: "a", Width: 4, Type: "float"}}},
+		{name: "string of bits", fields: []Field{{Name: "a", Width: 7, Type: String}}},
+		{name: "duplicate", fields: []Field{{Name: "a", Width: 1}, {Name: "a", Width: 1}}},
+		{name: "enum value", fields: []Field{{Name: "a", Width: 2, Type: Enum, Values: map[string]string{"one": "1"}}}},
+		{name: "negative repeat", fields: []Field{{Name: "a", Width: 1, Repeat: "-1"}}},
+		{name: "eos not last", fields: []Field{{Name: "a", Width: 1, Repeat: EOS}, {Name: "b", Width: 1}}},
+		{name: "count after", fields: []Field{{Name: "a", Width: 1, Repeat: "b"}, {Name: "b", Width: 1}}},
+		{name: "count not uint", fields: []Field{{Name: "b", Width: 1, Type: Bool}, {Name: "a", Width: 1, Repeat: "b"}}},
+		{name: "count repeated", fields: []Field{{Name: "n", Width: 2, Repeat: "2"}, {Name: "x", Type: Bool, Repeat: "n"}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &Schema{Fields: tc.fields}
+			assert.ErrorIs(t, s.Validate(), ErrInvalidSchema)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	s, err := Unmarshal([]byte(packetSchema))
+	r.NoError(err)
+
+	// version 5, ack, data, temp -3, 2 bytes "hi"
+	first := "101 1 0010 111101 10 01101000 01101001"
+	// version 1, no ack, ping, temp 31, no payload
+	second := "001 0 0000 011111 00"
+	// kind 7 has no name, the payload of 3 bytes is cut
+	third := "000 0 0111 000000 11 01100001"
+
+	res, err := Parse(bitsOf(first+second+third), s)
+	r.NoError(err)
+	r.Len(res.Records, 2)
+	a.Equal("packet", res.Schema)
+
+	a.Equal(Record{
+		Offset: 0,
+		Fields: []Value{
+			{Name: "version", Offset: 0, Width: 3, Value: uint64(5)},
+			{Name: "ack", Offset: 3, Width: 1, Value: true},
+			{Name: "kind", Offset: 4, Width: 4, Value: "data"},
+			{Name: "temp", Offset: 8, Width: 6, Value: int64(-3)},
+			{Name: "len", Offset: 14, Width: 2, Value: uint64(2)},
+			{Name: "payload", Offset: 16, Width: 16, Value: []any{"h", "i"}},
+		},
+	}, res.Records[0])
+
+	a.Equal(32, res.Records[1].Offset)
+	a.Equal("ping", res.Records[1].Fields[2].Value)
+	a.Equal(int64(31), res.Records[1].Fields[3].Value)
+	a.Equal([]any{}, res.Records[1].Fields[5].Value)
+
+	a.Equal(types.Range{Offset: 32 + 16, Length: len(bitsOf(third))}, res.Unused)
+
+	res, err = Parse(bitsOf(first+second+third), s, WithMaxRecords(1))
+	r.NoError(err)
+	r.Len(res.Records, 1)
+	a.Equal(types.Range{Offset: 32, Length: 16 + len(bitsOf(third))}, res.Unused)
+}
+
+func TestParseRepeat(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	s := &Schema{Fields: []Field{
+		{Name: "magic", Width: 16, Type: String},
+		{Name: "flags", Type: Bool, Repeat: "3"},
+		{Name: "samples", Width: 4, Type: Int, Repeat: EOS},
+	}}
+
+	bits := []types.Bit{}
+	for _, b := range []byte("OK") {
+		byteBits := engine.ByteToBits(b)
+		bits = append(bits, byteBits[:]...)
+	}
+	bits = append(bits, bitsOf("101 0111 1000 0001 11")...)
+
+	res, err := Parse(bits, s)
+	r.NoError(err)
+	r.Len(res.Records, 1)
+
+	fields := res.Records[0].Fields
+	a.Equal("OK", fields[0].Value)
+	a.Equal([]any{true, false, true}, fields[1].Value)
+	a.Equal([]any{int64(7), int64(-8), int64(1)}, fields[2].Value)
+	a.Equal(12, fields[2].Width)
+	a.Equal(types.Range{Offset: 31, Length: 2}, res.Unused)
+}
+
+func TestParseRepeatTooLong(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	bits := make([]types.Bit, 800)
+	for _, repeat := range []string{"801", "100000000000000", "9223372036854775807"} {
+		s := &Schema{Fields: []Field{{Name: "b", Type: Bool, Repeat: repeat}}}
+
+		res, err := Parse(bits, s)
+		r.NoError(err)
+		a.Empty(res.Records)
+		a.Equal(types.Range{Offset: 0, Length: 800}, res.Unused)
+
+		_, err = repeatCount(s.Fields[0], nil, len(bits))
+		a.ErrorIs(err, ErrNotEnoughBits)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	s, err := Unmarshal([]byte(packetSchema))
+	r.NoError(err)
+	res, err := Parse(bitsOf("101 1 0010 111101 10 01101000 01101001 0"), s)
+	r.NoError(err)
+
+	buf := &bytes.Buffer{}
+	r.NoError(WriteTable(buf, res))
+	a.Contains(buf.String(), `payload  ["h" "i"]`)
+	a.Contains(buf.String(), "unused: 1 bits at offset 32")
+
+	buf.Reset()
+	r.NoError(WriteJSON(buf, res))
+	a.Contains(buf.String(), `"value": -3`)
+}
@@ -0,0 +1,117 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/fedemengo/d2bist/pkg/engine"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+var ErrInvalidPattern = errors.New("invalid pattern")
+
+// Pattern is a bit string where some positions can be any bit
+type Pattern struct {
+	str    string
+	length int
+	value  []uint64
+	mask   []uint64
+}
+
+// Match is an occurrence of the pattern, Errors is the number of mismatching bits
+type Match struct {
+	Offset int `json:"offset"`
+	Errors int `json:"errors"`
+}
+
+// ParsePattern reads a pattern made of `0`, `1` and `x` for don't-care bits
+func ParsePattern(s string) (*Pattern, error) {
+	if len(s) == 0 {
+		return nil, fmt.Errorf("empty pattern: %w", ErrInvalidPattern)
+	}
+
+	value := make([]types.Bit, len(s))
+	mask := make([]types.Bit, len(s))
+	for i, c := range s {
+		switch c {
+		case '0':
+			mask[i] = 1
+		case '1':
+			value[i], mask[i] = 1, 1
+		case 'x', 'X':
+		default:
+			return nil, fmt.Errorf("cannot handle `%c` at %d: %w", c, i, ErrInvalidPattern)
+		}
+	}
+
+	return &Pattern{
+		str:    s,
+		length: len(s),
+		value:  engine.PackBits(value),
+		mask:   engine.PackBits(mask),
+	}, nil
+}
+
+func (p *Pattern) String() string {
+	return p.str
+}
+
+// Len returns the length of the pattern in bits
+func (p *Pattern) Len() int {
+	return p.length
+}
+
+// Search returns the offsets where the pattern occurs with at most maxErrors mismatching bits
+//
+// bits are packed in 64 bits words, each candidate window is extracted with two shifts
+// and compared a word at a time, so the cost is O(N*L/64)
+func Search(bits []types.Bit, p *Pattern, maxErrors int) []Match {
+	matches := []Match{}
+	if p.length > len(bits) {
+		return matches
+	}
+
+	words := engine.PackBits(bits)
+	// a trailing zero word avoids bound checks when shifting the last window
+	words = append(words, 0)
+
+	for offset := 0; offset+p.length <= len(bits); offset++ {
+		errs := mismatches(words, offset, p, maxErrors)
+		if errs <= maxErrors {
+			matches = append(matches, Match{Offset: offset, Errors: errs})
+		}
+	}
+
+	return matches
+}
+
+// mismatches counts the cared bits that differ from the pattern, it stops once maxErrors is exceeded
+func mismatches(words []uint64, offset int, p *Pattern, maxErrors int) int {
+	first, shift := offset/64, uint(offset%64)
+
+	errs := 0
+	for k := range p.value {
+		w := words[first+k] << shift
+		if shift > 0 {
+			w |= words[first+k+1] >> (64 - shift)
+		}
+
+		errs += bits.OnesCount64((w ^ p.value[k]) & p.mask[k])
+		if errs > maxErrors {
+			return errs
+		}
+	}
+
+	return errs
+}
+
+// Ranges returns the bits covered by each match
+func Ranges(matches []Match, p *Pattern) []types.Range {
+	ranges := make([]types.Range, len(matches))
+	for i, m := range matches {
+		ranges[i] = types.Range{Offset: m.Offset, Length: p.length}
+	}
+
+	return ranges
+}
@@ -0,0 +1,106 @@
+package search
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+func naiveSearch(bits []types.Bit, pattern string, maxErrors int) []Match {
+	matches := []Match{}
+	for offset := 0; offset+len(pattern) <= len(bits); offset++ {
+		errs := 0
+		for i, c := range pattern {
+			if c != 'x' && types.Bit(c-'0') != bits[offset+i] {
+				errs++
+			}
+		}
+		if errs <= maxErrors {
+			matches = append(matches, Match{Offset: offset, Errors: errs})
+		}
+	}
+
+	return matches
+}
+
+func TestSearch(t *testing.T) {
+	testCases := []struct {
+		name            string
+		bits            []types.Bit
+		pattern         string
+		maxErrors       int
+		expectedMatches []Match
+	}{
+		{
+			name:            "exact unaligned",
+			bits:            []types.Bit{0, 0, 1, 0, 1, 1, 0, 1, 0, 1},
+			pattern:         "101",
+			expectedMatches: []Match{{Offset: 2}, {Offset: 5}, {Offset: 7}},
+		}, {
+			name:            "wildcards",
+			bits:            []types.Bit{1, 1, 0, 1, 0, 0},
+			pattern:         "1x0",
+			expectedMatches: []Match{{Offset: 0}, {Offset: 3}},
+		}, {
+			name:            "hamming errors",
+			bits:            []types.Bit{1, 1, 1, 0, 0, 0},
+			pattern:         "111",
+			maxErrors:       1,
+			expectedMatches: []Match{{Offset: 0}, {Offset: 1, Errors: 1}},
+		}, {
+			name:            "pattern longer than bits",
+			bits:            []types.Bit{1, 1},
+			pattern:         "111",
+			expectedMatches: []Match{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			a, r := assert.New(tt), require.New(tt)
+
+			p, err := ParsePattern(tc.pattern)
+			r.NoError(err)
+
+			a.Equal(tc.expectedMatches, Search(tc.bits, p, tc.maxErrors))
+		})
+	}
+}
+
+func TestSearchLongPatterns(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	bits := make([]types.Bit, 5000)
+	for i := range bits {
+		bits[i] = types.Bit(rng.Intn(2))
+	}
+
+	for _, length := range []int{1, 63, 64, 65, 130} {
+		offset := rng.Intn(len(bits) - length)
+		pattern := []byte{}
+		for _, b := range bits[offset : offset+length] {
+			pattern = append(pattern, b.ToByte())
+		}
+		pattern[length/2] = 'x'
+
+		for _, maxErrors := range []int{0, 2} {
+			p, err := ParsePattern(string(pattern))
+			require.NoError(t, err)
+
+			matches := Search(bits, p, maxErrors)
+			assert.Equal(t, naiveSearch(bits, string(pattern), maxErrors), matches)
+			assert.Contains(t, matches, Match{Offset: offset})
+		}
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	_, err := ParsePattern("10a1")
+	require.ErrorIs(t, err, ErrInvalidPattern)
+
+	_, err = ParsePattern("")
+	require.ErrorIs(t, err, ErrInvalidPattern)
+}
@@ -0,0 +1,114 @@
+package segment
+
+import (
+	"math"
+
+	"github.com/fedemengo/d2bist/pkg/engine"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+// Class is the likely content of a region
+type Class string
+
+const (
+	// Zeros is padding, almost every byte has the same value (usually 0x00 or 0xff)
+	Zeros = Class("zeros")
+	// Text is mostly printable ASCII
+	Text = Class("text")
+	// Code is structured data with redundancy, like machine code or tables
+	Code = Class("code")
+	// Compressed has almost no redundancy, but its bytes are not uniform
+	Compressed = Class("compressed")
+	// Random has uniformly distributed bytes, like encrypted data or random numbers
+	Random = Class("random")
+)
+
+const (
+	// padding is the min fraction of the most frequent byte in Zeros
+	padding = 0.99
+	// printable is the min fraction of printable bytes in Text
+	printable = 0.95
+	// structured is the max byte entropy, in bits per bit, of Code
+	structured = 0.9
+	// uniformBytes is the min number of bytes of Random, with fewer bytes the chi-square
+	// test cannot tell random from compressed
+	uniformBytes = 10 * 256
+	// uniformSigmas is how many deviations the chi-square statistic of Random can be from its mean
+	uniformSigmas = 4
+)
+
+// Classify returns the likely class of the bytes of bits, the trailing bits that do not
+// fill a byte are ignored
+//
+// the class is decided by the byte frequencies: the most frequent byte for Zeros, printable bytes for Text,
+// the byte entropy for Code and a chi-square test of uniformity for Random, the rest is Compressed
+func Classify(bits []types.Bit) Class {
+	counts := [256]int{}
+	n := len(bits) / 8
+	if n == 0 {
+		return Zeros
+	}
+
+	var byteBits [8]types.Bit
+	for i := 0; i < n; i++ {
+		copy(byteBits[:], bits[i*8:i*8+8])
+		counts[engine.BitsToByte(byteBits)]++
+	}
+
+	mostFrequent, text := 0, 0
+	for b, count := range counts {
+		if count > mostFrequent {
+			mostFrequent = count
+		}
+		if isPrintable(byte(b)) {
+			text += count
+		}
+	}
+
+	switch {
+	case float64(mostFrequent) >= padding*float64(n):
+		return Zeros
+	case float64(text) >= printable*float64(n):
+		return Text
+	case byteEntropy(counts, n) < structured:
+		return Code
+	case n >= uniformBytes && isUniform(counts, n):
+		return Random
+	default:
+		return Compressed
+	}
+}
+
+func isPrintable(b byte) bool {
+	return (b >= 0x20 && b < 0x7f) || b == '\t' || b == '\n' || b == '\r'
+}
+
+// byteEntropy is the Shannon entropy of the bytes divided by 8, with the Miller-Madow correction
+// of the bias of the few bytes of short regions
+func byteEntropy(counts [256]int, n int) float64 {
+	entropy, seen := 0.0, 0
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / float64(n)
+			entropy -= p * math.Log2(p)
+			seen++
+		}
+	}
+	entropy += float64(seen-1) / (2 * float64(n) * math.Ln2)
+
+	return math.Min(entropy/8, 1)
+}
+
+// isUniform tests if the byte frequencies are uniform, the chi-square statistic with 255 degrees
+// of freedom has mean 255 and deviation sqrt(2*255)
+func isUniform(counts [256]int, n int) bool {
+	expected := float64(n) / 256
+
+	chi2 := 0.0
+	for _, count := range counts {
+		d := float64(count) - expected
+		chi2 += d * d / expected
+	}
+
+	return chi2 <= 255+uniformSigmas*math.Sqrt(2*255)
+}
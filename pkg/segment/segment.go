@@ -0,0 +1,361 @@
+package segment
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+var ErrUnknownMethod = errors.New("unknown change-point method")
+
+// Method is the change-point detection algorithm
+type Method string
+
+const (
+	// PELT finds the change points that minimize the cost of the segments plus a penalty
+	// for each change point, pruning the candidates that cannot be optimal
+	PELT = Method("pelt")
+	// BinSeg splits the series where the cost decreases the most, until no split pays the penalty
+	BinSeg = Method("binseg")
+	// CUSUM accumulates the deviations from the mean of the segment and starts
+	// a new segment when they exceed a threshold
+	CUSUM = Method("cusum")
+)
+
+const (
+	defaultMinSize = 2
+	// minSigma keeps the penalty positive when the series is piecewise constant
+	minSigma = 1e-3
+	// defaultCUSUMThreshold is the CUSUM threshold in units of the noise deviation
+	defaultCUSUMThreshold = 8
+)
+
+type config struct {
+	method  Method
+	penalty float64
+	minSize int
+}
+
+type Opt func(c *config)
+
+// WithMethod sets the change-point detection algorithm, PELT by default
+func WithMethod(method Method) Opt {
+	return func(c *config) {
+		c.method = method
+	}
+}
+
+// WithPenalty sets how large a change must be to start a new segment, in units of the noise
+// variance for PELT and BinSeg (3*ln(n) by default) and of the noise deviation for CUSUM (8 by default)
+func WithPenalty(penalty float64) Opt {
+	return func(c *config) {
+		c.penalty = penalty
+	}
+}
+
+// WithMinSize sets the min number of values of a segment, 2 by default
+func WithMinSize(minSize int) Opt {
+	return func(c *config) {
+		c.minSize = minSize
+	}
+}
+
+func newConfig(opts []Opt) *config {
+	c := &config{
+		method:  PELT,
+		minSize: defaultMinSize,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func (c *config) validate() error {
+	if c.minSize < 1 {
+		return fmt.Errorf("min segment size %d must be positive", c.minSize)
+	}
+	if c.penalty < 0 {
+		return fmt.Errorf("penalty %g cannot be negative", c.penalty)
+	}
+
+	return nil
+}
+
+// Segment splits bits where the statistics of the entropy series e change, each region
+// has the mean entropy of its windows and the class of its bytes, see Classify
+func Segment(bits []types.Bit, e *types.Entropy, opts ...Opt) ([]types.Region, error) {
+	c := newConfig(opts)
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	if len(e.Values) == 0 {
+		return nil, nil
+	}
+
+	changes, err := changePoints(e.Values, c)
+	if err != nil {
+		return nil, err
+	}
+
+	starts := append([]int{0}, changes...)
+	regions := make([]types.Region, 0, len(starts))
+	for i, start := range starts {
+		end := len(e.Values)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+
+		offset, next := e.Offset(start), len(bits)
+		if end < len(e.Values) {
+			next = e.Offset(end)
+		}
+
+		// the bytes are on the grid of the input, a region may start in the middle of a byte
+		aligned := min((offset+7)/8*8, next)
+
+		regions = append(regions, types.Region{
+			Range:   types.Range{Offset: offset, Length: next - offset},
+			Entropy: mean(e.Values[start:end]),
+			Class:   string(Classify(bits[aligned:next])),
+		})
+	}
+
+	return regions, nil
+}
+
+// ChangePoints returns the indexes of the values that start a new segment, in increasing order
+func ChangePoints(values []float64, opts ...Opt) ([]int, error) {
+	c := newConfig(opts)
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	return changePoints(values, c)
+}
+
+func changePoints(values []float64, c *config) ([]int, error) {
+	sigma := noiseDeviation(values)
+
+	switch c.method {
+	case PELT, BinSeg:
+		penalty := c.penalty
+		if penalty == 0 {
+			penalty = 3 * math.Log(float64(len(values)))
+		}
+		penalty *= sigma * sigma
+
+		cost := newMeanCost(values)
+		if c.method == PELT {
+			return pelt(cost, len(values), penalty, c.minSize), nil
+		}
+		return binSeg(cost, len(values), penalty, c.minSize), nil
+	case CUSUM:
+		threshold := c.penalty
+		if threshold == 0 {
+			threshold = defaultCUSUMThreshold
+		}
+
+		return cusum(values, threshold*sigma, sigma/2, c.minSize), nil
+	default:
+		return nil, fmt.Errorf("method `%s`: %w", c.method, ErrUnknownMethod)
+	}
+}
+
+// meanCost is the sum of the squared deviations from the mean of a segment,
+// the cost of a change in mean of normal values
+type meanCost struct {
+	sum, sumSq []float64
+}
+
+func newMeanCost(values []float64) *meanCost {
+	c := &meanCost{
+		sum:   make([]float64, len(values)+1),
+		sumSq: make([]float64, len(values)+1),
+	}
+	for i, v := range values {
+		c.sum[i+1] = c.sum[i] + v
+		c.sumSq[i+1] = c.sumSq[i] + v*v
+	}
+
+	return c
+}
+
+// cost of the values in [start, end)
+func (c *meanCost) cost(start, end int) float64 {
+	n := float64(end - start)
+	s := c.sum[end] - c.sum[start]
+
+	return math.Max(c.sumSq[end]-c.sumSq[start]-s*s/n, 0)
+}
+
+// pelt is the optimal partitioning of Killick et al. (2012), F[t] is the min cost of the first
+// t values and the candidates whose cost already exceeds F[t] are pruned
+func pelt(c *meanCost, n int, penalty float64, minSize int) []int {
+	if n < 2*minSize {
+		return nil
+	}
+
+	f := make([]float64, n+1)
+	last := make([]int, n+1)
+	f[0] = -penalty
+	for t := 1; t <= n; t++ {
+		f[t] = math.Inf(1)
+	}
+
+	candidates := []int{0}
+	for t := minSize; t <= n; t++ {
+		for _, tau := range candidates {
+			if t-tau < minSize || math.IsInf(f[tau], 1) {
+				continue
+			}
+			if v := f[tau] + c.cost(tau, t) + penalty; v < f[t] {
+				f[t], last[t] = v, tau
+			}
+		}
+
+		pruned := candidates[:0]
+		for _, tau := range candidates {
+			if t-tau < minSize || f[tau]+c.cost(tau, t) <= f[t] {
+				pruned = append(pruned, tau)
+			}
+		}
+		candidates = append(pruned, t-minSize+1)
+	}
+
+	changes := []int{}
+	for t := last[n]; t > 0; t = last[t] {
+		changes = append(changes, t)
+	}
+	sort.Ints(changes)
+
+	return changes
+}
+
+// binSeg splits [start, end) at the point that decreases the cost the most, while the decrease
+// is larger than the penalty
+func binSeg(c *meanCost, n int, penalty float64, minSize int) []int {
+	changes := []int{}
+
+	var split func(start, end int)
+	split = func(start, end int) {
+		best, bestGain := -1, penalty
+		total := c.cost(start, end)
+		for tau := start + minSize; tau <= end-minSize; tau++ {
+			if gain := total - c.cost(start, tau) - c.cost(tau, end); gain > bestGain {
+				best, bestGain = tau, gain
+			}
+		}
+		if best < 0 {
+			return
+		}
+
+		changes = append(changes, best)
+		split(start, best)
+		split(best, end)
+	}
+	split(0, n)
+
+	sort.Ints(changes)
+
+	return changes
+}
+
+// cusum is the two sided CUSUM of Page (1954), the reference is the mean of the segment so far and
+// drift is the deviation that is not accumulated, when the accumulated deviation exceeds threshold the
+// change is placed at the best split of the segment so far, where the next segment starts
+func cusum(values []float64, threshold, drift float64, minSize int) []int {
+	changes := []int{}
+	cost := newMeanCost(values)
+
+	start := 0
+	for start+2*minSize <= len(values) {
+		sum := 0.0
+		for _, v := range values[start : start+minSize] {
+			sum += v
+		}
+
+		alarm := -1
+		up, down := 0.0, 0.0
+		for t := start + minSize; t < len(values); t++ {
+			ref := sum / float64(t-start)
+			up = math.Max(0, up+values[t]-ref-drift)
+			down = math.Max(0, down+ref-values[t]-drift)
+			if up > threshold || down > threshold {
+				alarm = t
+				break
+			}
+			sum += values[t]
+		}
+		if alarm < 0 {
+			break
+		}
+
+		// the new segment has at least minSize values from the alarm
+		end := min(max(alarm+minSize, start+2*minSize), len(values))
+		change, gain := -1, -1.0
+		for tau := start + minSize; tau <= end-minSize; tau++ {
+			if g := cost.cost(start, end) - cost.cost(start, tau) - cost.cost(tau, end); g > gain {
+				change, gain = tau, g
+			}
+		}
+		if change < 0 || len(values)-change < minSize {
+			break
+		}
+
+		changes = append(changes, change)
+		start = change
+	}
+
+	return changes
+}
+
+// noiseDeviation estimates the deviation of the noise from the median absolute difference of
+// consecutive values, which is not affected by the few changes in mean
+func noiseDeviation(values []float64) float64 {
+	if len(values) < 2 {
+		return minSigma
+	}
+
+	diffs := make([]float64, len(values)-1)
+	for i := range diffs {
+		diffs[i] = math.Abs(values[i+1] - values[i])
+	}
+	sort.Float64s(diffs)
+
+	// for normal noise the median of |x1 - x2| is 0.6745 * sqrt(2) * sigma
+	sigma := diffs[len(diffs)/2] / (0.6745 * math.Sqrt2)
+
+	return math.Max(sigma, minSigma)
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+
+	return sum / float64(len(values))
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
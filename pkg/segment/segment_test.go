@@ -0,0 +1,220 @@
+package segment
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fedemengo/d2bist/pkg/engine"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+// steps returns a series with the given means for n values each, with normal noise
+func steps(seed int64, n int, noise float64, means ...float64) []float64 {
+	rnd := rand.New(rand.NewSource(seed))
+
+	values := []float64{}
+	for _, m := range means {
+		for i := 0; i < n; i++ {
+			values = append(values, m+rnd.NormFloat64()*noise)
+		}
+	}
+
+	return values
+}
+
+func bytesToBits(data []byte) []types.Bit {
+	bits := make([]types.Bit, 0, len(data)*8)
+	for _, b := range data {
+		byteBits := engine.ByteToBits(b)
+		bits = append(bits, byteBits[:]...)
+	}
+
+	return bits
+}
+
+func TestChangePoints(t *testing.T) {
+	testCases := []struct {
+		name     string
+		values   []float64
+		expected []int
+	}{
+		{
+			name:     "constant",
+			values:   steps(1, 100, 0.01, 0.5),
+			expected: []int{},
+		}, {
+			name:     "one step",
+			values:   steps(2, 50, 0.01, 0.2, 0.9),
+			expected: []int{50},
+		}, {
+			name:     "three levels",
+			values:   steps(3, 40, 0.02, 0, 1, 0.6),
+			expected: []int{40, 80},
+		}, {
+			name:     "piecewise constant",
+			values:   steps(4, 10, 0, 1, 0, 1),
+			expected: []int{10, 20},
+		},
+	}
+
+	for _, method := range []Method{PELT, BinSeg, CUSUM} {
+		for _, tc := range testCases {
+			t.Run(string(method)+" "+tc.name, func(t *testing.T) {
+				changes, err := ChangePoints(tc.values, WithMethod(method))
+				require.NoError(t, err)
+				assert.Equal(t, tc.expected, changes)
+			})
+		}
+	}
+}
+
+func TestChangePointsOpts(t *testing.T) {
+	values := steps(5, 50, 0.01, 0.2, 0.9)
+
+	_, err := ChangePoints(values, WithMethod("kmeans"))
+	assert.ErrorIs(t, err, ErrUnknownMethod)
+
+	_, err = ChangePoints(values, WithMinSize(0))
+	assert.Error(t, err)
+
+	_, err = ChangePoints(values, WithPenalty(-1))
+	assert.Error(t, err)
+
+	// a penalty larger than the change keeps a single segment
+	changes, err := ChangePoints(values, WithPenalty(1e6))
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestClassify(t *testing.T) {
+	rnd := rand.New(rand.NewSource(6))
+
+	random := make([]byte, 16384)
+	rnd.Read(random)
+
+	// bytes with a skewed but high entropy distribution, like a compressed stream
+	compressed := make([]byte, 16384)
+	for i := range compressed {
+		compressed[i] = byte(rnd.Intn(256) | rnd.Intn(2))
+	}
+
+	// few distinct bytes, like opcodes
+	code := make([]byte, 4096)
+	for i := range code {
+		code[i] = []byte{0x48, 0x89, 0xe5, 0xc3, 0x00, 0x8b, 0x55, 0x90}[rnd.Intn(8)] + byte(rnd.Intn(2))
+	}
+
+	testCases := []struct {
+		name     string
+		data     []byte
+		expected Class
+	}{
+		{
+			name:     "zeros",
+			data:     make([]byte, 4096),
+			expected: Zeros,
+		}, {
+			name:     "erased flash",
+			data:     []byte(strings.Repeat("\xff", 4096)),
+			expected: Zeros,
+		}, {
+			name:     "text",
+			data:     []byte(strings.Repeat("the quick brown fox jumps over the lazy dog\n", 100)),
+			expected: Text,
+		}, {
+			name:     "code",
+			data:     code,
+			expected: Code,
+		}, {
+			name:     "compressed",
+			data:     compressed,
+			expected: Compressed,
+		}, {
+			name:     "random",
+			data:     random,
+			expected: Random,
+		}, {
+			name:     "too short for random",
+			data:     random[:256],
+			expected: Compressed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Classify(bytesToBits(tc.data)))
+		})
+	}
+}
+
+func TestSegment(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	rnd := rand.New(rand.NewSource(7))
+	random := make([]byte, 4096)
+	rnd.Read(random)
+
+	data := append(make([]byte, 2048), []byte(strings.Repeat("hello world ", 512))...)
+	data = append(data, random...)
+	bits := bytesToBits(data)
+
+	// windows of 64 bytes every 32 bytes
+	e := &types.Entropy{Name: types.ShannonEntropy}
+	for offset := 0; offset < len(bits); offset += 256 {
+		e.Offsets = append(e.Offsets, offset)
+		switch {
+		case offset < 2048*8:
+			e.Values = append(e.Values, 0)
+		case offset < (2048+6144)*8:
+			e.Values = append(e.Values, 0.4)
+		default:
+			e.Values = append(e.Values, 1)
+		}
+	}
+
+	regions, err := Segment(bits, e)
+	r.NoError(err)
+	r.Len(regions, 3)
+
+	a.Equal(types.Range{Offset: 0, Length: 2048 * 8}, regions[0].Range)
+	a.Equal(string(Zeros), regions[0].Class)
+	a.Equal(types.Range{Offset: 2048 * 8, Length: 6144 * 8}, regions[1].Range)
+	a.Equal(string(Text), regions[1].Class)
+	a.InDelta(0.4, regions[1].Entropy, 1e-9)
+	a.Equal(types.Range{Offset: (2048 + 6144) * 8, Length: 4096 * 8}, regions[2].Range)
+	a.Equal(string(Random), regions[2].Class)
+
+	regions, err = Segment(bits, &types.Entropy{})
+	r.NoError(err)
+	a.Empty(regions)
+}
+
+func TestSegmentUnaligned(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	data := append(make([]byte, 512), []byte(strings.Repeat("hello world ", 256))...)
+	bits := bytesToBits(data)
+
+	// windows every 100 bits, the text region starts in the middle of a byte
+	e := &types.Entropy{Name: types.ShannonEntropy}
+	for offset := 0; offset < len(bits); offset += 100 {
+		e.Offsets = append(e.Offsets, offset)
+		if offset < 512*8 {
+			e.Values = append(e.Values, 0)
+		} else {
+			e.Values = append(e.Values, 0.4)
+		}
+	}
+
+	regions, err := Segment(bits, e)
+	r.NoError(err)
+	r.Len(regions, 2)
+
+	a.Equal(string(Zeros), regions[0].Class)
+	a.Equal(4100, regions[1].Offset)
+	a.Equal(string(Text), regions[1].Class)
+}
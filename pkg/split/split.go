@@ -0,0 +1,224 @@
+package split
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"github.com/fedemengo/d2bist/pkg/analyze"
+	"github.com/fedemengo/d2bist/pkg/compression"
+	iio "github.com/fedemengo/d2bist/pkg/io"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+// ManifestFile is the name of the manifest written next to the chunks
+const ManifestFile = "manifest.json"
+
+// Chunk is a piece of the input written to File, its range is in bits of the input
+type Chunk struct {
+	Index int    `json:"index"`
+	File  string `json:"file"`
+	types.Range
+	Stats analyze.Summary `json:"stats"`
+}
+
+// Manifest records how the input was split, the chunks are in the order of the input
+type Manifest struct {
+	Bits        int                         `json:"bits"`
+	BinStr      bool                        `json:"binstr"`
+	Compression compression.CompressionType `json:"compression"`
+	Chunks      []Chunk                     `json:"chunks"`
+}
+
+type config struct {
+	binStr      bool
+	cType       compression.CompressionType
+	codecOpts   []compression.Opt
+	summaryOpts []analyze.Opt
+}
+
+type Opt func(c *config)
+
+// WithBinStr writes the chunks as strings of 0s and 1s, by default the chunks are bytes and
+// the last byte of a chunk that is not a multiple of 8 bits is padded with 0s
+func WithBinStr() Opt {
+	return func(c *config) {
+		c.binStr = true
+	}
+}
+
+// WithCompression compresses each chunk with the codec
+func WithCompression(cType compression.CompressionType, opts ...compression.Opt) Opt {
+	return func(c *config) {
+		c.cType = cType
+		c.codecOpts = append(c.codecOpts, opts...)
+	}
+}
+
+// WithSummaryOpts sets how the stats of each chunk are calculated, see analyze.Summarize
+func WithSummaryOpts(opts ...analyze.Opt) Opt {
+	return func(c *config) {
+		c.summaryOpts = append(c.summaryOpts, opts...)
+	}
+}
+
+// BySize splits n bits in chunks of size bits, the last chunk has the remaining bits
+func BySize(n, size int) ([]types.Range, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("chunk size %d must be positive", size)
+	}
+
+	ranges := []types.Range{}
+	for offset := 0; offset < n; offset += size {
+		ranges = append(ranges, types.Range{Offset: offset, Length: min(size, n-offset)})
+	}
+
+	return ranges, nil
+}
+
+// AtBoundaries splits n bits at the increasing bit offsets of boundaries
+func AtBoundaries(n int, boundaries []int) ([]types.Range, error) {
+	ranges := []types.Range{}
+
+	start := 0
+	for _, b := range boundaries {
+		if b <= start || b >= n {
+			return nil, fmt.Errorf("boundary %d must be increasing and within the %d bits", b, n)
+		}
+		ranges = append(ranges, types.Range{Offset: start, Length: b - start})
+		start = b
+	}
+	if start < n {
+		ranges = append(ranges, types.Range{Offset: start, Length: n - start})
+	}
+
+	return ranges, nil
+}
+
+// Write writes the ranges of bits to their own file in dir, with the manifest of the chunks
+func Write(ctx context.Context, dir string, bits []types.Bit, ranges []types.Range, opts ...Opt) (*Manifest, error) {
+	log := zerolog.Ctx(ctx)
+
+	c := &config{
+		cType: compression.None,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{
+		Bits:        len(bits),
+		BinStr:      c.binStr,
+		Compression: c.cType,
+		Chunks:      make([]Chunk, 0, len(ranges)),
+	}
+
+	names := make([]string, 0, len(ranges))
+	chunkBits := map[string][]types.Bit{}
+	for i, rg := range ranges {
+		if rg.Offset < 0 || rg.Length < 1 || rg.Offset+rg.Length > len(bits) {
+			return nil, fmt.Errorf("chunk %d at %d of %d bits is not within the %d bits", i, rg.Offset, rg.Length, len(bits))
+		}
+
+		name := chunkName(i, len(ranges), c)
+		chunk := bits[rg.Offset : rg.Offset+rg.Length]
+		if err := writeChunk(ctx, filepath.Join(dir, name), chunk, c); err != nil {
+			return nil, fmt.Errorf("cannot write chunk %d: %w", i, err)
+		}
+
+		names = append(names, name)
+		chunkBits[name] = chunk
+		m.Chunks = append(m.Chunks, Chunk{Index: i, File: name, Range: rg})
+	}
+
+	read := func(_ context.Context, name string) ([]types.Bit, error) {
+		return chunkBits[name], nil
+	}
+	for i, s := range analyze.Files(ctx, names, read, c.summaryOpts...) {
+		m.Chunks[i].Stats = s
+	}
+
+	log.Trace().Int("chunks", len(m.Chunks)).Str("dir", dir).Msg("chunks written")
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), data, 0o644); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// chunkName is the index of the chunk, padded to sort the files in the order of the input
+func chunkName(i, n int, c *config) string {
+	digits := len(fmt.Sprint(max(n-1, 0)))
+
+	ext := "bin"
+	if c.binStr {
+		ext = "txt"
+	}
+	if c.cType != compression.None {
+		ext += "." + strings.ToLower(string(c.cType))
+	}
+
+	return fmt.Sprintf("chunk-%0*d.%s", digits, i, ext)
+}
+
+func writeChunk(ctx context.Context, path string, bits []types.Bit, c *config) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	bw := bufio.NewWriter(f)
+	w, err := compression.NewCompressedWriter(ctx, bw, c.cType, c.codecOpts...)
+	if err != nil {
+		return err
+	}
+
+	if c.binStr {
+		_, err = io.WriteString(w, iio.BitsToString(bits))
+	} else {
+		err = iio.BitsToByteWriter(ctx, w, bits)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
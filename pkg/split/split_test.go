@@ -0,0 +1,135 @@
+package split
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fedemengo/d2bist/pkg/compression"
+	iio "github.com/fedemengo/d2bist/pkg/io"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+func TestBySize(t *testing.T) {
+	ranges, err := BySize(20, 8)
+	require.NoError(t, err)
+	assert.Equal(t, []types.Range{{Offset: 0, Length: 8}, {Offset: 8, Length: 8}, {Offset: 16, Length: 4}}, ranges)
+
+	ranges, err = BySize(16, 8)
+	require.NoError(t, err)
+	assert.Equal(t, []types.Range{{Offset: 0, Length: 8}, {Offset: 8, Length: 8}}, ranges)
+
+	_, err = BySize(16, 0)
+	assert.Error(t, err)
+}
+
+func TestAtBoundaries(t *testing.T) {
+	ranges, err := AtBoundaries(20, []int{3, 10})
+	require.NoError(t, err)
+	assert.Equal(t, []types.Range{{Offset: 0, Length: 3}, {Offset: 3, Length: 7}, {Offset: 10, Length: 10}}, ranges)
+
+	ranges, err = AtBoundaries(20, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []types.Range{{Offset: 0, Length: 20}}, ranges)
+
+	for _, boundaries := range [][]int{{10, 3}, {0}, {20}, {5, 5}} {
+		_, err = AtBoundaries(20, boundaries)
+		assert.Error(t, err, boundaries)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	ctx := context.Background()
+	data := []byte(strings.Repeat("split me in pieces ", 20))
+	bits, err := iio.BitsFromByteReader(ctx, bytes.NewReader(data))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name  string
+		opts  []Opt
+		ext   string
+		reads func(r *bytes.Reader) ([]types.Bit, error)
+	}{
+		{
+			name: "bytes",
+			ext:  "bin",
+			reads: func(r *bytes.Reader) ([]types.Bit, error) {
+				return iio.BitsFromByteReader(ctx, r)
+			},
+		}, {
+			name: "binstr",
+			opts: []Opt{WithBinStr()},
+			ext:  "txt",
+			reads: func(r *bytes.Reader) ([]types.Bit, error) {
+				return iio.BitsFromBinStrReader(ctx, r)
+			},
+		}, {
+			name: "compressed",
+			opts: []Opt{WithCompression(compression.Zstd)},
+			ext:  "bin.zstd",
+			reads: func(r *bytes.Reader) ([]types.Bit, error) {
+				cr, err := compression.NewCompressedReader(ctx, r, compression.Zstd)
+				if err != nil {
+					return nil, err
+				}
+				return iio.BitsFromByteReader(ctx, cr)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			a, r := assert.New(tt), require.New(tt)
+
+			dir := filepath.Join(tt.TempDir(), "chunks")
+			ranges, err := BySize(len(bits), 128*8)
+			r.NoError(err)
+			r.Len(ranges, 3)
+
+			m, err := Write(ctx, dir, bits, ranges, tc.opts...)
+			r.NoError(err)
+			r.Len(m.Chunks, 3)
+			a.Equal(len(bits), m.Bits)
+
+			// the chunks put back together are the input
+			joined := []types.Bit{}
+			for i, c := range m.Chunks {
+				a.Equal(i, c.Index)
+				a.Equal(fmt.Sprintf("chunk-%d.%s", i, tc.ext), c.File)
+				a.Equal(ranges[i], c.Range)
+				a.Equal(c.Length, c.Stats.Bits)
+				a.Equal(c.File, c.Stats.File)
+				a.NotEmpty(c.Stats.Entropy)
+
+				data, err := os.ReadFile(filepath.Join(dir, c.File))
+				r.NoError(err)
+				chunk, err := tc.reads(bytes.NewReader(data))
+				r.NoError(err)
+				joined = append(joined, chunk...)
+			}
+			a.Equal(bits, joined)
+
+			data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+			r.NoError(err)
+			manifest := &Manifest{}
+			r.NoError(json.Unmarshal(data, manifest))
+			a.Equal(m.Chunks[1].Range, manifest.Chunks[1].Range)
+			a.Equal(m.Chunks[1].File, manifest.Chunks[1].File)
+		})
+	}
+}
+
+func TestWriteInvalidRange(t *testing.T) {
+	bits := make([]types.Bit, 16)
+
+	_, err := Write(context.Background(), t.TempDir(), bits, []types.Range{{Offset: 8, Length: 16}})
+	assert.Error(t, err)
+}
@@ -9,23 +9,20 @@ import (
 
 	"github.com/fedemengo/d2bist/pkg/compression"
 	"github.com/fedemengo/d2bist/pkg/engine"
-	iio "github.com/fedemengo/d2bist/pkg/io"
 	"github.com/fedemengo/d2bist/pkg/types"
 )
 
 func ShannonEntropy(ctx context.Context, bits []types.Bit, chunkSize, symbolLen int) *types.Entropy {
-	shannon := types.NewShannonEntropy()
-	for i := 0; i < len(bits); i += chunkSize {
-		nextBlockSize := min(chunkSize, len(bits)-i)
-		chunk := bits[i : i+nextBlockSize]
-		entropy := shannonEntropy(ctx, chunk, symbolLen)
-		shannon.Values = append(shannon.Values, entropy)
-	}
-
-	return shannon
+	// the shannon estimator does not fail
+	entropy, _ := Estimate(ctx, bits, chunkSize, chunkSize, ShannonEstimator(symbolLen))
+	return entropy
 }
 
 func shannonEntropy(ctx context.Context, chunk []types.Bit, symbolLen int) float64 {
+	if symbolLen > maxIntSymbolLen {
+		return wideShannonEntropy(ctx, chunk, symbolLen)
+	}
+
 	log := zerolog.Ctx(ctx).With().Logger()
 
 	bw := engine.NewBitsWindow(chunk, symbolLen)
@@ -69,24 +66,101 @@ func shannonEntropy(ctx context.Context, chunk []types.Bit, symbolLen int) float
 	return entropy
 }
 
-func CompressionEntropy(ctx context.Context, bits []types.Bit, chunkSize, _ int, cType compression.CompressionType) *types.Entropy {
-	compr := types.NewCompressionEntropy(cType)
+// maxIntSymbolLen is the longest symbol that fits the uint64 of a BitsWindow
+const maxIntSymbolLen = 64
 
-	for i := 0; i < len(bits); i += chunkSize {
-		nextBlockSize := min(chunkSize, len(bits)-i)
-		chunk := bits[i : i+nextBlockSize]
+// wideShannonEntropy is the shannon entropy of symbols longer than 64 bits, counted by their bit string
+func wideShannonEntropy(ctx context.Context, chunk []types.Bit, symbolLen int) float64 {
+	log := zerolog.Ctx(ctx)
 
-		e := float64(0)
-		cr, err := iio.BitsToReader(ctx, chunk, cType)
-		if err == nil {
-			e = float64(cr.Size()*8) / float64(len(chunk))
-		}
-		if e > 1 {
-			e = 1
-		}
+	counts := make(map[string]int, len(chunk)/symbolLen)
+	for i := 0; i+symbolLen <= len(chunk); i += symbolLen {
+		counts[engine.BitsToString(chunk[i:i+symbolLen])]++
+	}
+
+	symbolsCount := float64(len(chunk)) / float64(symbolLen)
+	entropy := float64(0)
+	for _, count := range counts {
+		pX := float64(count) / symbolsCount
+		entropy -= pX * math.Log2(pX)
+	}
+
+	// normalize the entropy in the range [0, 1]
+	entropy /= math.Log2(symbolsCount)
+
+	log.Debug().Float64("entropy", entropy).Int("symbolLen", symbolLen).Msg("entropy for chunk of wide symbols calculated")
+
+	return entropy
+}
+
+// CompressionEntropy is the ratio of compressed to raw bits of each chunk, capped at 1
+func CompressionEntropy(ctx context.Context, bits []types.Bit, chunkSize, _ int, cType compression.CompressionType, opts ...compression.Opt) (*types.Entropy, error) {
+	return Estimate(ctx, bits, chunkSize, chunkSize, CompressionEstimator(cType, opts...))
+}
+
+// slidingShannon keeps the symbol counts of a window of bits, the tail window reads the symbols
+// that leave the window and the head window the ones that enter it
+type slidingShannon struct {
+	head, tail engine.BitsWindow
+	symbolLen  int
+
+	counts  map[uint64]int
+	symbols int
+	// sumCLogC is the sum of c*log2(c) over the symbol counts
+	sumCLogC float64
+}
+
+func newSlidingShannon(bits []types.Bit, symbolLen int) *slidingShannon {
+	return &slidingShannon{
+		head:      engine.NewBitsWindow(bits, symbolLen),
+		tail:      engine.NewBitsWindow(bits, symbolLen),
+		symbolLen: symbolLen,
+		counts:    map[uint64]int{},
+	}
+}
+
+// grow adds n symbols at the head of the window
+func (s *slidingShannon) grow(n int) {
+	for i := 0; i < n; i++ {
+		s.add(s.head.ToInt(), 1)
+		// the head only fails to slide past the last symbol, which is never read
+		_ = s.head.SlideBy(s.symbolLen)
+	}
+	s.symbols += n
+}
+
+// slide moves the window by n symbols
+func (s *slidingShannon) slide(n int) {
+	for i := 0; i < n; i++ {
+		s.add(s.tail.ToInt(), -1)
+		_ = s.tail.SlideBy(s.symbolLen)
+	}
+	s.symbols -= n
+
+	s.grow(n)
+}
+
+func (s *slidingShannon) add(symbol uint64, delta int) {
+	c := s.counts[symbol]
+	s.sumCLogC -= cLogC(c)
+	c += delta
+	s.sumCLogC += cLogC(c)
+	s.counts[symbol] = c
+}
+
+// entropy is the Shannon entropy of the window normalized in [0, 1] like shannonEntropy,
+// -sum(p*log2(p)) = log2(N) - sum(c*log2(c))/N
+func (s *slidingShannon) entropy() float64 {
+	n := float64(s.symbols)
+	logN := math.Log2(n)
+
+	return (logN - s.sumCLogC/n) / logN
+}
 
-		compr.Values = append(compr.Values, e)
+func cLogC(c int) float64 {
+	if c <= 0 {
+		return 0
 	}
 
-	return compr
+	return float64(c) * math.Log2(float64(c))
 }
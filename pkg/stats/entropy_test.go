@@ -82,6 +82,13 @@ func TestShannongEntropy(t *testing.T) {
 		bits32[i], _ = engine.IntToBits(uint64(i), 5)
 	}
 
+	// 128 bits symbols that differ only in their first byte
+	wide := make([][]types.Bit, 8)
+	for i := range wide {
+		wide[i], _ = engine.IntToBits(uint64(i), 8)
+		wide[i] = append(wide[i], nZeros(120)...)
+	}
+
 	testCases := []struct {
 		name          string
 		bits          []types.Bit
@@ -128,6 +135,18 @@ func TestShannongEntropy(t *testing.T) {
 			expectedLen:   24,
 			lenSymbol:     3,
 			expectedValue: 1,
+		}, {
+			name:          "wide symbols zero entropy, 1024 bits, 128 bit symbol",
+			bits:          nZeros(1024),
+			expectedLen:   1024,
+			lenSymbol:     128,
+			expectedValue: 0,
+		}, {
+			name:          "wide symbols max entropy, 1024 bits, 128 bit symbol",
+			bits:          flattenBits(wide),
+			expectedLen:   1024,
+			lenSymbol:     128,
+			expectedValue: 1,
 		},
 	}
 
@@ -0,0 +1,289 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/fedemengo/d2bist/pkg/compression"
+	iio "github.com/fedemengo/d2bist/pkg/io"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+var (
+	ErrUnknownEstimator = errors.New("unknown estimator")
+	ErrEstimatorExists  = errors.New("estimator already registered")
+)
+
+// Estimator measures the entropy, or complexity, of a chunk of bits as a value in [0, 1]
+//
+// Estimate is called concurrently when several inputs are analysed
+type Estimator interface {
+	// Name names the series of the estimator
+	Name() types.EntropyType
+	Estimate(ctx context.Context, chunk []types.Bit) (float64, error)
+}
+
+// EstimatorFunc adapts a function to an Estimator named name
+func EstimatorFunc(name types.EntropyType, estimate func(ctx context.Context, chunk []types.Bit) (float64, error)) Estimator {
+	return &funcEstimator{name: name, estimate: estimate}
+}
+
+type funcEstimator struct {
+	name     types.EntropyType
+	estimate func(ctx context.Context, chunk []types.Bit) (float64, error)
+}
+
+func (e *funcEstimator) Name() types.EntropyType {
+	return e.name
+}
+
+func (e *funcEstimator) Estimate(ctx context.Context, chunk []types.Bit) (float64, error) {
+	return e.estimate(ctx, chunk)
+}
+
+// ShannonEstimator is the Shannon entropy of the symbols of symbolLen bits
+func ShannonEstimator(symbolLen int) Estimator {
+	return &shannonEstimator{symbolLen: symbolLen}
+}
+
+type shannonEstimator struct {
+	symbolLen int
+}
+
+func (e *shannonEstimator) Name() types.EntropyType {
+	return types.ShannonEntropy
+}
+
+func (e *shannonEstimator) Estimate(ctx context.Context, chunk []types.Bit) (float64, error) {
+	return shannonEntropy(ctx, chunk, e.symbolLen), nil
+}
+
+// EstimateWindows counts only the symbols that leave and enter the window as it slides,
+// when windows overlap and symbols are aligned with the stride
+func (e *shannonEstimator) EstimateWindows(ctx context.Context, bits []types.Bit, chunkSize, stride int) []float64 {
+	offsets := windowOffsets(len(bits), chunkSize, stride)
+
+	values := make([]float64, 0, len(offsets))
+	if stride >= chunkSize || e.symbolLen > maxIntSymbolLen || stride%e.symbolLen != 0 || chunkSize%e.symbolLen != 0 || chunkSize > len(bits) {
+		for _, offset := range offsets {
+			values = append(values, shannonEntropy(ctx, bits[offset:min(offset+chunkSize, len(bits))], e.symbolLen))
+		}
+		return values
+	}
+
+	sw := newSlidingShannon(bits, e.symbolLen)
+	sw.grow(chunkSize / e.symbolLen)
+	values = append(values, sw.entropy())
+
+	for _, offset := range offsets[1:] {
+		if offset+chunkSize > len(bits) {
+			values = append(values, shannonEntropy(ctx, bits[offset:], e.symbolLen))
+			break
+		}
+
+		sw.slide(stride / e.symbolLen)
+		values = append(values, sw.entropy())
+	}
+
+	return values
+}
+
+// CompressionEstimator is the ratio of compressed to raw bits, capped at 1
+func CompressionEstimator(cType compression.CompressionType, opts ...compression.Opt) Estimator {
+	return &compressionEstimator{cType: cType, opts: opts}
+}
+
+type compressionEstimator struct {
+	cType compression.CompressionType
+	opts  []compression.Opt
+}
+
+func (e *compressionEstimator) Name() types.EntropyType {
+	return types.EntropyType(e.cType)
+}
+
+func (e *compressionEstimator) Estimate(ctx context.Context, chunk []types.Bit) (float64, error) {
+	cr, err := iio.BitsToReader(ctx, chunk, e.cType, e.opts...)
+	if err != nil {
+		return 0, fmt.Errorf("cannot compress chunk: %w", err)
+	}
+	if len(chunk) == 0 {
+		return 0, nil
+	}
+
+	return math.Min(float64(cr.Size()*8)/float64(len(chunk)), 1), nil
+}
+
+// withCodecOpts returns a copy of e that also uses opts, the options of e take precedence
+func (e *compressionEstimator) withCodecOpts(opts ...compression.Opt) *compressionEstimator {
+	return &compressionEstimator{
+		cType: e.cType,
+		opts:  append(append([]compression.Opt{}, opts...), e.opts...),
+	}
+}
+
+// LZ76Estimator is the Lempel-Ziv (1976) complexity of the chunk, the number of distinct
+// phrases of its parsing normalized by n/log2(n), capped at 1
+func LZ76Estimator() Estimator {
+	return lz76Estimator{}
+}
+
+type lz76Estimator struct{}
+
+func (lz76Estimator) Name() types.EntropyType {
+	return types.LZ76Complexity
+}
+
+func (lz76Estimator) Estimate(_ context.Context, chunk []types.Bit) (float64, error) {
+	n := len(chunk)
+	if n < 2 {
+		return 0, nil
+	}
+
+	c := lz76Complexity(chunk)
+
+	return math.Min(float64(c)*math.Log2(float64(n))/float64(n), 1), nil
+}
+
+// lz76Complexity counts the phrases of the LZ76 parsing of s, with the Kaspar-Schuster algorithm
+//
+// a phrase is the shortest substring that is not a copy of one that starts earlier
+func lz76Complexity(s []types.Bit) int {
+	n := len(s)
+	if n == 0 {
+		return 0
+	}
+
+	// l is the start of the current phrase, i the start of the copy being tried,
+	// k the length of the match and kMax the longest match of the current phrase
+	c, l, i, k, kMax := 1, 1, 0, 1, 1
+	for l+k <= n {
+		if s[i+k-1] == s[l+k-1] {
+			k++
+			if l+k > n {
+				c++
+			}
+			continue
+		}
+
+		kMax = max(kMax, k)
+		i++
+		if i < l {
+			k = 1
+			continue
+		}
+
+		// no earlier copy, the phrase ends here
+		c++
+		l += kMax
+		i, k, kMax = 0, 1, 1
+	}
+
+	return c
+}
+
+var estimators = struct {
+	sync.RWMutex
+	byName map[string]func(symbolLen int) Estimator
+}{
+	byName: map[string]func(symbolLen int) Estimator{
+		"shannon": ShannonEstimator,
+		"lz76": func(int) Estimator {
+			return LZ76Estimator()
+		},
+	},
+}
+
+// RegisterEstimator makes the estimator created by newEstimator selectable by name, see NewEstimator
+func RegisterEstimator(name string, newEstimator func(symbolLen int) Estimator) error {
+	estimators.Lock()
+	defer estimators.Unlock()
+
+	if _, ok := estimators.byName[name]; ok {
+		return fmt.Errorf("estimator `%s`: %w", name, ErrEstimatorExists)
+	}
+	if _, err := compression.Parse(name); err == nil {
+		return fmt.Errorf("estimator `%s` names a codec: %w", name, ErrEstimatorExists)
+	}
+
+	estimators.byName[name] = newEstimator
+
+	return nil
+}
+
+// NewEstimator returns the estimator selected by name: shannon, lz76, a registered estimator
+// or the compression ratio of a codec, named by one of its aliases
+func NewEstimator(name string, symbolLen int) (Estimator, error) {
+	estimators.RLock()
+	newEstimator, ok := estimators.byName[name]
+	estimators.RUnlock()
+	if ok {
+		return newEstimator(symbolLen), nil
+	}
+
+	cType, err := compression.Parse(name)
+	if err != nil || cType == compression.None || cType == compression.Auto {
+		return nil, fmt.Errorf("estimator `%s`: %w", name, ErrUnknownEstimator)
+	}
+
+	return CompressionEstimator(cType), nil
+}
+
+// DefaultEstimators are the codecs of compression.EntropyTypes (gzip, brotli and bzip2) and Shannon
+func DefaultEstimators(symbolLen int) []Estimator {
+	ests := []Estimator{}
+	for _, cType := range compression.EntropyTypes() {
+		ests = append(ests, CompressionEstimator(cType))
+	}
+
+	return append(ests, ShannonEstimator(symbolLen))
+}
+
+// SlidingEstimator is an Estimator that updates its estimate as the window slides, instead of
+// estimating each window from scratch
+type SlidingEstimator interface {
+	Estimator
+	// EstimateWindows estimates the windows of chunkSize bits every stride bits, see windowOffsets
+	EstimateWindows(ctx context.Context, bits []types.Bit, chunkSize, stride int) []float64
+}
+
+// Estimate calculates the series of e on windows of chunkSize bits every stride bits,
+// the windows overlap when stride < chunkSize, it fails on the first window that e cannot estimate
+func Estimate(ctx context.Context, bits []types.Bit, chunkSize, stride int, e Estimator) (*types.Entropy, error) {
+	entropy := &types.Entropy{
+		Name:    e.Name(),
+		Offsets: windowOffsets(len(bits), chunkSize, stride),
+	}
+
+	if se, ok := e.(SlidingEstimator); ok {
+		entropy.Values = se.EstimateWindows(ctx, bits, chunkSize, stride)
+		return entropy, nil
+	}
+
+	for _, offset := range entropy.Offsets {
+		v, err := e.Estimate(ctx, bits[offset:min(offset+chunkSize, len(bits))])
+		if err != nil {
+			return nil, fmt.Errorf("estimator `%s` at bit %d: %w", e.Name(), offset, err)
+		}
+		entropy.Values = append(entropy.Values, v)
+	}
+
+	return entropy, nil
+}
+
+// windowOffsets returns the offsets of the windows of chunkSize bits every stride bits, up to
+// the first window that reaches the end of the n bits, which is shorter when it does not fit
+func windowOffsets(n, chunkSize, stride int) []int {
+	offsets := []int{}
+	for offset := 0; offset < n; offset += stride {
+		offsets = append(offsets, offset)
+		if offset+chunkSize >= n {
+			break
+		}
+	}
+
+	return offsets
+}
@@ -0,0 +1,242 @@
+package stats
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fedemengo/d2bist/pkg/compression"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+func bitsOf(s string) []types.Bit {
+	bits := make([]types.Bit, len(s))
+	for i, c := range s {
+		bits[i] = types.Bit(c - '0')
+	}
+
+	return bits
+}
+
+func TestLZ76Complexity(t *testing.T) {
+	testCases := []struct {
+		name     string
+		bits     string
+		expected int
+	}{
+		{
+			name:     "empty",
+			bits:     "",
+			expected: 0,
+		}, {
+			name:     "single bit",
+			bits:     "1",
+			expected: 1,
+		}, {
+			name:     "constant",
+			bits:     "0000000000",
+			expected: 2,
+		}, {
+			name:     "alternating",
+			bits:     "0101010101",
+			expected: 3,
+		}, {
+			// 0 · 001 · 10 · 100 · 1000 · 101
+			name:     "kaspar schuster",
+			bits:     "0001101001000101",
+			expected: 6,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, lz76Complexity(bitsOf(tc.bits)))
+		})
+	}
+}
+
+func TestLZ76Estimator(t *testing.T) {
+	ctx := context.Background()
+	rnd := rand.New(rand.NewSource(42))
+
+	random := make([]types.Bit, 8192)
+	for i := range random {
+		random[i] = types.Bit(rnd.Intn(2))
+	}
+
+	e := LZ76Estimator()
+	assert.Equal(t, types.LZ76Complexity, e.Name())
+	v, err := e.Estimate(ctx, make([]types.Bit, 8192))
+	require.NoError(t, err)
+	assert.Less(t, v, 0.01)
+
+	v, err = e.Estimate(ctx, random)
+	require.NoError(t, err)
+	assert.Greater(t, v, 0.9)
+}
+
+func TestEntropiesEstimators(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+	ctx := context.Background()
+
+	bits := repetitiveBits(1, 4096, 64, 8)[:4096]
+
+	ones := EstimatorFunc("ones", func(_ context.Context, chunk []types.Bit) (float64, error) {
+		n := 0
+		for _, b := range chunk {
+			n += int(b)
+		}
+		return float64(n) / float64(len(chunk)), nil
+	})
+
+	entropies, err := Entropies(ctx, bits, 1024, 2, WithEstimators(LZ76Estimator(), ones, CompressionEstimator(compression.Zstd)))
+	r.NoError(err)
+	r.Len(entropies, 3)
+	a.Equal(types.LZ76Complexity, entropies[0].Name)
+	a.Equal(types.EntropyType("ones"), entropies[1].Name)
+	a.Equal(types.ZstdEntropy, entropies[2].Name)
+	for _, e := range entropies {
+		a.Len(e.Values, 4)
+	}
+
+	defaults, err := Entropies(ctx, bits, 1024, 2)
+	r.NoError(err)
+	r.Len(defaults, 4)
+	a.Equal(types.GzipEntropy, defaults[0].Name)
+	a.Equal(types.BrotliEntropy, defaults[1].Name)
+	a.Equal(types.Bzip2Entropy, defaults[2].Name)
+	a.Equal(types.ShannonEntropy, defaults[3].Name)
+
+	// a codec that fails fails the series, instead of estimating a zero entropy
+	_, err = Entropies(ctx, bits, 1024, 2, WithEstimators(CompressionEstimator(compression.Zstd, compression.WithLevel(9))))
+	a.Error(err)
+}
+
+func TestRegisterEstimator(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	half := func(int) Estimator {
+		return EstimatorFunc("half", func(context.Context, []types.Bit) (float64, error) {
+			return 0.5, nil
+		})
+	}
+
+	r.NoError(RegisterEstimator("half", half))
+	a.ErrorIs(RegisterEstimator("half", half), ErrEstimatorExists)
+	a.ErrorIs(RegisterEstimator("lz76", half), ErrEstimatorExists)
+	a.ErrorIs(RegisterEstimator("gzip", half), ErrEstimatorExists)
+
+	e, err := NewEstimator("half", 2)
+	r.NoError(err)
+	a.Equal(types.EntropyType("half"), e.Name())
+
+	e, err = NewEstimator("bz2", 2)
+	r.NoError(err)
+	a.Equal(types.Bzip2Entropy, e.Name())
+
+	_, err = NewEstimator("rar", 2)
+	a.ErrorIs(err, ErrUnknownEstimator)
+}
+
+func TestWindowOffsets(t *testing.T) {
+	testCases := []struct {
+		name      string
+		n         int
+		chunkSize int
+		stride    int
+		expected  []int
+	}{
+		{
+			name:      "disjoint",
+			n:         10,
+			chunkSize: 4,
+			stride:    4,
+			expected:  []int{0, 4, 8},
+		}, {
+			name:      "overlapping",
+			n:         10,
+			chunkSize: 4,
+			stride:    2,
+			expected:  []int{0, 2, 4, 6},
+		}, {
+			name:      "overlapping last window shorter",
+			n:         11,
+			chunkSize: 4,
+			stride:    3,
+			expected:  []int{0, 3, 6, 9},
+		}, {
+			name:      "chunk larger than bits",
+			n:         3,
+			chunkSize: 8,
+			stride:    2,
+			expected:  []int{0},
+		}, {
+			name:      "empty",
+			n:         0,
+			chunkSize: 8,
+			stride:    2,
+			expected:  []int{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, windowOffsets(tc.n, tc.chunkSize, tc.stride))
+		})
+	}
+}
+
+func TestSlidingShannon(t *testing.T) {
+	ctx := context.Background()
+	bits := repetitiveBits(7, 3000, 48, 10)[:3001]
+
+	testCases := []struct {
+		chunkSize int
+		stride    int
+		symbolLen int
+	}{
+		{chunkSize: 256, stride: 256, symbolLen: 2},
+		{chunkSize: 256, stride: 64, symbolLen: 2},
+		{chunkSize: 256, stride: 8, symbolLen: 8},
+		{chunkSize: 300, stride: 6, symbolLen: 3},
+		{chunkSize: 256, stride: 3, symbolLen: 2},
+		{chunkSize: 4096, stride: 8, symbolLen: 2},
+	}
+
+	for _, tc := range testCases {
+		e := ShannonEstimator(tc.symbolLen).(SlidingEstimator)
+		values := e.EstimateWindows(ctx, bits, tc.chunkSize, tc.stride)
+
+		offsets := windowOffsets(len(bits), tc.chunkSize, tc.stride)
+		require.Len(t, values, len(offsets))
+		for i, offset := range offsets {
+			expected := shannonEntropy(ctx, bits[offset:min(offset+tc.chunkSize, len(bits))], tc.symbolLen)
+			assert.InDelta(t, expected, values[i], 1e-9, "chunk %d stride %d slen %d offset %d", tc.chunkSize, tc.stride, tc.symbolLen, offset)
+		}
+	}
+}
+
+func TestEntropiesStride(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+	ctx := context.Background()
+
+	bits := repetitiveBits(3, 2048, 64, 4)[:2048]
+
+	entropies, err := Entropies(ctx, bits, 512, 2, WithStride(128), WithEstimators(ShannonEstimator(2), LZ76Estimator()))
+	r.NoError(err)
+	r.Len(entropies, 2)
+	for _, e := range entropies {
+		a.Equal([]int{0, 128, 256, 384, 512, 640, 768, 896, 1024, 1152, 1280, 1408, 1536}, e.Offsets)
+		a.Len(e.Values, len(e.Offsets))
+	}
+
+	disjoint, err := Entropies(ctx, bits, 512, 2, WithEstimators(ShannonEstimator(2)))
+	r.NoError(err)
+	a.Equal([]int{0, 512, 1024, 1536}, disjoint[0].Offsets)
+	for i, offset := range disjoint[0].Offsets {
+		a.InDelta(disjoint[0].Values[i], entropies[0].Values[offset/128], 1e-9)
+	}
+}
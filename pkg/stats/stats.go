@@ -2,14 +2,12 @@ package stats
 
 import (
 	"context"
-	"sort"
 
 	"github.com/rs/zerolog"
 
 	"github.com/fedemengo/go-data-structures/heap"
 
 	"github.com/fedemengo/d2bist/pkg/compression"
-	"github.com/fedemengo/d2bist/pkg/engine"
 	"github.com/fedemengo/d2bist/pkg/types"
 )
 
@@ -24,6 +22,11 @@ type analysisOpt struct {
 	maxBlockSize int
 	blockSize    int
 	symbolLen    int
+	sketchWidth  int
+	sketchDepth  int
+	codecOpts    map[compression.CompressionType][]compression.Opt
+	estimators   []Estimator
+	stride       int
 }
 
 type Opt func(*analysisOpt)
@@ -52,9 +55,44 @@ func WithSymbolLen(chunkSize int) Opt {
 	}
 }
 
+// WithApproximate estimates the top K substrings with a count-min sketch of width x depth counters
+func WithApproximate(width, depth int) Opt {
+	return func(o *analysisOpt) {
+		o.sketchWidth = width
+		o.sketchDepth = depth
+	}
+}
+
+// WithCodecOpts tunes the codec of the cType compression entropy
+func WithCodecOpts(cType compression.CompressionType, opts ...compression.Opt) Opt {
+	return func(o *analysisOpt) {
+		if o.codecOpts == nil {
+			o.codecOpts = map[compression.CompressionType][]compression.Opt{}
+		}
+		o.codecOpts[cType] = append(o.codecOpts[cType], opts...)
+	}
+}
+
+// WithEstimators replaces the default estimators of the entropy series, see DefaultEstimators
+func WithEstimators(estimators ...Estimator) Opt {
+	return func(o *analysisOpt) {
+		o.estimators = append(o.estimators, estimators...)
+	}
+}
+
+// WithStride calculates the entropy on windows every stride bits, by default the windows are disjoint
+func WithStride(stride int) Opt {
+	return func(o *analysisOpt) {
+		o.stride = stride
+	}
+}
+
 // AnalizeBits count the occurences of bit string of different length
 //
 // Using a sliding window, bits string up to length = L (4) are counted in O(N), O(L*N) in general
+//
+// windows longer than 64 bits are identified by a rolling hash, and only the top K are kept,
+// with WithApproximate the counts are estimated with a count-min sketch in bounded memory
 func AnalizeBits(ctx context.Context, bits []types.Bit, opts ...Opt) *types.Stats {
 	log := zerolog.Ctx(ctx)
 
@@ -75,87 +113,42 @@ func AnalizeBits(ctx context.Context, bits []types.Bit, opts ...Opt) *types.Stat
 	}
 
 	var windows []int
-	var accumulators []uint64
-	counterForLen := map[int]map[uint64]int{}
 
 	calculateEntropy := false
 	// if blockSize is set, calculate entropy for that window size
 	if o.blockSize > 0 {
 		calculateEntropy = true
-		accumulators = make([]uint64, 1)
 		windows = []int{o.blockSize}
-		counterForLen[o.blockSize] = make(map[uint64]int, o.blockSize)
 	} else {
-		accumulators = make([]uint64, o.maxBlockSize)
-		for i := range accumulators {
+		for i := 0; i < o.maxBlockSize; i++ {
 			windows = append(windows, i+1)
-			counterForLen[i+1] = map[uint64]int{}
 		}
 	}
 
 	log.Info().
 		Ints("windows", windows).
 		Bool("entropyCalc", calculateEntropy).
+		Bool("approximate", o.sketchWidth > 0).
 		Int("symbolLen", o.symbolLen).
 		Msg("counting bit strings")
 
 	// count all bit strings of length windowSize
-	for j, windowSize := range windows {
+	for _, windowSize := range windows {
 		log.Trace().
 			Int("windowSize", windowSize).
 			Msg("counting bit strings")
-		// count all bit strings of length windowSize
-		for i, b := range bits {
-			if i%8_000 == 0 {
-				log.Trace().
-					Int("windowSize", windowSize).
-					Int("bitsCount", i).
-					Int("totalBits", len(bits)).
-					Msg("bits processed")
-			}
-
-			accumulators[j] &= ^(1 << j) // clear exiting bits
-			accumulators[j] <<= 1        // align all j-1 bits
-			accumulators[j] |= uint64(b) // add new bit
-
-			// window is not full yet
-			if i+1 < windowSize {
-				continue
-			}
-
-			counterForLen[windowSize][accumulators[j]]++
-		}
-	}
 
-	// select top K most frequent bit strings
-	for _, windowSize := range windows {
-		topKSelected := getTopKFreqSubstrs(o.topKFreq, counterForLen[windowSize])
-
-		strAllCount := map[string]int{}
-		strTopKSelected := map[string]int{}
-		strSubstrs := []string{}
-
-		for substr, count := range counterForLen[windowSize] {
-			s, err := engine.IntToBitString(substr, windowSize)
-			if err != nil {
-				log.Fatal().Err(err).Msg("error converting int to bit string")
-			}
-
-			strSubstrs = append(strSubstrs, s)
-			strAllCount[s] = count
-			if _, ok := topKSelected[substr]; ok {
-				strTopKSelected[s] = count
-			}
+		var substrCount types.SubstrCount
+		switch {
+		case o.sketchWidth > 0:
+			substrCount = approxCountSubstrs(ctx, bits, windowSize, o.topKFreq, o.sketchWidth, o.sketchDepth)
+		case windowSize <= maxIntWindow:
+			substrCount = countShortSubstrs(ctx, bits, windowSize, o.topKFreq)
+		default:
+			substrCount = countLongSubstrs(ctx, bits, windowSize, o.topKFreq)
 		}
-		sort.Strings(strSubstrs)
 
-		stats.SubstrsCount = append(stats.SubstrsCount, types.SubstrCount{
-			Length:        windowSize,
-			Total:         len(counterForLen[windowSize]),
-			AllCounts:     strAllCount,
-			SortedSubstrs: strSubstrs,
-			Counts:        strTopKSelected,
-		})
+		stats.SubstrsCount = append(stats.SubstrsCount, substrCount)
 	}
 
 	if !calculateEntropy {
@@ -167,36 +160,70 @@ func AnalizeBits(ctx context.Context, bits []types.Bit, opts ...Opt) *types.Stat
 		Bool("entropyCalc", calculateEntropy).
 		Msg("calculating entropy")
 
-	stats.Entropy = append(
-		stats.Entropy,
-		CompressionEntropy(ctx, bits, o.blockSize, o.symbolLen, compression.Gzip),
-		CompressionEntropy(ctx, bits, o.blockSize, o.symbolLen, compression.Brotli),
-		CompressionEntropy(ctx, bits, o.blockSize, o.symbolLen, compression.Bzip2),
-		ShannonEntropy(ctx, bits, o.blockSize, o.symbolLen),
-	)
+	entropies, err := Entropies(ctx, bits, o.blockSize, o.symbolLen, opts...)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot calculate entropy")
+		return stats
+	}
+	stats.Entropy = append(stats.Entropy, entropies...)
 
 	log.Trace().Msg("done bits analysis")
 
 	return stats
 }
 
+// Entropies calculates the entropy of each window of chunkSize bits with every estimator,
+// by default the codecs of compression.EntropyTypes and Shannon
+//
+// only the codec options, the estimators and the stride of opts are used
+func Entropies(ctx context.Context, bits []types.Bit, chunkSize, symbolLen int, opts ...Opt) ([]*types.Entropy, error) {
+	o := &analysisOpt{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	stride := o.stride
+	if stride <= 0 {
+		stride = chunkSize
+	}
+
+	estimators := o.estimators
+	if len(estimators) == 0 {
+		estimators = DefaultEstimators(symbolLen)
+	}
+
+	entropies := make([]*types.Entropy, 0, len(estimators))
+	for _, e := range estimators {
+		if ce, ok := e.(*compressionEstimator); ok && len(o.codecOpts[ce.cType]) > 0 {
+			e = ce.withCodecOpts(o.codecOpts[ce.cType]...)
+		}
+		entropy, err := Estimate(ctx, bits, chunkSize, stride, e)
+		if err != nil {
+			return nil, err
+		}
+		entropies = append(entropies, entropy)
+	}
+
+	return entropies, nil
+}
+
 func getTopKFreqSubstrs(k int, counterForLen map[uint64]int) map[uint64]int {
 	if k <= 0 {
 		return counterForLen
 	}
 
-	// max heap
+	// min heap, the front is the least frequent of the top K
 	topK := heap.NewHeap(func(e1, e2 heap.Elem) bool {
 		return e1.Key.(int) < e2.Key.(int)
 	})
 
 	// select top K bit strings of length j+1
 	//
-	// by putting all elements in a max heap
+	// by replacing the least frequent selected one with more frequent elements
 	for substr, count := range counterForLen {
 		if topK.Size() < k {
 			topK.Push(heap.Elem{Key: count, Val: substr})
-		} else if count < topK.Front().Key.(int) {
+		} else if count > topK.Front().Key.(int) {
 			topK.Pop()
 			topK.Push(heap.Elem{Key: count, Val: substr})
 		}
@@ -217,3 +244,10 @@ func min(a, b int) int {
 	}
 	return b
 }
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,313 @@
+package stats
+
+import (
+	"context"
+	"math/bits"
+	"sort"
+
+	"github.com/rs/zerolog"
+
+	"github.com/fedemengo/go-data-structures/heap"
+
+	"github.com/fedemengo/d2bist/pkg/engine"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+const (
+	DefaultSketchWidth = 1 << 20
+	DefaultSketchDepth = 4
+
+	// windows up to maxIntWindow bits are identified by their value
+	maxIntWindow = 64
+
+	// rolling hashes are computed modulo the Mersenne prime 2^61-1
+	hashMod  = 1<<61 - 1
+	hashBase = 0x1f3a_9c5e_77d1_0b63 % hashMod
+)
+
+// forEachWindow calls fn with the offset and the key of every window of windowSize bits
+//
+// windows up to 64 bits are keyed by their value, longer windows by a polynomial rolling hash
+func forEachWindow(ctx context.Context, bitsArr []types.Bit, windowSize int, fn func(offset int, key uint64)) {
+	log := zerolog.Ctx(ctx)
+
+	if windowSize <= maxIntWindow {
+		mask := ^uint64(0) >> (64 - windowSize)
+		acc := uint64(0)
+		for i, b := range bitsArr {
+			if i%8_000 == 0 {
+				log.Trace().
+					Int("windowSize", windowSize).
+					Int("bitsCount", i).
+					Int("totalBits", len(bitsArr)).
+					Msg("bits processed")
+			}
+
+			acc = (acc<<1 | uint64(b)) & mask
+
+			// window is not full yet
+			if i+1 < windowSize {
+				continue
+			}
+
+			fn(i+1-windowSize, acc)
+		}
+
+		return
+	}
+
+	// weight of the bit exiting the window: base^(windowSize-1)
+	top := uint64(1)
+	for i := 1; i < windowSize; i++ {
+		top = mulMod(top, hashBase)
+	}
+
+	h := uint64(0)
+	for i, b := range bitsArr {
+		if i >= windowSize {
+			h = (h + hashMod - mulMod(uint64(bitsArr[i-windowSize])+1, top)) % hashMod
+		}
+		h = (mulMod(h, hashBase) + uint64(b) + 1) % hashMod
+
+		if i+1 < windowSize {
+			continue
+		}
+
+		fn(i+1-windowSize, h)
+	}
+}
+
+func mulMod(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	// 2^64 = 2^3 * 2^61 ≡ 8 (mod 2^61-1)
+	r := (hi<<3 | lo>>61) + lo&hashMod
+	for r >= hashMod {
+		r -= hashMod
+	}
+
+	return r
+}
+
+// countShortSubstrs counts exactly the windows up to 64 bits, using their value as key
+func countShortSubstrs(ctx context.Context, bitsArr []types.Bit, windowSize, topK int) types.SubstrCount {
+	log := zerolog.Ctx(ctx)
+
+	counter := map[uint64]int{}
+	forEachWindow(ctx, bitsArr, windowSize, func(_ int, key uint64) {
+		counter[key]++
+	})
+
+	topKSelected := getTopKFreqSubstrs(topK, counter)
+
+	strAllCount := map[string]int{}
+	strTopKSelected := map[string]int{}
+	strSubstrs := []string{}
+
+	for substr, count := range counter {
+		s, err := engine.IntToBitString(substr, windowSize)
+		if err != nil {
+			log.Fatal().Err(err).Msg("error converting int to bit string")
+		}
+
+		strSubstrs = append(strSubstrs, s)
+		strAllCount[s] = count
+		if _, ok := topKSelected[substr]; ok {
+			strTopKSelected[s] = count
+		}
+	}
+	sort.Strings(strSubstrs)
+
+	return types.SubstrCount{
+		Length:        windowSize,
+		Total:         len(counter),
+		AllCounts:     strAllCount,
+		SortedSubstrs: strSubstrs,
+		Counts:        strTopKSelected,
+	}
+}
+
+type longEntry struct {
+	offset int
+	count  int
+}
+
+// countLongSubstrs counts exactly the windows longer than 64 bits
+//
+// windows are grouped by rolling hash, and a window is compared with the first occurrence
+// of each string in its group, so that hash collisions are not merged
+//
+// to keep the memory bounded, AllCounts and SortedSubstrs only hold the top K substrings
+func countLongSubstrs(ctx context.Context, bitsArr []types.Bit, windowSize, topK int) types.SubstrCount {
+	words := engine.PackBits(bitsArr)
+	words = append(words, 0)
+
+	groups := map[uint64][]longEntry{}
+	total := 0
+	forEachWindow(ctx, bitsArr, windowSize, func(offset int, key uint64) {
+		group := groups[key]
+		for i := range group {
+			if windowsEqual(words, group[i].offset, offset, windowSize) {
+				group[i].count++
+				return
+			}
+		}
+		groups[key] = append(group, longEntry{offset: offset, count: 1})
+		total++
+	})
+
+	// key substrings by first offset
+	counter := make(map[uint64]int, total)
+	for _, group := range groups {
+		for _, e := range group {
+			counter[uint64(e.offset)] = e.count
+		}
+	}
+
+	selected := getTopKFreqSubstrs(topK, counter)
+	return offsetsToSubstrCount(bitsArr, windowSize, total, selected)
+}
+
+// windowsEqual compares the windows starting at i and j, 64 bits at a time
+func windowsEqual(words []uint64, i, j, length int) bool {
+	for k := 0; k < length; k += 64 {
+		n := min(64, length-k)
+		wi, wj := wordAt(words, i+k), wordAt(words, j+k)
+		if n < 64 {
+			wi >>= 64 - n
+			wj >>= 64 - n
+		}
+		if wi != wj {
+			return false
+		}
+	}
+
+	return true
+}
+
+// wordAt returns the 64 bits starting at offset
+func wordAt(words []uint64, offset int) uint64 {
+	first, shift := offset/64, uint(offset%64)
+	w := words[first] << shift
+	if shift > 0 {
+		w |= words[first+1] >> (64 - shift)
+	}
+
+	return w
+}
+
+// approxCountSubstrs estimates the top K most frequent windows with bounded memory
+//
+// a first pass adds every window to a count-min sketch, a second pass keeps
+// the K windows with the highest estimate (heavy hitters), counts can be overestimated
+func approxCountSubstrs(ctx context.Context, bitsArr []types.Bit, windowSize, topK, width, depth int) types.SubstrCount {
+	if topK <= 0 {
+		topK = defaultTopK
+	}
+
+	sketch := newCountMinSketch(width, depth)
+	forEachWindow(ctx, bitsArr, windowSize, func(_ int, key uint64) {
+		sketch.add(key)
+	})
+
+	candidates := map[uint64]bool{}
+	top := heap.NewHeap(func(e1, e2 heap.Elem) bool {
+		return e1.Key.(int) < e2.Key.(int)
+	})
+	forEachWindow(ctx, bitsArr, windowSize, func(offset int, key uint64) {
+		if candidates[key] {
+			return
+		}
+
+		estimate := sketch.estimate(key)
+		if top.Size() >= topK {
+			if estimate <= top.Front().Key.(int) {
+				return
+			}
+			evicted := top.Pop()
+			delete(candidates, evicted.Val.([2]uint64)[0])
+		}
+
+		candidates[key] = true
+		top.Push(heap.Elem{Key: estimate, Val: [2]uint64{key, uint64(offset)}})
+	})
+
+	selected := map[uint64]int{}
+	for top.Size() > 0 {
+		e := top.Pop()
+		selected[e.Val.([2]uint64)[1]] = e.Key.(int)
+	}
+
+	substrCount := offsetsToSubstrCount(bitsArr, windowSize, len(selected), selected)
+	substrCount.Approximate = true
+
+	return substrCount
+}
+
+// offsetsToSubstrCount renders the substrings identified by their offset
+func offsetsToSubstrCount(bitsArr []types.Bit, windowSize, total int, counts map[uint64]int) types.SubstrCount {
+	strCounts := make(map[string]int, len(counts))
+	strSubstrs := make([]string, 0, len(counts))
+	for offset, count := range counts {
+		s := engine.BitsToString(bitsArr[offset : int(offset)+windowSize])
+		strCounts[s] = count
+		strSubstrs = append(strSubstrs, s)
+	}
+	sort.Strings(strSubstrs)
+
+	return types.SubstrCount{
+		Length:        windowSize,
+		Total:         total,
+		AllCounts:     strCounts,
+		SortedSubstrs: strSubstrs,
+		Counts:        strCounts,
+	}
+}
+
+type countMinSketch struct {
+	width  uint64
+	counts [][]uint32
+}
+
+func newCountMinSketch(width, depth int) *countMinSketch {
+	if width < 1 {
+		width = DefaultSketchWidth
+	}
+	if depth < 1 {
+		depth = DefaultSketchDepth
+	}
+
+	counts := make([][]uint32, depth)
+	for i := range counts {
+		counts[i] = make([]uint32, width)
+	}
+
+	return &countMinSketch{width: uint64(width), counts: counts}
+}
+
+func (s *countMinSketch) index(row int, key uint64) uint64 {
+	// splitmix64 finalizer, seeded by row
+	x := key + uint64(row+1)*0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x % s.width
+}
+
+func (s *countMinSketch) add(key uint64) {
+	for row := range s.counts {
+		s.counts[row][s.index(row, key)]++
+	}
+}
+
+func (s *countMinSketch) estimate(key uint64) int {
+	est := uint32(0)
+	for row := range s.counts {
+		c := s.counts[row][s.index(row, key)]
+		if row == 0 || c < est {
+			est = c
+		}
+	}
+
+	return int(est)
+}
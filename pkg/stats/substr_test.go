@@ -0,0 +1,146 @@
+package stats
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fedemengo/d2bist/pkg/engine"
+	"github.com/fedemengo/d2bist/pkg/types"
+)
+
+// repetitiveBits returns random bits where a random block is repeated several times
+func repetitiveBits(seed int64, n, blockLen, repeats int) []types.Bit {
+	rng := rand.New(rand.NewSource(seed))
+	random := func(n int) []types.Bit {
+		bits := make([]types.Bit, n)
+		for i := range bits {
+			bits[i] = types.Bit(rng.Intn(2))
+		}
+		return bits
+	}
+
+	block := random(blockLen)
+	bits := []types.Bit{}
+	for i := 0; i < repeats; i++ {
+		bits = append(bits, random(n/repeats)...)
+		bits = append(bits, block...)
+	}
+
+	return bits
+}
+
+func naiveCount(bits []types.Bit, windowSize int) map[string]int {
+	counts := map[string]int{}
+	for i := 0; i+windowSize <= len(bits); i++ {
+		counts[engine.BitsToString(bits[i:i+windowSize])]++
+	}
+
+	return counts
+}
+
+func TestSubstrCount(t *testing.T) {
+	bits := repetitiveBits(42, 3000, 200, 7)
+
+	testCases := []struct {
+		name       string
+		windowSize int
+	}{
+		{name: "short window", windowSize: 3},
+		{name: "64 bits window", windowSize: 64},
+		{name: "long window", windowSize: 70},
+		{name: "multi word window", windowSize: 130},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			a, r := assert.New(tt), require.New(tt)
+
+			s := AnalizeBits(context.Background(), bits, WithMaxBlockSize(tc.windowSize), WithTopKFreq(-1))
+			r.Len(s.SubstrsCount, tc.windowSize)
+
+			expected := naiveCount(bits, tc.windowSize)
+			substrCount := s.SubstrsCount[tc.windowSize-1]
+			a.Equal(tc.windowSize, substrCount.Length)
+			a.Equal(len(expected), substrCount.Total)
+			a.Equal(expected, substrCount.AllCounts)
+			a.Equal(expected, substrCount.Counts)
+			a.False(substrCount.Approximate)
+		})
+	}
+}
+
+func TestSubstrCountTopK(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	bits := repetitiveBits(7, 3000, 150, 9)
+	block := engine.BitsToString(bits[3000/9 : 3000/9+100])
+
+	for _, windowSize := range []int{8, 100} {
+		substrCount := countSubstrs(bits, windowSize, 3)
+		r.Equal(windowSize, substrCount.Length)
+
+		expected := naiveCount(bits, windowSize)
+		counts := substrCount.Counts
+		a.Len(counts, 3)
+
+		minSelected := len(bits)
+		for substr, count := range counts {
+			a.Equal(expected[substr], count)
+			minSelected = min(minSelected, count)
+		}
+		// no substring left out is more frequent than the selected ones
+		for substr, count := range expected {
+			if _, ok := counts[substr]; !ok {
+				a.LessOrEqual(count, minSelected)
+			}
+		}
+
+		if windowSize == 100 {
+			// every window of the repeated block occurs once per repetition
+			a.Equal(9, minSelected)
+			a.Equal(9, expected[block])
+		}
+	}
+}
+
+func TestSubstrCountApproximate(t *testing.T) {
+	a, r := assert.New(t), require.New(t)
+
+	bits := repetitiveBits(3, 5000, 120, 12)
+	block := engine.BitsToString(bits[5000/12 : 5000/12+100])
+
+	s := AnalizeBits(context.Background(), bits, WithMaxBlockSize(100), WithTopKFreq(5), WithApproximate(1<<12, 4))
+	r.Len(s.SubstrsCount, 100)
+
+	substrCount := s.SubstrsCount[99]
+	a.True(substrCount.Approximate)
+	a.Len(substrCount.Counts, 5)
+
+	// the count-min sketch never underestimates
+	a.GreaterOrEqual(substrCount.Counts[block], 12)
+
+	expected := naiveCount(bits, 100)
+	for substr, count := range substrCount.Counts {
+		a.GreaterOrEqual(count, expected[substr])
+	}
+}
+
+func countSubstrs(bits []types.Bit, windowSize, topK int) types.SubstrCount {
+	if windowSize <= maxIntWindow {
+		return countShortSubstrs(context.Background(), bits, windowSize, topK)
+	}
+
+	return countLongSubstrs(context.Background(), bits, windowSize, topK)
+}
+
+func TestGetTopKFreqSubstrs(t *testing.T) {
+	a := assert.New(t)
+
+	counter := map[uint64]int{0: 1, 1: 10, 2: 3, 3: 7, 4: 2}
+	a.Equal(map[uint64]int{1: 10, 3: 7}, getTopKFreqSubstrs(2, counter))
+	a.Equal(counter, getTopKFreqSubstrs(-1, counter))
+}
@@ -0,0 +1,415 @@
+package types
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	svg "github.com/ajstarks/svgo"
+	"github.com/vdobler/chart"
+	"github.com/vdobler/chart/imgg"
+	"github.com/vdobler/chart/svgg"
+
+	"github.com/fedemengo/d2bist/pkg/compression"
+)
+
+var ErrPlotFormat = errors.New("plot format is not supported")
+
+const (
+	chartW = 1300
+	chartH = 800
+)
+
+// shannonColor and lz76Color are the colours of the Shannon and LZ76 series, compression series take
+// the colour of their codec and other series the colours of fallbackColors
+var (
+	shannonColor = color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	lz76Color    = color.RGBA{R: 0, G: 0, B: 0, A: 255}
+)
+
+// entropyColor returns the colour of the series of eType, false when it has none
+func entropyColor(eType EntropyType) (color.RGBA, bool) {
+	switch eType {
+	case ShannonEntropy:
+		return shannonColor, true
+	case LZ76Complexity:
+		return lz76Color, true
+	}
+
+	codec, ok := compression.Lookup(compression.CompressionType(eType))
+	if !ok || codec.Color == (color.RGBA{}) {
+		return color.RGBA{}, false
+	}
+
+	return codec.Color, true
+}
+
+var fallbackColors = []color.RGBA{
+	{R: 255, G: 0, B: 255, A: 255},   // Magenta
+	{R: 0, G: 200, B: 255, A: 255},   // Sky
+	{R: 128, G: 128, B: 0, A: 255},   // Olive
+	{R: 0, G: 0, B: 128, A: 255},     // Navy
+	{R: 255, G: 105, B: 180, A: 255}, // Pink
+	{R: 128, G: 128, B: 128, A: 255}, // Gray
+}
+
+// lineStyles tell apart the sources of the series in a comparison
+var lineStyles = []chart.LineStyle{
+	chart.SolidLine,
+	chart.DashedLine,
+	chart.DottedLine,
+	chart.DashDotDotLine,
+	chart.LongDashLine,
+	chart.LongDotLine,
+}
+
+// EntropySet is the entropy series of one input
+type EntropySet struct {
+	Source  string     `json:"source"`
+	Entropy []*Entropy `json:"entropy"`
+}
+
+type chartConfig struct {
+	title  string
+	width  int
+	height int
+	xRange *[2]float64
+	yRange [2]float64
+	logX   bool
+	smooth int
+}
+
+type ChartOpt func(c *chartConfig)
+
+// WithChartTitle sets the title on top of the chart
+func WithChartTitle(title string) ChartOpt {
+	return func(c *chartConfig) {
+		c.title = title
+	}
+}
+
+// WithChartSize sets the size of png and svg charts in pixels
+func WithChartSize(width, height int) ChartOpt {
+	return func(c *chartConfig) {
+		c.width, c.height = width, height
+	}
+}
+
+// WithChartXRange fixes the bit offsets shown on the x axis, by default every window is shown
+func WithChartXRange(min, max float64) ChartOpt {
+	return func(c *chartConfig) {
+		c.xRange = &[2]float64{min, max}
+	}
+}
+
+// WithChartYRange fixes the entropy shown on the y axis, by default [0, 1]
+func WithChartYRange(min, max float64) ChartOpt {
+	return func(c *chartConfig) {
+		c.yRange = [2]float64{min, max}
+	}
+}
+
+// WithChartLogX uses a logarithmic x axis, offsets are shifted by 1 so that the first window is at 1
+func WithChartLogX() ChartOpt {
+	return func(c *chartConfig) {
+		c.logX = true
+	}
+}
+
+// WithChartSmoothing plots the moving average of window values instead of the values, csv and json keep the raw values
+func WithChartSmoothing(window int) ChartOpt {
+	return func(c *chartConfig) {
+		c.smooth = window
+	}
+}
+
+type entropyWriter func(w io.Writer, sets []EntropySet, c *chartConfig) error
+
+var entropyWriters = map[string]entropyWriter{
+	".png":  writeEntropyPNG,
+	".svg":  writeEntropySVG,
+	".csv":  writeEntropyCSV,
+	".json": writeEntropyJSON,
+}
+
+// WriteEntropyChart writes the entropy series to path, in the format of its extension (png, svg, csv or json)
+func WriteEntropyChart(path string, entropies []*Entropy, opts ...ChartOpt) error {
+	return WriteEntropyComparison(path, []EntropySet{{Entropy: entropies}}, opts...)
+}
+
+// WriteEntropyComparison writes the entropy series of several inputs to path, in the format of its extension
+//
+// in png and svg charts each estimator keeps its colour and each input has its own line style
+func WriteEntropyComparison(path string, sets []EntropySet, opts ...ChartOpt) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	if _, ok := entropyWriters[ext]; !ok {
+		return fmt.Errorf("extension `%s` of `%s`: %w", ext, path, ErrPlotFormat)
+	}
+
+	// the file is created only when the chart is valid
+	var buf bytes.Buffer
+	if err := EncodeEntropyChart(&buf, strings.TrimPrefix(ext, "."), sets, opts...); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// EncodeEntropyChart writes to w the entropy series of the inputs in format (png, svg, csv or json)
+func EncodeEntropyChart(w io.Writer, format string, sets []EntropySet, opts ...ChartOpt) error {
+	c := &chartConfig{
+		title:  "data entropy",
+		width:  chartW,
+		height: chartH,
+		yRange: [2]float64{0, 1},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.width < 1 || c.height < 1 {
+		return fmt.Errorf("chart size %dx%d must be positive", c.width, c.height)
+	}
+	if c.yRange[0] >= c.yRange[1] || (c.xRange != nil && c.xRange[0] >= c.xRange[1]) {
+		return fmt.Errorf("chart ranges must have min < max")
+	}
+	if c.logX && c.xRange != nil && c.xRange[0] <= 0 {
+		return fmt.Errorf("log x axis requires a positive min")
+	}
+
+	write, ok := entropyWriters["."+strings.ToLower(format)]
+	if !ok {
+		return fmt.Errorf("format `%s`: %w", format, ErrPlotFormat)
+	}
+
+	return write(w, sets, c)
+}
+
+// seriesLabel prefixes the estimator name with the source, when there is one
+func seriesLabel(source string, name EntropyType) string {
+	if len(source) == 0 {
+		return string(name)
+	}
+
+	return fmt.Sprintf("%s: %s", source, name)
+}
+
+// movingAverage averages each value with the ones in a window centered on it
+func movingAverage(values []float64, window int) []float64 {
+	if window < 2 {
+		return values
+	}
+
+	prefix := make([]float64, len(values)+1)
+	for i, v := range values {
+		prefix[i+1] = prefix[i] + v
+	}
+
+	avg := make([]float64, len(values))
+	for i := range values {
+		from, to := i-window/2, i-window/2+window
+		if from < 0 {
+			from = 0
+		}
+		if to > len(values) {
+			to = len(values)
+		}
+		avg[i] = (prefix[to] - prefix[from]) / float64(to-from)
+	}
+
+	return avg
+}
+
+func entropyChart(sets []EntropySet, c *chartConfig) *chart.ScatterChart {
+	pl := chart.ScatterChart{Title: c.title}
+
+	pl.YRange.MinMode.Fixed = true
+	pl.YRange.MinMode.Value = c.yRange[0]
+	pl.YRange.MaxMode.Fixed = true
+	pl.YRange.MaxMode.Value = c.yRange[1]
+	pl.YRange.TicSetting.Delta = (c.yRange[1] - c.yRange[0]) / 4
+	pl.YRange.Label = "entropy"
+	pl.YRange.TicSetting.Format = func(v float64) string {
+		return fmt.Sprintf("%.2f", v)
+	}
+	pl.YRange.TicSetting.Mirror = 0
+
+	// the first window is at 1 on a log axis
+	firstX := 0
+	if c.logX {
+		firstX = 1
+	}
+
+	maxX, unknown := firstX+1, map[EntropyType]color.RGBA{}
+	for si, set := range sets {
+		for _, e := range set.Entropy {
+			entropy := movingAverage(e.Values, c.smooth)
+
+			x, y := make([]float64, len(entropy)), make([]float64, len(entropy))
+			for i, v := range entropy {
+				x[i] = float64(firstX + e.Offset(i))
+				y[i] = v
+			}
+			if len(x) > 0 && int(x[len(x)-1]) > maxX {
+				maxX = int(x[len(x)-1])
+			}
+
+			seriesColor, ok := entropyColor(e.Name)
+			if !ok {
+				if seriesColor, ok = unknown[e.Name]; !ok {
+					seriesColor = fallbackColors[len(unknown)%len(fallbackColors)]
+					unknown[e.Name] = seriesColor
+				}
+			}
+
+			pl.AddDataPair(
+				seriesLabel(set.Source, e.Name),
+				x, y,
+				chart.PlotStyleLines,
+				chart.Style{
+					Symbol:      0,
+					SymbolColor: seriesColor,
+					LineColor:   seriesColor,
+					LineWidth:   2,
+					LineStyle:   lineStyles[si%len(lineStyles)],
+				})
+		}
+	}
+
+	// a column of the key for each input
+	pl.Key.Pos = "obr"
+	pl.Key.Cols = len(sets)
+
+	pl.XRange.Log = c.logX
+	pl.XRange.MinMode.Fixed = true
+	pl.XRange.MinMode.Value = float64(firstX)
+	pl.XRange.MaxMode.Fixed = true
+	pl.XRange.MaxMode.Value = float64(maxX)
+	if c.xRange != nil {
+		pl.XRange.MinMode.Value = c.xRange[0]
+		pl.XRange.MaxMode.Value = c.xRange[1]
+	}
+
+	if !c.logX {
+		pl.XRange.TicSetting.Delta = float64(int(pl.XRange.MaxMode.Value-pl.XRange.MinMode.Value) / 5)
+	}
+	pl.XRange.TicSetting.Mirror = 0
+	pl.XRange.TicSetting.Grid = chart.GridOff
+	pl.XRange.Label = "offset"
+
+	return &pl
+}
+
+func writeEntropyPNG(w io.Writer, sets []EntropySet, c *chartConfig) error {
+	dumper := NewDumper(1, 1, c.width, c.height)
+	dumper.Plot(entropyChart(sets, c))
+
+	return dumper.Encode(w)
+}
+
+func writeEntropySVG(w io.Writer, sets []EntropySet, c *chartConfig) error {
+	s := svg.New(w)
+	s.Start(c.width, c.height)
+	s.Rect(0, 0, c.width, c.height, "fill: #ffffff")
+
+	sgr := svgg.AddTo(s, 0, 0, c.width, c.height, "", 12, color.RGBA{0xff, 0xff, 0xff, 0xff})
+	entropyChart(sets, c).Plot(sgr)
+
+	s.End()
+
+	return nil
+}
+
+// writeEntropyCSV writes a row per window, at the bit offsets of the longest series, with the value of each entropy,
+// a series shorter than the others has empty cells
+func writeEntropyCSV(w io.Writer, sets []EntropySet, _ *chartConfig) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{"offset"}
+	longest := &Entropy{}
+	for _, set := range sets {
+		for _, e := range set.Entropy {
+			header = append(header, seriesLabel(set.Source, e.Name))
+			if len(e.Values) > len(longest.Values) {
+				longest = e
+			}
+		}
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for i := range longest.Values {
+		record := []string{strconv.Itoa(longest.Offset(i))}
+		for _, set := range sets {
+			for _, e := range set.Entropy {
+				value := ""
+				if i < len(e.Values) {
+					// adding 0 turns -0 into 0
+					value = strconv.FormatFloat(e.Values[i]+0, 'f', -1, 64)
+				}
+				record = append(record, value)
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// writeEntropyJSON writes the list of entropies, or the list of sets when comparing several inputs
+func writeEntropyJSON(w io.Writer, sets []EntropySet, _ *chartConfig) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if len(sets) == 1 && len(sets[0].Source) == 0 {
+		return enc.Encode(sets[0].Entropy)
+	}
+
+	return enc.Encode(sets)
+}
+
+type Dumper struct {
+	N, M, W, H int
+	Cnt        int
+	I          *image.RGBA
+}
+
+func NewDumper(n, m, w, h int) *Dumper {
+	dumper := Dumper{N: n, M: m, W: w, H: h}
+
+	dumper.I = image.NewRGBA(image.Rect(0, 0, n*w, m*h))
+	bg := image.NewUniform(color.RGBA{0xff, 0xff, 0xff, 0xff})
+	draw.Draw(dumper.I, dumper.I.Bounds(), bg, image.ZP, draw.Src)
+
+	return &dumper
+}
+
+// Encode writes the plots as png to w
+func (d *Dumper) Encode(w io.Writer) error {
+	return png.Encode(w, d.I)
+}
+
+func (d *Dumper) Plot(c chart.Chart) {
+	row, col := d.Cnt/d.N, d.Cnt%d.N
+
+	igr := imgg.AddTo(d.I, col*d.W, row*d.H, d.W, d.H, color.RGBA{0xff, 0xff, 0xff, 0xff}, nil, nil)
+	c.Plot(igr)
+
+	d.Cnt++
+}
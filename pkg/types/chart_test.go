@@ -0,0 +1,116 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteEntropyCSV(t *testing.T) {
+	entropies := []*Entropy{
+		{Name: ShannonEntropy, Values: []float64{0.5, 1}},
+		{Name: GzipEntropy, Values: []float64{0.25}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeEntropyCSV(&buf, []EntropySet{{Entropy: entropies}}, nil))
+
+	assert.Equal(t, "offset,Shannon,Gzip\n0,0.5,0.25\n1,1,\n", buf.String())
+}
+
+func TestWriteEntropyJSON(t *testing.T) {
+	entropies := []*Entropy{
+		{Name: ShannonEntropy, Values: []float64{0.5, 1}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeEntropyJSON(&buf, []EntropySet{{Entropy: entropies}}, nil))
+
+	var decoded []*Entropy
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, entropies, decoded)
+}
+
+func TestWriteEntropyChartFormat(t *testing.T) {
+	entropies := []*Entropy{
+		{Name: ShannonEntropy, Values: []float64{0.5, 1}},
+	}
+	dir := t.TempDir()
+
+	for _, name := range []string{"e.png", "e.svg", "e.csv", "e.JSON"} {
+		assert.NoError(t, WriteEntropyChart(filepath.Join(dir, name), entropies), name)
+	}
+
+	assert.ErrorIs(t, WriteEntropyChart(filepath.Join(dir, "e.gif"), entropies), ErrPlotFormat)
+	assert.Error(t, WriteEntropyChart(filepath.Join(dir, "missing", "e.png"), entropies))
+}
+
+func TestWriteEntropyComparisonCSV(t *testing.T) {
+	sets := []EntropySet{
+		{Source: "a", Entropy: []*Entropy{{Name: ShannonEntropy, Values: []float64{0.5}}}},
+		{Source: "b", Entropy: []*Entropy{{Name: ShannonEntropy, Values: []float64{1}}}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeEntropyCSV(&buf, sets, nil))
+
+	assert.Equal(t, "offset,a: Shannon,b: Shannon\n0,0.5,1\n", buf.String())
+}
+
+func TestWriteEntropyChartOpts(t *testing.T) {
+	entropies := []*Entropy{
+		{Name: ShannonEntropy, Values: []float64{0.5, 1, 0.25}},
+		{Name: ZstdEntropy, Values: []float64{0.5, 1, 0.25}},
+		{Name: EntropyType("custom"), Values: []float64{0.5, 1, 0.25}},
+	}
+	dir := t.TempDir()
+
+	assert.NoError(t, WriteEntropyChart(filepath.Join(dir, "e.png"), entropies,
+		WithChartTitle("title"),
+		WithChartSize(400, 300),
+		WithChartXRange(1, 10),
+		WithChartYRange(0.2, 0.8),
+		WithChartLogX(),
+		WithChartSmoothing(2),
+	))
+
+	assert.Error(t, WriteEntropyChart(filepath.Join(dir, "e.png"), entropies, WithChartSize(0, 300)))
+	assert.Error(t, WriteEntropyChart(filepath.Join(dir, "e.png"), entropies, WithChartYRange(1, 0)))
+	assert.Error(t, WriteEntropyChart(filepath.Join(dir, "e.png"), entropies, WithChartXRange(0, 10), WithChartLogX()))
+}
+
+func TestMovingAverage(t *testing.T) {
+	testCases := []struct {
+		name           string
+		values         []float64
+		window         int
+		expectedValues []float64
+	}{
+		{
+			name:           "no smoothing",
+			values:         []float64{0, 1, 0},
+			window:         1,
+			expectedValues: []float64{0, 1, 0},
+		}, {
+			name:           "odd window",
+			values:         []float64{0, 3, 0, 3},
+			window:         3,
+			expectedValues: []float64{1.5, 1, 2, 1.5},
+		}, {
+			name:           "even window",
+			values:         []float64{0, 2, 4},
+			window:         2,
+			expectedValues: []float64{0, 1, 3},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			assert.Equal(tt, tc.expectedValues, movingAverage(tc.values, tc.window))
+		})
+	}
+}
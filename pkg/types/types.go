@@ -3,17 +3,8 @@ package types
 import (
 	"errors"
 	"fmt"
-	"image"
-	"image/color"
-	"image/draw"
-	"image/png"
 	"io"
-	"log"
 	"math"
-	"os"
-
-	"github.com/vdobler/chart"
-	"github.com/vdobler/chart/imgg"
 
 	"github.com/fedemengo/d2bist/pkg/compression"
 	"github.com/fedemengo/go-data-structures/heap"
@@ -30,6 +21,15 @@ const (
 	S2Entropy      = EntropyType(compression.S2)
 	ZstdEntropy    = EntropyType(compression.Zstd)
 	Bzip2Entropy   = EntropyType(compression.Bzip2)
+	CMEntropy      = EntropyType(compression.CM)
+	ZlibEntropy    = EntropyType(compression.Zlib)
+	XzEntropy      = EntropyType(compression.Xz)
+	Lz4Entropy     = EntropyType(compression.Lz4)
+	SnappyEntropy  = EntropyType(compression.Snappy)
+	LzwEntropy     = EntropyType(compression.Lzw)
+
+	// LZ76Complexity is the normalized Lempel-Ziv complexity, not an entropy but on the same scale
+	LZ76Complexity = EntropyType("LZ76")
 )
 
 type Bit uint8
@@ -45,6 +45,21 @@ func (b Bit) ToByte() byte {
 	}
 }
 
+// Range is a section of a bit string, Offset and Length are in bits
+type Range struct {
+	Offset int `json:"offset"`
+	Length int `json:"length"`
+}
+
+// Region is a section of a bit string with homogeneous entropy
+//
+// Entropy is the mean entropy of its windows and Class its likely content (zeros, text, code, compressed or random)
+type Region struct {
+	Range
+	Entropy float64 `json:"entropy"`
+	Class   string  `json:"class"`
+}
+
 type SubstrCount struct {
 	Total  int
 	Length int
@@ -52,11 +67,25 @@ type SubstrCount struct {
 
 	SortedSubstrs []string
 	AllCounts     map[string]int
+
+	// Approximate is set when the counts are estimated and can be overestimated
+	Approximate bool
 }
 
 type Entropy struct {
-	Name   EntropyType
-	Values []float64
+	Name   EntropyType `json:"name"`
+	Values []float64   `json:"values"`
+	// Offsets are the bit offsets of the windows of Values, nil when the windows are not known
+	Offsets []int `json:"offsets,omitempty"`
+}
+
+// Offset returns the bit offset of the window of the i-th value, or i when the offsets are not known
+func (e *Entropy) Offset(i int) int {
+	if i < len(e.Offsets) {
+		return e.Offsets[i]
+	}
+
+	return i
 }
 
 func NewShannonEntropy() *Entropy {
@@ -75,11 +104,15 @@ type Stats struct {
 	SubstrsCount []SubstrCount
 
 	CompressionStats *CompressionStats
-	EntropyPlotName  string
+	ExtractionStats  *ExtractionStats
+	LineCodeStats    *LineCodeStats
+	Regions          []Region
+	EntropyPlotPath  string
+	EntropyChartOpts []ChartOpt
 	Entropy          []*Entropy
 }
 
-func (s *Stats) RenderStats(w io.Writer) {
+func (s *Stats) RenderStats(w io.Writer) error {
 	if s.CompressionStats != nil {
 		s.BitsStrCount = map[int]map[int64]int{
 			1: s.BitsStrCount[1],
@@ -102,6 +135,10 @@ func (s *Stats) RenderStats(w io.Writer) {
 			}
 		}
 
+		if substrGroup.Approximate {
+			fmt.Fprintf(w, "approximate top %d of length %d\n", len(substrGroup.Counts), substrGroup.Length)
+		}
+
 		if len(substrGroup.Counts) < len(substrGroup.AllCounts) {
 			s.printTopBistrK(max, total, substrGroup, w)
 		} else {
@@ -113,8 +150,38 @@ func (s *Stats) RenderStats(w io.Writer) {
 		}
 	}
 
-	if len(s.Entropy) > 0 {
-		renderEntropyChart(s.EntropyPlotName, s.Entropy)
+	if s.LineCodeStats != nil {
+		fmt.Fprintf(w, `
+line code: %s (%d -> %d bits)
+line code violations: %d
+`, s.LineCodeStats.Code, s.LineCodeStats.InputBits, s.LineCodeStats.OutputBits, len(s.LineCodeStats.ViolationOffsets))
+		for i, offset := range s.LineCodeStats.ViolationOffsets {
+			if i == maxViolationsRendered {
+				fmt.Fprintf(w, "... %d more\n", len(s.LineCodeStats.ViolationOffsets)-i)
+				break
+			}
+			fmt.Fprintf(w, "violation at offset %d\n", offset)
+		}
+	}
+
+	if s.ExtractionStats != nil {
+		fmt.Fprintf(w, `
+extractor: %s
+extraction yield: %.3f (%d -> %d bits)
+`, s.ExtractionStats.Extractor, s.ExtractionStats.Yield, s.ExtractionStats.InputBits, s.ExtractionStats.OutputBits)
+	}
+
+	if len(s.Regions) > 0 {
+		fmt.Fprintf(w, "\nregions: %d\n", len(s.Regions))
+		for _, r := range s.Regions {
+			fmt.Fprintf(w, "%10d %10d  %.3f  %s\n", r.Offset, r.Length, r.Entropy, r.Class)
+		}
+	}
+
+	if len(s.Entropy) > 0 && len(s.EntropyPlotPath) > 0 {
+		if err := WriteEntropyChart(s.EntropyPlotPath, s.Entropy, s.EntropyChartOpts...); err != nil {
+			return fmt.Errorf("cannot write entropy chart: %w", err)
+		}
 	}
 
 	if s.CompressionStats != nil {
@@ -122,10 +189,12 @@ func (s *Stats) RenderStats(w io.Writer) {
 compression ratio: %.3f
 compression algorithm: %s
 `, s.CompressionStats.CompressionRatio, s.CompressionStats.CompressionAlgorithm)
-		s.CompressionStats.Stats.RenderStats(w)
-	} else {
-		fmt.Fprintln(w)
+		return s.CompressionStats.Stats.RenderStats(w)
 	}
+
+	fmt.Fprintln(w)
+
+	return nil
 }
 
 func (s *Stats) printTopBistrK(max, total int, substrGroup SubstrCount, w io.Writer) {
@@ -172,113 +241,31 @@ func (s *Stats) printAllBistrK(max, total int, substrGroup SubstrCount, w io.Wri
 	}
 }
 
-var colorsMap = map[EntropyType]color.RGBA{
-	ShannonEntropy: {R: 255, G: 0, B: 0, A: 255},
-	GzipEntropy:    {R: 0, G: 0, B: 255, A: 255},
-	BrotliEntropy:  {R: 0, G: 255, B: 0, A: 255},
-	Bzip2Entropy:   {R: 255, G: 165, B: 0, A: 1},
-}
-
-func renderEntropyChart(plotName string, entropies []*Entropy) {
-	dumper := NewDumper(plotName, 1, 1, 1300, 800)
-	defer dumper.Close()
-
-	pl := chart.ScatterChart{Title: "data entropy"}
-
-	pl.YRange.MinMode.Fixed = true
-	pl.YRange.MinMode.Value = 0
-	pl.YRange.MaxMode.Fixed = true
-	pl.YRange.MaxMode.Value = 1
-	pl.YRange.TicSetting.Delta = 0.25
-	pl.YRange.Label = "entropy"
-	pl.YRange.TicSetting.Format = func(v float64) string {
-		return fmt.Sprintf("%.2f", v)
-	}
-	pl.YRange.TicSetting.Mirror = 0
-
-	maxEntropyPoints := 0
-	for _, e := range entropies {
-		log.Printf("entropy: %s, len: %d", e.Name, len(e.Values))
-		entropy := e.Values
-
-		if len(entropy) > maxEntropyPoints {
-			maxEntropyPoints = len(entropy)
-		}
-
-		x, y := make([]float64, len(entropy)), make([]float64, len(entropy))
-		for i, e := range entropy {
-			x[i] = float64(i)
-			y[i] = e
-		}
-
-		pl.AddDataPair(
-			string(e.Name),
-			x, y,
-			chart.PlotStyleLines,
-			chart.Style{
-				Symbol:      0,
-				SymbolColor: colorsMap[e.Name],
-				LineStyle:   chart.SolidLine,
-			})
-	}
-
-	pl.Key.Pos = "obr"
-	pl.Key.Cols = maxEntropyPoints
-
-	pl.XRange.MinMode.Fixed = true
-	pl.XRange.MinMode.Value = 0
-	pl.XRange.MaxMode.Fixed = true
-	pl.XRange.MaxMode.Value = float64(maxEntropyPoints)
-
-	pl.XRange.TicSetting.Delta = float64(maxEntropyPoints / 5)
-	pl.XRange.TicSetting.Mirror = 0
-	pl.XRange.TicSetting.Grid = chart.GridOff
-	pl.XRange.Label = "offset"
-
-	dumper.Plot(&pl)
-}
-
-type Dumper struct {
-	N, M, W, H int
-	Cnt        int
-	I          *image.RGBA
-	imgFile    *os.File
-}
-
-func NewDumper(name string, n, m, w, h int) *Dumper {
-	var err error
-	dumper := Dumper{N: n, M: m, W: w, H: h}
-
-	dumper.imgFile, err = os.Create(name + ".png")
-	if err != nil {
-		panic(err)
-	}
-	dumper.I = image.NewRGBA(image.Rect(0, 0, n*w, m*h))
-	bg := image.NewUniform(color.RGBA{0xff, 0xff, 0xff, 0xff})
-	draw.Draw(dumper.I, dumper.I.Bounds(), bg, image.ZP, draw.Src)
-
-	return &dumper
-}
+type CompressionStats struct {
+	CompressionRatio     float64
+	CompressionAlgorithm string
 
-func (d *Dumper) Close() {
-	png.Encode(d.imgFile, d.I)
-	d.imgFile.Close()
+	Stats *Stats
 }
 
-func (d *Dumper) Plot(c chart.Chart) {
-	row, col := d.Cnt/d.N, d.Cnt%d.N
-
-	igr := imgg.AddTo(d.I, col*d.W, row*d.H, d.W, d.H, color.RGBA{0xff, 0xff, 0xff, 0xff}, nil, nil)
-	c.Plot(igr)
-
-	d.Cnt++
+// ExtractionStats reports the bits yield of a randomness extractor
+//
+// Yield is the percentage of input bits that survived extraction
+type ExtractionStats struct {
+	Extractor  string
+	InputBits  int
+	OutputBits int
+	Yield      float64
 }
 
-type CompressionStats struct {
-	CompressionRatio     float64
-	CompressionAlgorithm string
+const maxViolationsRendered = 10
 
-	Stats *Stats
+// LineCodeStats reports the offsets of the symbols that were not valid for the line code
+type LineCodeStats struct {
+	Code             string
+	InputBits        int
+	OutputBits       int
+	ViolationOffsets []int
 }
 
 type Result struct {